@@ -0,0 +1,186 @@
+import type { AutoscalerConfig, Explanation, PauseState } from "./types.js";
+import { AutoscalerState } from "./state.js";
+import { Scaler } from "./scaler.js";
+
+// Error codes shared with the monitor's JSON error envelope
+type ErrorCode =
+    | "UNAUTHENTICATED"
+    | "INVALID_ARGUMENT"
+    | "METHOD_NOT_ALLOWED";
+
+export class AdminApi {
+    constructor(
+        private state: AutoscalerState,
+        private scaler: Scaler,
+        // Read on each request, since subclasses set config after the base
+        // constructor
+        private config: () => AutoscalerConfig,
+        private getNow: () => string,
+    ) {}
+
+    // Whether the request is for an admin endpoint, which only exist while
+    // adminToken is set
+    handles(url: URL): boolean {
+        if (!this.config().adminToken) {
+            return false;
+        }
+        const prefix = this.prefix();
+        return (
+            url.pathname === prefix || url.pathname.startsWith(`${prefix}/`)
+        );
+    }
+
+    async fetch(request: Request): Promise<Response> {
+        if (!this.authorized(request)) {
+            return errorResponse(
+                401,
+                "UNAUTHENTICATED",
+                "missing or invalid bearer token",
+            );
+        }
+
+        const url = new URL(request.url);
+        switch (url.pathname.slice(this.prefix().length)) {
+            case "/cordon":
+                return this.cordon(request, url);
+            case "/pause":
+                return this.pause(request, url);
+            case "/explain":
+                return this.explain(request);
+        }
+        return errorResponse(
+            404,
+            "INVALID_ARGUMENT",
+            `no admin endpoint ${url.pathname}`,
+        );
+    }
+
+    // GET lists the cordoned instances; POST and DELETE ?instance=<name>
+    // cordon and uncordon one. A cordoned instance gets no new requests and
+    // isn't scaled down, so it can be debugged live
+    private cordon(request: Request, url: URL): Response {
+        if (request.method !== "GET") {
+            const cordon = request.method === "POST";
+            if (!cordon && request.method !== "DELETE") {
+                return errorResponse(
+                    405,
+                    "METHOD_NOT_ALLOWED",
+                    "method not allowed",
+                );
+            }
+            const name = url.searchParams.get("instance");
+            if (!name) {
+                return errorResponse(
+                    400,
+                    "INVALID_ARGUMENT",
+                    "instance is required",
+                );
+            }
+            if (!this.state.setCordoned(name, cordon)) {
+                return errorResponse(
+                    404,
+                    "INVALID_ARGUMENT",
+                    `no instance ${name}`,
+                );
+            }
+            console.info(
+                `${cordon ? "Cordoned" : "Uncordoned"} instance ${name}`,
+            );
+        }
+
+        return Response.json({
+            cordoned: this.state
+                .getCordonedInstances()
+                .map((instance) => instance.name),
+        });
+    }
+
+    // GET reports whether scaling is paused; POST ?reason=<why> pauses it
+    // and DELETE resumes it. The paused config option can't be overridden
+    // here, so the response may still show scaling paused after DELETE
+    private pause(request: Request, url: URL): Response {
+        const now = this.getNow();
+        switch (request.method) {
+            case "GET":
+                break;
+            case "POST": {
+                const reason = url.searchParams.get("reason");
+                this.state.setPaused(true, reason, now);
+                console.warn(
+                    `Scaling paused (${reason ?? "no reason given"}); no instance will be created or destroyed until it's resumed`,
+                );
+                break;
+            }
+            case "DELETE":
+                if (this.state.getPause().paused) {
+                    this.state.setPaused(false, null, now);
+                    console.info("Scaling resumed");
+                }
+                break;
+            default:
+                return errorResponse(
+                    405,
+                    "METHOD_NOT_ALLOWED",
+                    "method not allowed",
+                );
+        }
+
+        return Response.json(this.state.getPause(this.config().paused));
+    }
+
+    // GET reports what the next heartbeat would decide from the current
+    // metrics, and which constraint, if any, holds a triggered action back
+    private explain(request: Request): Response {
+        if (request.method !== "GET") {
+            return errorResponse(
+                405,
+                "METHOD_NOT_ALLOWED",
+                "method not allowed",
+            );
+        }
+
+        const pause = this.state.getPause(this.config().paused);
+        const explanation: Explanation & { pause: PauseState } = {
+            ...this.scaler.explain(Date.now()),
+            pause,
+        };
+        // A pause holds whatever the heartbeat would otherwise do
+        if (pause.paused) {
+            for (const decision of [
+                explanation.scaleUp,
+                explanation.scaleDown,
+            ]) {
+                if (decision.action !== "none") {
+                    decision.action = "none";
+                    decision.blockedBy = "paused";
+                }
+            }
+        }
+        return Response.json(explanation);
+    }
+
+    private prefix(): string {
+        return this.config().adminEndpoint ?? "/_autoscaler";
+    }
+
+    private authorized(request: Request): boolean {
+        const token = this.config().adminToken ?? "";
+        const encoder = new TextEncoder();
+        const presented = encoder.encode(
+            request.headers.get("Authorization") ?? "",
+        );
+        const expected = encoder.encode(`Bearer ${token}`);
+        return (
+            presented.byteLength === expected.byteLength &&
+            crypto.subtle.timingSafeEqual(presented, expected)
+        );
+    }
+}
+
+function errorResponse(
+    status: number,
+    code: ErrorCode,
+    message: string,
+): Response {
+    return Response.json({ error: { code, message } }, { status });
+}
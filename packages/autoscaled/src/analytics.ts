@@ -0,0 +1,132 @@
+import type {
+    AutoscalerConfig,
+    AnalyticsBucket,
+    DecisionRecord,
+    ReplicaRecord,
+} from "./types.js";
+import { AutoscalerState, type HistoryTable } from "./state.js";
+
+const DECISION_COLUMNS = [
+    "at",
+    "action",
+    "reason",
+    "instance",
+    "resumed",
+    "instance_count",
+] as const;
+
+const REPLICA_COLUMNS = [
+    "at",
+    "instance_count",
+    "healthy_count",
+    "draining_count",
+    "standby_count",
+    "active_requests",
+    "avg_cpu",
+    "avg_memory",
+    "avg_disk",
+] as const;
+
+export class HistoryExporter {
+    constructor(
+        private state: AutoscalerState,
+        // Read on each run, since subclasses set config and its bucket
+        // binding after the base constructor
+        private config: () => AutoscalerConfig,
+    ) {}
+
+    // Records the replicas on each heartbeat, and exports the history once
+    // analyticsExportInterval has passed since the last export
+    async run(now: string): Promise<void> {
+        const config = this.config();
+        const bucket = config.analyticsBucket;
+        if (!bucket) {
+            return;
+        }
+
+        this.state.recordReplicas(now);
+
+        // The first run starts the interval instead of exporting one row
+        const last = this.state.getLastHistoryExport();
+        if (!last) {
+            this.state.recordHistoryExport(now);
+            return;
+        }
+        const interval = config.analyticsExportInterval ?? 3_600_000;
+        if (Date.parse(now) - Date.parse(last) < interval) {
+            return;
+        }
+        const prefix = config.analyticsPrefix ?? "autoscaled/";
+        await this.export(bucket, prefix, now);
+    }
+
+    // Writes the history recorded since the last export as one CSV object per
+    // table, then deletes the rows that were written. Rows stay in storage
+    // if the write fails, to go out with the next export
+    private async export(
+        bucket: AnalyticsBucket,
+        prefix: string,
+        now: string,
+    ): Promise<void> {
+        await this.exportTable(
+            bucket,
+            prefix,
+            "decision_history",
+            "decisions",
+            this.state.getDecisionHistory(),
+            DECISION_COLUMNS,
+            now,
+        );
+        await this.exportTable(
+            bucket,
+            prefix,
+            "replica_history",
+            "replicas",
+            this.state.getReplicaHistory(),
+            REPLICA_COLUMNS,
+            now,
+        );
+        this.state.recordHistoryExport(now);
+    }
+
+    private async exportTable<T extends DecisionRecord | ReplicaRecord>(
+        bucket: AnalyticsBucket,
+        prefix: string,
+        table: HistoryTable,
+        name: string,
+        rows: T[],
+        columns: readonly (keyof T & string)[],
+        now: string,
+    ): Promise<void> {
+        const last = rows[rows.length - 1];
+        if (!last) {
+            return;
+        }
+
+        const lines = [columns.join(",")];
+        for (const row of rows) {
+            lines.push(
+                columns
+                    .map((column) =>
+                        csvField(row[column] as string | number | null),
+                    )
+                    .join(","),
+            );
+        }
+
+        // Partitioned by day, so query engines can prune by date
+        const key = `${prefix}${name}/date=${now.slice(0, 10)}/${now.replace(/[:.]/g, "-")}.csv`;
+        await bucket.put(key, lines.join("\n") + "\n", {
+            httpMetadata: { contentType: "text/csv" },
+        });
+        this.state.deleteHistory(table, last.id);
+    }
+}
+
+function csvField(value: string | number | null | undefined): string {
+    if (value === null || value === undefined) {
+        return "";
+    }
+    const s = String(value);
+    return /[",\n\r]/.test(s) ? `"${s.replaceAll('"', '""')}"` : s;
+}
@@ -0,0 +1,50 @@
+import type { BootRecord, BootTimeStats, BootTimeSummary } from "./types.js";
+
+// Summarizes recorded boots, separately for created and resumed instances
+// since resuming a standby container skips provisioning
+export function summarizeBootTimes(
+    boots: BootRecord[],
+    booting: number,
+    objective: number | undefined,
+): BootTimeStats {
+    return {
+        created: summarize(
+            boots.filter((boot) => boot.resumed === 0),
+            objective,
+        ),
+        resumed: summarize(
+            boots.filter((boot) => boot.resumed === 1),
+            objective,
+        ),
+        booting,
+    };
+}
+
+function summarize(
+    boots: BootRecord[],
+    objective: number | undefined,
+): BootTimeSummary {
+    const times = boots.map((boot) => boot.boot_ms).sort((a, b) => a - b);
+    return {
+        count: times.length,
+        p50: percentile(times, 0.5),
+        p95: percentile(times, 0.95),
+        max: times[times.length - 1] ?? null,
+        withinObjective:
+            objective && times.length > 0
+                ? Math.round(
+                      (times.filter((time) => time <= objective).length /
+                          times.length) *
+                          1e4,
+                  ) / 1e4
+                : null,
+    };
+}
+
+// Nearest-rank percentile of sorted times
+function percentile(times: number[], p: number): number | null {
+    if (times.length === 0) {
+        return null;
+    }
+    return times[Math.max(0, Math.ceil(p * times.length) - 1)] ?? null;
+}
@@ -0,0 +1,98 @@
+// Upper bounds, in milliseconds, of the wait-time histogram buckets
+export const WAIT_BUCKETS = [
+    100, 250, 500, 1_000, 2_500, 5_000, 10_000, 30_000,
+];
+
+export interface ColdStartStats {
+    since: string; // ISO 8601
+    // Requests routed to an instance or answered for lack of one
+    requests: number;
+    // Requests that waited for an instance to be started for them
+    coldStarts: number;
+    // Cold starts answered with a 503 to retry once the instance is up,
+    // rather than served
+    turnedAway: number;
+    // coldStarts / requests
+    fraction: number;
+    waitMs: {
+        average: number;
+        max: number;
+        // Cold starts that waited at most each bound, as in a Prometheus
+        // histogram, ending with "+Inf" for all of them
+        buckets: Record<string, number>;
+    };
+}
+
+// Counts the requests that paid for scale-to-zero or an unhealthy instance
+// by waiting for an instance to start. Counts are kept in memory since the
+// Autoscaler started, so they reset when it's evicted
+export class ColdStartTracker {
+    private readonly since: string;
+    private requests = 0;
+    private coldStarts = 0;
+    private turnedAway = 0;
+    private totalWait = 0;
+    private maxWait = 0;
+    private buckets = new Array<number>(WAIT_BUCKETS.length).fill(0);
+
+    constructor(getNow: () => string) {
+        this.since = getNow();
+    }
+
+    countRequest(): void {
+        this.requests++;
+    }
+
+    // Records a request that waited waitMs for an instance to start, and
+    // returns its response tagged with X-Cold-Start so clients and logs can
+    // tell it apart from a warm one
+    record(response: Response, waitMs: number, served: boolean): Response {
+        this.coldStarts++;
+        if (!served) {
+            this.turnedAway++;
+        }
+        this.totalWait += waitMs;
+        this.maxWait = Math.max(this.maxWait, waitMs);
+        const bucket = WAIT_BUCKETS.findIndex((bound) => waitMs <= bound);
+        if (bucket !== -1) {
+            this.buckets[bucket] = (this.buckets[bucket] ?? 0) + 1;
+        }
+
+        if (response.webSocket) {
+            return response;
+        }
+        // Responses from containers have immutable headers
+        const tagged = new Response(response.body, response);
+        tagged.headers.set("X-Cold-Start", String(Math.round(waitMs)));
+        return tagged;
+    }
+
+    stats(): ColdStartStats {
+        const buckets: Record<string, number> = {};
+        let cumulative = 0;
+        WAIT_BUCKETS.forEach((bound, i) => {
+            cumulative += this.buckets[i] ?? 0;
+            buckets[String(bound)] = cumulative;
+        });
+        buckets["+Inf"] = this.coldStarts;
+
+        return {
+            since: this.since,
+            requests: this.requests,
+            coldStarts: this.coldStarts,
+            turnedAway: this.turnedAway,
+            fraction:
+                this.requests > 0
+                    ? Math.round((this.coldStarts / this.requests) * 1e4) / 1e4
+                    : 0,
+            waitMs: {
+                average:
+                    this.coldStarts > 0
+                        ? Math.round(this.totalWait / this.coldStarts)
+                        : 0,
+                max: Math.round(this.maxWait),
+                buckets,
+            },
+        };
+    }
+}
@@ -0,0 +1,59 @@
+import type { AutoscalerConfig, ScaleEvent } from "./types.js";
+
+// Runs the beforeScale and afterScale hooks, each bounded by hookTimeout so
+// a slow hook can't stall the heartbeat
+export class ScaleHooks {
+    constructor(
+        // Read on each call, since subclasses set config after the base
+        // constructor
+        private config: () => AutoscalerConfig,
+    ) {}
+
+    // Returns false when beforeScale vetoes a scale-down. A hook that throws
+    // or times out is logged and doesn't veto, so a broken hook can't keep
+    // the fleet from shrinking
+    async before(event: ScaleEvent): Promise<boolean> {
+        const hook = this.config().beforeScale;
+        if (!hook) {
+            return true;
+        }
+        const result = await this.run("beforeScale", event, () => hook(event));
+        if (result === false && event.action === "scale_down") {
+            console.info(`beforeScale vetoed scaling down ${event.instance}`);
+            return false;
+        }
+        return true;
+    }
+
+    async after(event: ScaleEvent): Promise<void> {
+        const hook = this.config().afterScale;
+        if (hook) {
+            await this.run("afterScale", event, () => hook(event));
+        }
+    }
+
+    private async run<T>(
+        name: string,
+        event: ScaleEvent,
+        call: () => T | Promise<T>,
+    ): Promise<T | undefined> {
+        const timeout = this.config().hookTimeout ?? 5_000;
+        let timer: ReturnType<typeof setTimeout> | undefined;
+        try {
+            return await Promise.race([
+                Promise.resolve().then(call),
+                new Promise<never>((_, reject) => {
+                    timer = setTimeout(
+                        () => reject(new Error(`timed out after ${timeout}ms`)),
+                        timeout,
+                    );
+                }),
+            ]);
+        } catch (error) {
+            console.error(`${name} hook for ${event.action} failed:`, error);
+            return undefined;
+        } finally {
+            clearTimeout(timer);
+        }
+    }
+}
@@ -10,12 +10,23 @@ import type {
     InstanceType,
     Instance,
     AutoscalerConfig,
+    AnalyticsBucket,
+    DecisionAction,
+    DecisionReason,
+    ScaleEvent,
 } from "./types.js";
 
 import { AutoscalerState } from "./state.js";
 import { Scaler } from "./scaler.js";
 import { Router } from "./router.js";
 import { InstanceManager } from "./instance-manager.js";
+import { HistoryExporter } from "./analytics.js";
+import { AdminApi } from "./admin.js";
+import { RetryStormDetector } from "./retry-storm.js";
+import { ScaleHooks } from "./hooks.js";
+import { ColdStartTracker } from "./cold-starts.js";
+import { summarizeBootTimes } from "./boot-times.js";
+import { Tracer, type Span } from "./tracing.js";
 
 export type {
     GenericContainer,
@@ -26,6 +37,8 @@ export type {
     InstanceType,
     Instance,
     AutoscalerConfig,
+    AnalyticsBucket,
+    ScaleEvent,
 };
 
 export { INSTANCE_SPECS } from "./types.js";
@@ -63,12 +76,22 @@ export class Autoscaler<Env> extends DurableObject<Env> {
         scaleDownCooldown: 120_000,
         healthCheckRetries: 3,
         drainTimeout: 60_000,
+        maxStandbyInstances: 0,
+        standbyTimeout: 3_600_000,
+        analyticsExportInterval: 3_600_000,
+        analyticsPrefix: "autoscaled/",
     };
 
     private state!: AutoscalerState;
     private scaler!: Scaler;
     private router!: Router;
     private instanceManager!: InstanceManager<Env>;
+    private historyExporter!: HistoryExporter;
+    private admin!: AdminApi;
+    private retryStorms!: RetryStormDetector;
+    private hooks!: ScaleHooks;
+    private coldStarts!: ColdStartTracker;
+    private tracer!: Tracer;
 
     #getISO8601Now(): string {
         return new Date().toISOString();
@@ -90,6 +113,26 @@ export class Autoscaler<Env> extends DurableObject<Env> {
             this.config,
             () => this.#getISO8601Now(),
         );
+        this.historyExporter = new HistoryExporter(
+            this.state,
+            () => this.config,
+        );
+        this.admin = new AdminApi(
+            this.state,
+            this.scaler,
+            () => this.config,
+            () => this.#getISO8601Now(),
+        );
+        this.retryStorms = new RetryStormDetector(
+            () => this.config,
+            () => this.#getISO8601Now(),
+        );
+        this.hooks = new ScaleHooks(() => this.config);
+        this.coldStarts = new ColdStartTracker(() => this.#getISO8601Now());
+        this.tracer = new Tracer(
+            () => this.config,
+            (promise) => ctx.waitUntil(promise),
+        );
 
         ctx.blockConcurrencyWhile(async () => {
             this.state.migrate(this.config.maxInstances);
@@ -109,6 +152,16 @@ export class Autoscaler<Env> extends DurableObject<Env> {
     }
 
     override async alarm(): Promise<void> {
+        await this.tracer.trace("autoscaler.evaluate", (root) =>
+            this.#evaluate(root),
+        );
+
+        // Schedule next alarm
+        await this.scheduleAlarm();
+    }
+
+    // Runs one heartbeat's evaluation, traced under root
+    async #evaluate(root: Span): Promise<void> {
         // 1. Cleanup stale instances
         const cleaned = await this.instanceManager.cleanupStaleInstances();
         if (cleaned.length > 0) {
@@ -123,26 +176,52 @@ export class Autoscaler<Env> extends DurableObject<Env> {
         await this.instanceManager.keepAlive(instances);
 
         // 3. Update metrics (includes health checks)
-        await this.#updateAllInstanceMetrics();
+        await root.run(
+            "metrics.fetch",
+            () => this.#updateAllInstanceMetrics(),
+            { "autoscaler.instances": this.state.getInstanceCount(false) },
+        );
 
         // 4. Evaluate scale-up based on metrics
-        if (this.scaler.shouldScaleUpForMetrics()) {
-            await this.#scaleUp();
+        const scaleUp = await root.run("policy.evaluate", (span) => {
+            const decided = this.scaler.shouldScaleUpForMetrics();
+            span.setAttribute("autoscaler.scale_up", decided);
+            return decided;
+        });
+        if (scaleUp) {
+            await root.run("scale_up", (span) => this.#scaleUp(span));
         }
 
         // 5. Evaluate scale-down
-        if (this.scaler.shouldScaleDown()) {
-            await this.#scaleDown();
+        const scaleDown = await root.run("policy.evaluate", (span) => {
+            const decided = this.scaler.shouldScaleDown();
+            span.setAttribute("autoscaler.scale_down", decided);
+            return decided;
+        });
+        if (scaleDown) {
+            await root.run("scale_down", () => this.#scaleDown());
         }
 
         // 6. Process draining instances
         await this.#processDrainingInstances();
 
-        // 7. Schedule next alarm
-        await this.scheduleAlarm();
+        // 7. Destroy instances that have been on standby too long
+        await this.#expireStandbyInstances();
+
+        // 8. Record replicas and export history
+        try {
+            await this.historyExporter.run(this.#getISO8601Now());
+        } catch (error) {
+            console.error("Error exporting history:", error);
+        }
     }
 
     private async warmUpInstances(): Promise<void> {
+        if (this.#isPaused()) {
+            console.info("Scaling paused, not warming up instances");
+            return;
+        }
+
         const minInstances = this.config.minInstances ?? 0;
         for (let i = 0; i < minInstances; i++) {
             const reserved = this.state.tryReserveSlot();
@@ -154,7 +233,8 @@ export class Autoscaler<Env> extends DurableObject<Env> {
             }
 
             try {
-                const container = await this.instanceManager.createInstance();
+                const { container } =
+                    await this.#startInstanceTraced("min_instances");
                 this.ctx.waitUntil(container.startAndWaitForPorts());
                 const state = await container.getState();
                 await this.#trackNewInstance(container, state, 0);
@@ -176,11 +256,38 @@ export class Autoscaler<Env> extends DurableObject<Env> {
             return await this.#getHealthz();
         }
 
+        if (this.admin.handles(url)) {
+            return await this.admin.fetch(request);
+        }
+
+        const response = await this.#routeRequest(request);
+        if (!this.config.capacityHeaders || response.webSocket) {
+            return response;
+        }
+        // Responses from containers have immutable headers
+        const withHeaders = new Response(response.body, response);
+        withHeaders.headers.set(
+            "X-Capacity-Remaining",
+            String(this.router.getCapacityRemaining()),
+        );
+        return withHeaders;
+    }
+
+    async #routeRequest(request: Request): Promise<Response> {
+        if (!this.retryStorms.admit(request)) {
+            const window = this.config.retryStormWindow ?? 10_000;
+            return new Response("Too Many Requests", {
+                status: 429,
+                headers: { "Retry-After": String(Math.ceil(window / 1000)) },
+            });
+        }
+        this.coldStarts.countRequest();
+
         try {
             const instance = this.router.selectInstance();
 
             if (!instance) {
-                return await this.#handleNoInstanceAvailable();
+                return await this.#handleNoInstanceAvailable(request);
             }
 
             const containerResult =
@@ -208,7 +315,14 @@ export class Autoscaler<Env> extends DurableObject<Env> {
                     previousRequests,
                 )
             ) {
-                this.ctx.waitUntil(this.#handleOptimisticScaleUp());
+                // Retries inflate the request rate during a storm
+                if (this.retryStorms.active()) {
+                    console.info(
+                        "Holding request-based scale-up during a retry storm",
+                    );
+                } else {
+                    this.ctx.waitUntil(this.#handleOptimisticScaleUp());
+                }
             }
 
             return await this.#executeRequest(request, container);
@@ -219,29 +333,79 @@ export class Autoscaler<Env> extends DurableObject<Env> {
     }
 
     async #getHealthz(): Promise<Response> {
+        const pause = this.state.getPause(this.config.paused);
         const instanceCount = this.state.getInstanceCount();
+        const protectedCount = this.state.getProtectedCount();
+        const cordonedCount = this.state.getCordonedInstances().length;
         const instances = this.state.getInstances();
+        const standby = this.state.getStandbyInstances();
 
         return new Response(
             JSON.stringify({
+                pause,
                 instanceCount,
+                protectedCount,
+                cordonedCount,
+                retryStorm: this.retryStorms.status(),
+                coldStarts: this.coldStarts.stats(),
+                bootTime: summarizeBootTimes(
+                    this.state.getBootHistory(),
+                    this.state.getBootingCount(),
+                    this.config.bootTimeObjective,
+                ),
                 instances,
+                standbyCount: standby.length,
+                standby,
             }),
             { status: 200 },
         );
     }
 
-    async #handleNoInstanceAvailable(): Promise<Response> {
+    async #handleNoInstanceAvailable(request: Request): Promise<Response> {
+        if (this.#isPaused()) {
+            this.#holdDecision("scale_up", "no_instance");
+            return this.#pausedResponse();
+        }
+
         if (this.state.tryReserveSlot()) {
+            const started = Date.now();
             try {
                 await this.instanceManager.cleanupStaleInstances();
-                const container = await this.instanceManager.createInstance();
+                const { container, resumed } =
+                    await this.#startInstanceTraced("no_instance");
                 const state = await container.getState();
                 await this.#trackNewInstance(container, state, 0);
-                return new Response("Service is starting up, please retry", {
-                    status: 503,
-                    headers: { "Retry-After": "5" },
-                });
+                this.#recordDecision(
+                    "scale_up",
+                    "no_instance",
+                    container,
+                    resumed,
+                );
+
+                // A resumed instance is ready as soon as its ports are, so
+                // the request doesn't have to wait for a retry
+                if (resumed) {
+                    this.state.incrementRequests(
+                        this.instanceManager.getContainerName(container),
+                        this.#getISO8601Now(),
+                        true,
+                        1,
+                    );
+                    const waited = Date.now() - started;
+                    return this.coldStarts.record(
+                        await this.#executeRequest(request, container),
+                        waited,
+                        true,
+                    );
+                }
+                return this.coldStarts.record(
+                    new Response("Service is starting up, please retry", {
+                        status: 503,
+                        headers: { "Retry-After": "5" },
+                    }),
+                    Date.now() - started,
+                    false,
+                );
             } catch (error) {
                 console.error("Failed to create new instance:", error);
                 this.state.releaseSlot();
@@ -277,13 +441,25 @@ export class Autoscaler<Env> extends DurableObject<Env> {
         request: Request,
         container: ContainerStub<Env>,
     ): Promise<Response> {
+        if (this.#isPaused()) {
+            this.#holdDecision("scale_up", "unhealthy");
+            return this.#pausedResponse();
+        }
+
+        const started = Date.now();
         if (this.state.tryReserveSlot()) {
             try {
                 await this.instanceManager.cleanupStaleInstances();
-                const newContainer =
-                    await this.instanceManager.createInstance();
+                const { container: newContainer, resumed } =
+                    await this.#startInstanceTraced("unhealthy");
                 const newState = await newContainer.getState();
                 await this.#trackNewInstance(newContainer, newState, 0);
+                this.#recordDecision(
+                    "scale_up",
+                    "unhealthy",
+                    newContainer,
+                    resumed,
+                );
 
                 // Use the new instance for this request
                 const newInstanceName =
@@ -294,7 +470,12 @@ export class Autoscaler<Env> extends DurableObject<Env> {
                     true,
                     1,
                 );
-                return await this.#executeRequest(request, newContainer);
+                const waited = Date.now() - started;
+                return this.coldStarts.record(
+                    await this.#executeRequest(request, newContainer),
+                    waited,
+                    true,
+                );
             } catch (error) {
                 console.warn(
                     "Failed to create new instance, releasing reservation:",
@@ -306,7 +487,12 @@ export class Autoscaler<Env> extends DurableObject<Env> {
 
         console.info("Replacing unhealthy instance");
         const replaced = await this.instanceManager.replaceInstance(container);
-        return await this.#executeRequest(request, replaced.container);
+        const waited = Date.now() - started;
+        return this.coldStarts.record(
+            await this.#executeRequest(request, replaced.container),
+            waited,
+            true,
+        );
     }
 
     async #executeRequest(
@@ -354,58 +540,247 @@ export class Autoscaler<Env> extends DurableObject<Env> {
         );
     }
 
+    // Starts an instance outside the heartbeat, as a trace of its own
+    #startInstanceTraced(
+        reason: DecisionReason | "min_instances",
+    ): Promise<{ container: ContainerStub<Env>; resumed: boolean }> {
+        return this.tracer.trace(
+            "autoscaler.scale_up",
+            (span) => this.#startInstance(span),
+            { "autoscaler.reason": reason },
+        );
+    }
+
+    // Resumes the most recent standby instance if there is one, and creates a
+    // new instance otherwise. The provider call and instance readiness are
+    // traced under parent
+    async #startInstance(parent: Span): Promise<{
+        container: ContainerStub<Env>;
+        resumed: boolean;
+    }> {
+        const standby = this.state.takeStandbyInstance();
+        if (standby) {
+            const startedAt = this.#getISO8601Now();
+            try {
+                const container = await parent.run(
+                    "provider.resume",
+                    () => this.instanceManager.resumeInstance(standby.name),
+                    { "autoscaler.instance": standby.name },
+                );
+                this.#trackBoot(container, startedAt, true, parent);
+                return { container, resumed: true };
+            } catch (error) {
+                console.warn(
+                    `Failed to resume standby instance ${standby.name}, creating a new one:`,
+                    error,
+                );
+                await this.instanceManager.destroyStandbyInstance(standby.name);
+            }
+        }
+
+        const startedAt = this.#getISO8601Now();
+        const container = await parent.run("provider.create", (span) =>
+            this.instanceManager.createInstance().then((created) => {
+                span.setAttribute(
+                    "autoscaler.instance",
+                    this.instanceManager.getContainerName(created),
+                );
+                return created;
+            }),
+        );
+        this.#trackBoot(container, startedAt, false, parent);
+        return { container, resumed: false };
+    }
+
+    // Times the boot of a started instance in the background, from the
+    // create or resume call to its first passing health check
+    #trackBoot(
+        container: ContainerStub<Env>,
+        startedAt: string,
+        resumed: boolean,
+        parent: Span,
+    ): void {
+        const name = this.instanceManager.getContainerName(container);
+        this.state.recordBootStart(name, startedAt, resumed);
+        const span = parent.child("instance.ready", {
+            "autoscaler.instance": name,
+        });
+        const ready = this.instanceManager
+            .waitUntilReady(container)
+            .then((passed) => {
+                if (passed) {
+                    this.#markReady(name);
+                } else {
+                    span.fail("no passing health check while polled");
+                }
+            })
+            .catch((error) => {
+                span.fail(error);
+                console.error(`Error timing boot of ${name}:`, error);
+            })
+            .finally(() => span.end());
+        span.waitFor(ready);
+        this.ctx.waitUntil(ready);
+    }
+
+    #markReady(name: string): void {
+        const boot = this.state.markReady(name, this.#getISO8601Now());
+        if (!boot) {
+            return;
+        }
+        const objective = this.config.bootTimeObjective;
+        if (objective && boot.boot_ms > objective) {
+            console.warn(
+                `Instance ${name} took ${boot.boot_ms}ms to boot, over the ${objective}ms objective`,
+            );
+        } else {
+            console.info(`Instance ${name} booted in ${boot.boot_ms}ms`);
+        }
+    }
+
+    #isPaused(): boolean {
+        return this.state.getPause(this.config.paused).paused;
+    }
+
+    // Counts and logs a decision that a pause kept from being carried out
+    #holdDecision(action: DecisionAction, reason: DecisionReason): void {
+        this.state.recordHeldDecision();
+        console.info(`Scaling paused, holding ${action} (${reason})`);
+    }
+
+    #scaleEvent(
+        action: DecisionAction,
+        reason: DecisionReason,
+        instance: string | null = null,
+    ): ScaleEvent {
+        return {
+            action,
+            reason,
+            instance,
+            instanceCount: this.state.getInstanceCount(),
+        };
+    }
+
+    #pausedResponse(): Response {
+        return new Response("Service Unavailable: scaling is paused", {
+            status: 503,
+            headers: { "Retry-After": "30" },
+        });
+    }
+
+    // Records a scaling decision for export, when analyticsBucket is set
+    #recordDecision(
+        action: DecisionAction,
+        reason: DecisionReason,
+        instance: ContainerStub<Env> | string,
+        resumed: boolean,
+    ): void {
+        if (!this.config.analyticsBucket) {
+            return;
+        }
+        const name =
+            typeof instance === "string"
+                ? instance
+                : this.instanceManager.getContainerName(instance);
+        this.state.recordDecision(
+            action,
+            reason,
+            name,
+            resumed,
+            this.#getISO8601Now(),
+        );
+    }
+
     async #handleOptimisticScaleUp(): Promise<void> {
+        if (this.#isPaused()) {
+            this.#holdDecision("scale_up", "requests");
+            return;
+        }
+
         const reserved = this.state.tryReserveSlot();
         if (!reserved) {
             console.warn("Max instances reached, skipping scale-up");
             return;
         }
 
+        let name: string;
         try {
-            const container = await this.instanceManager.createInstance();
+            await this.hooks.before(this.#scaleEvent("scale_up", "requests"));
+            const { container, resumed } =
+                await this.#startInstanceTraced("requests");
             const state = await container.getState();
             await this.#trackNewInstance(container, state, 0);
             this.state.recordScaleUp(this.#getISO8601Now());
+            this.#recordDecision("scale_up", "requests", container, resumed);
+            name = this.instanceManager.getContainerName(container);
             console.info(
-                `Created new instance ${this.instanceManager.getContainerName(container)} due to threshold crossing`,
+                `Created new instance ${name} due to threshold crossing`,
             );
         } catch (error) {
             console.error("Error during scale-up:", error);
             this.state.releaseSlot();
+            return;
         }
+        await this.hooks.after(this.#scaleEvent("scale_up", "requests", name));
     }
 
-    async #scaleUp(): Promise<void> {
+    async #scaleUp(parent: Span): Promise<void> {
+        if (this.#isPaused()) {
+            this.#holdDecision("scale_up", "metrics");
+            return;
+        }
+
         const reserved = this.state.tryReserveSlot();
         if (!reserved) {
             console.warn("Max instances reached, skipping threshold scale-up");
             return;
         }
 
+        let name: string;
         try {
-            const container = await this.instanceManager.createInstance();
+            await this.hooks.before(this.#scaleEvent("scale_up", "metrics"));
+            const { container, resumed } = await this.#startInstance(parent);
             const state = await container.getState();
             await this.#trackNewInstance(container, state, 0);
             this.state.recordScaleUp(this.#getISO8601Now());
-            console.info(
-                `Created new instance ${this.instanceManager.getContainerName(container)}`,
-            );
+            this.#recordDecision("scale_up", "metrics", container, resumed);
+            name = this.instanceManager.getContainerName(container);
+            console.info(`Created new instance ${name}`);
         } catch (error) {
             console.error("Error scaling up:", error);
             this.state.releaseSlot();
+            return;
         }
+        await this.hooks.after(this.#scaleEvent("scale_up", "metrics", name));
     }
 
     async #scaleDown(): Promise<void> {
+        if (this.#isPaused()) {
+            this.#holdDecision("scale_down", "metrics");
+            return;
+        }
+
         const instancesToRemove = this.scaler.selectInstancesForRemoval();
 
         let scaledDown = false;
         for (const instance of instancesToRemove) {
             if (!instance.name) continue;
 
+            const reason = instance.healthy === 0 ? "unhealthy" : "metrics";
+            const event = this.#scaleEvent("scale_down", reason, instance.name);
+            if (!(await this.hooks.before(event))) {
+                continue;
+            }
+
             try {
                 await this.#drainInstance(instance.name);
                 scaledDown = true;
+                this.#recordDecision(
+                    "scale_down",
+                    reason,
+                    instance.name,
+                    false,
+                );
             } catch (error) {
                 console.error(
                     `Error draining instance ${instance.name}:`,
@@ -430,10 +805,13 @@ export class Autoscaler<Env> extends DurableObject<Env> {
             try {
                 const container = this.container.getByName(instance.name);
 
-                await this.instanceManager.performHealthCheck(
+                const passed = await this.instanceManager.performHealthCheck(
                     container,
                     instance.name,
                 );
+                if (passed) {
+                    this.#markReady(instance.name);
+                }
 
                 const instanceRecord = this.state.getInstanceByName(
                     instance.name,
@@ -444,15 +822,34 @@ export class Autoscaler<Env> extends DurableObject<Env> {
                         const monitorzData =
                             await this.instanceManager.fetchMonitorz(container);
 
-                        const cpu = monitorzData.cpu_usage ?? 0;
-                        const memory = monitorzData.memory_usage ?? 0;
-                        const disk = monitorzData.disk_usage ?? 0;
+                        // Keep the last known value for metrics the monitor
+                        // couldn't measure, rather than treating them as idle
+                        const measured = (metric: string) =>
+                            (monitorzData.status?.[metric] ?? "ok") === "ok";
+
+                        const cpu = measured("cpu")
+                            ? (monitorzData.cpu_usage ?? 0)
+                            : instanceRecord.current_cpu;
+                        const memory = measured("memory")
+                            ? (monitorzData.memory_usage ?? 0)
+                            : instanceRecord.current_memory_MiB;
+                        const disk = measured("disk")
+                            ? (monitorzData.disk_usage ?? 0)
+                            : instanceRecord.current_disk_GB;
+
+                        if (monitorzData.degraded) {
+                            console.warn(
+                                `Degraded metrics for instance ${instance.name}:`,
+                                monitorzData.errors,
+                            );
+                        }
 
                         this.state.updateMetrics(
                             instance.name,
                             cpu,
                             memory,
                             disk,
+                            monitorzData.protected === true,
                         );
                     } catch (metricsError) {
                         console.error(
@@ -471,6 +868,12 @@ export class Autoscaler<Env> extends DurableObject<Env> {
     }
 
     async #processDrainingInstances(): Promise<void> {
+        // Draining instances get no new requests, and are kept until
+        // scaling resumes
+        if (this.#isPaused()) {
+            return;
+        }
+
         const drainingInstances = this.state.getDrainingInstances();
 
         for (const instance of drainingInstances) {
@@ -504,7 +907,7 @@ export class Autoscaler<Env> extends DurableObject<Env> {
 
         if (drainingInfo.active_requests === 0) {
             try {
-                await this.instanceManager.destroyInstance(instanceName);
+                await this.#retireInstance(instanceName);
                 return;
             } catch (error) {
                 console.error(
@@ -525,7 +928,7 @@ export class Autoscaler<Env> extends DurableObject<Env> {
                     `Instance ${instanceName} did not drain within timeout (${drainingInfo.active_requests} active requests remaining). Proceeding with removal.`,
                 );
                 try {
-                    await this.instanceManager.destroyInstance(instanceName);
+                    await this.#retireInstance(instanceName);
                 } catch (error) {
                     console.error(
                         `Error removing timed-out instance ${instanceName}:`,
@@ -535,4 +938,60 @@ export class Autoscaler<Env> extends DurableObject<Env> {
             }
         }
     }
+
+    // Removes a drained instance, then runs afterScale for it
+    async #retireInstance(instanceName: string): Promise<void> {
+        const reason =
+            this.state.getInstanceByName(instanceName)?.healthy === 0
+                ? "unhealthy"
+                : "metrics";
+        await this.#removeInstance(instanceName);
+        await this.hooks.after(
+            this.#scaleEvent("scale_down", reason, instanceName),
+        );
+    }
+
+    // Moves a drained instance to standby while there's room, and destroys
+    // it otherwise
+    async #removeInstance(instanceName: string): Promise<void> {
+        const maxStandby = this.config.maxStandbyInstances ?? 0;
+        if (this.state.getStandbyCount() < maxStandby) {
+            try {
+                await this.instanceManager.standbyInstance(instanceName);
+                // Standby instances don't count toward maxInstances
+                this.state.syncCapacity();
+                return;
+            } catch (error) {
+                console.error(
+                    `Error moving instance ${instanceName} to standby, destroying it:`,
+                    error,
+                );
+            }
+        }
+        await this.instanceManager.destroyInstance(instanceName);
+    }
+
+    async #expireStandbyInstances(): Promise<void> {
+        if (this.#isPaused()) {
+            return;
+        }
+
+        const standbyTimeout = this.config.standbyTimeout ?? 3_600_000;
+        const maxStandby = this.config.maxStandbyInstances ?? 0;
+        const standby = this.state.getStandbyInstances();
+
+        // Oldest first, so lowering maxStandbyInstances trims the coldest
+        for (const [i, instance] of standby.entries()) {
+            const age =
+                Date.now() - new Date(instance.standby_since).getTime();
+            if (age >= standbyTimeout || standby.length - i > maxStandby) {
+                console.info(
+                    `Destroying standby instance ${instance.name} after ${Math.round(age / 1000)}s`,
+                );
+                await this.instanceManager.destroyStandbyInstance(
+                    instance.name,
+                );
+            }
+        }
+    }
 }
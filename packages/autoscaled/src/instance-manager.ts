@@ -13,6 +13,12 @@ import type {
 } from "./types.js";
 import { AutoscalerState } from "./state.js";
 
+// How often, and for how long, a started instance's health check is
+// polled to time its boot. An instance still booting after that is timed
+// by the heartbeat's health checks instead
+const READY_POLL_INTERVAL = 500;
+const READY_TIMEOUT = 120_000;
+
 export class InstanceManager<Env = unknown> {
     constructor(
         private state: AutoscalerState,
@@ -39,6 +45,33 @@ export class InstanceManager<Env = unknown> {
         }
     }
 
+    // Stops the container but keeps it, so resuming it later skips
+    // provisioning a new one
+    async standbyInstance(name: string): Promise<void> {
+        const container = this.container.getByName(name);
+        await container.stop();
+        this.state.removeInstance(name);
+        this.state.addStandbyInstance(name, this.getNow());
+        console.info(`Moved instance ${name} to standby`);
+    }
+
+    async resumeInstance(name: string): Promise<ContainerStub<Env>> {
+        const container = this.container.getByName(name);
+        await container.startAndWaitForPorts();
+        console.info(`Resumed standby instance ${name}`);
+        return container;
+    }
+
+    async destroyStandbyInstance(name: string): Promise<void> {
+        try {
+            const container = this.container.getByName(name);
+            await container.destroy();
+        } catch (error) {
+            console.error(`Error destroying standby instance ${name}:`, error);
+        }
+        this.state.removeStandbyInstance(name);
+    }
+
     async replaceInstance(
         container: ContainerStub<Env>,
     ): Promise<ContainerWithState<Env>> {
@@ -54,18 +87,37 @@ export class InstanceManager<Env = unknown> {
         };
     }
 
+    // Polls a started instance's health check until it passes, without
+    // counting failures against it while it boots. Returns false if it
+    // didn't pass within READY_TIMEOUT
+    async waitUntilReady(container: ContainerStub<Env>): Promise<boolean> {
+        const deadline = Date.now() + READY_TIMEOUT;
+        while (Date.now() < deadline) {
+            try {
+                const response = await container.containerFetch(
+                    this.healthUrl(),
+                );
+                if (response.ok) {
+                    return true;
+                }
+            } catch {
+                // Not listening yet
+            }
+            await new Promise((resolve) =>
+                setTimeout(resolve, READY_POLL_INTERVAL),
+            );
+        }
+        return false;
+    }
+
     async performHealthCheck(
         container: ContainerStub<Env>,
         instanceName: string,
     ): Promise<boolean> {
-        const healthEndpoint = this.config.monitoringEndpoint ?? "/healthz";
-        const healthUrl = healthEndpoint.startsWith("http")
-            ? healthEndpoint
-            : `http://localhost:8080${healthEndpoint}`;
         const now = this.getNow();
 
         try {
-            const response = await container.containerFetch(healthUrl);
+            const response = await container.containerFetch(this.healthUrl());
             const isHealthy = response.ok;
 
             if (isHealthy) {
@@ -101,6 +153,13 @@ export class InstanceManager<Env = unknown> {
         }
     }
 
+    private healthUrl(): string {
+        const healthEndpoint = this.config.monitoringEndpoint ?? "/healthz";
+        return healthEndpoint.startsWith("http")
+            ? healthEndpoint
+            : `http://localhost:8080${healthEndpoint}`;
+    }
+
     async fetchMonitorz(container: ContainerStub<Env>): Promise<MonitorzData> {
         const url = this.config.monitorzURL ?? "http://localhost:81/monitorz";
         const response = await container.containerFetch(url);
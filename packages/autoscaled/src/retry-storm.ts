@@ -0,0 +1,121 @@
+import type { AutoscalerConfig } from "./types.js";
+
+// Keyed requests a window needs before it can count as a storm, so a
+// handful of ordinary retries don't trip it
+const MIN_STORM_REQUESTS = 20;
+
+// Caps the idempotency keys tracked per window, so a flood of unique keys
+// can't grow memory without bound
+export const MAX_TRACKED_KEYS = 10_000;
+
+export interface RetryStormStatus {
+    active: boolean;
+    since: string | null; // ISO 8601
+    // Attempts per idempotency key in the current window
+    ratio: number;
+}
+
+// Detects retry storms by comparing how many requests carry an idempotency
+// key with how many distinct keys there are. Counts are kept in memory per
+// window, since a storm only matters while it's happening
+export class RetryStormDetector {
+    private windowStart = 0;
+    private requests = 0;
+    private attempts = new Map<string, number>();
+    private since: string | null = null;
+
+    constructor(
+        // Read on each request, since subclasses set config after the base
+        // constructor
+        private config: () => AutoscalerConfig,
+        private getNow: () => string,
+    ) {}
+
+    // Counts the request, and returns false for a retry that should be
+    // turned away because a storm is under way: each key gets
+    // retryStormThreshold attempts per window
+    admit(request: Request): boolean {
+        const config = this.config();
+        const threshold = config.retryStormThreshold;
+        if (!threshold) {
+            return true;
+        }
+        const key = request.headers.get(
+            config.retryKeyHeader ?? "Idempotency-Key",
+        );
+        if (!key) {
+            return true;
+        }
+
+        this.roll(Date.now());
+        const attempts = (this.attempts.get(key) ?? 0) + 1;
+        // Only tracked keys count towards the ratio: counting requests for
+        // keys past the cap, without their keys, would read a flood of
+        // unique keys as a storm
+        if (attempts > 1 || this.attempts.size < MAX_TRACKED_KEYS) {
+            this.requests++;
+            this.attempts.set(key, attempts);
+        }
+
+        const ratio = this.ratio();
+        if (
+            !this.since &&
+            this.requests >= MIN_STORM_REQUESTS &&
+            ratio >= threshold
+        ) {
+            this.since = this.getNow();
+            console.warn(
+                `Retry storm: ${this.requests} requests for ${this.attempts.size} idempotency keys (${ratio.toFixed(1)} attempts each); turning away attempts beyond ${threshold} per key and holding request-based scale-up`,
+            );
+        }
+
+        return !this.since || attempts <= threshold;
+    }
+
+    // Whether a storm is under way, in which case the request rate is
+    // inflated by retries and shouldn't drive scale-up
+    active(): boolean {
+        this.roll(Date.now());
+        return this.since !== null;
+    }
+
+    status(): RetryStormStatus {
+        this.roll(Date.now());
+        return {
+            active: this.since !== null,
+            since: this.since,
+            ratio: Math.round(this.ratio() * 10) / 10,
+        };
+    }
+
+    private ratio(): number {
+        return this.attempts.size > 0 ? this.requests / this.attempts.size : 0;
+    }
+
+    // Starts a new window once the current one has run its length. A storm
+    // ends with the first window that doesn't look like one, including an
+    // empty window that no request arrived to roll over
+    private roll(now: number): void {
+        const config = this.config();
+        const window = config.retryStormWindow ?? 10_000;
+        if (now - this.windowStart < window) {
+            return;
+        }
+
+        const threshold = config.retryStormThreshold ?? 0;
+        const calm =
+            !threshold ||
+            now - this.windowStart >= 2 * window ||
+            this.requests < MIN_STORM_REQUESTS ||
+            this.ratio() < threshold;
+        if (this.since && calm) {
+            console.info(
+                `Retry storm over after ${Math.round((now - Date.parse(this.since)) / 1000)}s`,
+            );
+            this.since = null;
+        }
+        this.windowStart = now;
+        this.requests = 0;
+        this.attempts.clear();
+    }
+}
@@ -13,6 +13,7 @@ export class Router {
         const instances = this.state.getInstances({
             healthy: true,
             notDraining: true,
+            notCordoned: true,
             belowCapacity: maxRequestsPerInstance,
         });
 
@@ -23,6 +24,7 @@ export class Router {
         const anyHealthy = this.state.getInstances({
             healthy: true,
             notDraining: true,
+            notCordoned: true,
         });
 
         if (anyHealthy.length > 0) {
@@ -73,6 +75,37 @@ export class Router {
         return justCrossed;
     }
 
+    // Percent of the fleet's capacity, counting instances that could still be
+    // started up to maxInstances, that's free. An instance's load is its
+    // share of maxRequestsPerInstance when that's set, and its highest
+    // compute usage otherwise
+    getCapacityRemaining(): number {
+        const maxRequestsPerInstance = this.config.maxRequestsPerInstance;
+        const instances = this.state.getInstances({
+            healthy: true,
+            notDraining: true,
+            notCordoned: true,
+        });
+
+        let used = 0;
+        for (const instance of instances) {
+            const load = maxRequestsPerInstance
+                ? instance.active_requests / maxRequestsPerInstance
+                : Math.max(
+                      instance.current_cpu,
+                      instance.current_memory_MiB,
+                      instance.current_disk_GB,
+                  ) / 100;
+            used += Math.min(Math.max(load, 0), 1);
+        }
+
+        const capacity = Math.max(this.config.maxInstances, instances.length);
+        if (capacity === 0) {
+            return 0;
+        }
+        return Math.round(100 * (1 - used / capacity));
+    }
+
     getAtCapacityCount(): number {
         const maxRequestsPerInstance = this.config.maxRequestsPerInstance;
 
@@ -83,6 +116,7 @@ export class Router {
         const instances = this.state.getInstances({
             healthy: true,
             notDraining: true,
+            notCordoned: true,
         });
 
         return instances.filter(
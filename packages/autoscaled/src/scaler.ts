@@ -1,4 +1,10 @@
-import type { AutoscalerConfig, InstanceRecord } from "./types.js";
+import type {
+    AutoscalerConfig,
+    CooldownExplanation,
+    Explanation,
+    InstanceRecord,
+    Thresholds,
+} from "./types.js";
 import { AutoscalerState } from "./state.js";
 
 export class Scaler {
@@ -62,18 +68,25 @@ export class Scaler {
             return false;
         }
 
+        return (
+            this.averageRequestsPerInstance() >
+            this.config.maxRequestsPerInstance
+        );
+    }
+
+    averageRequestsPerInstance(): number {
+        // Cordoned instances take no new requests, so they add no capacity
         const instances = this.state.getInstances({
             healthy: true,
             notDraining: true,
+            notCordoned: true,
         });
+        const servingCount = this.state.getServingCount();
         const totalRequests = instances.reduce(
             (sum, inst) => sum + inst.active_requests,
             0,
         );
-        const avgRequestsPerInstance =
-            currentCount > 0 ? totalRequests / currentCount : 0;
-
-        return avgRequestsPerInstance > this.config.maxRequestsPerInstance;
+        return servingCount > 0 ? totalRequests / servingCount : 0;
     }
 
     shouldScaleUpForMetrics(): boolean {
@@ -89,13 +102,15 @@ export class Scaler {
         }
 
         // Check if thresholds are configured
-        if (!this.hasSpecificThresholds && !this.config.scaleThreshold) {
+        const thresholds = this.calculateScaleUpThresholds();
+        if (!thresholds) {
             return false;
         }
 
         const instances = this.state.getInstances({
             healthy: true,
             notDraining: true,
+            notCordoned: true,
         });
 
         if (instances.length === 0) {
@@ -108,37 +123,17 @@ export class Scaler {
         // Check if any instance is crossing thresholds (not already crossed recently)
         for (const instance of instances) {
             // Check if instance is eligible (hasn't crossed recently)
-            const canCross =
-                !instance.threshold_crossed_at ||
-                now - new Date(instance.threshold_crossed_at).getTime() >=
-                    cooldown;
-
-            if (!canCross) {
+            if (this.crossedRecently(instance, now, cooldown)) {
                 continue;
             }
 
-            // Check if exceeds thresholds
-            let exceedsThreshold = false;
-
-            if (this.hasAllSpecificThresholds) {
-                const cpuThreshold = this.config.scaleThesholdCPU ?? 0;
-                const memoryThreshold = this.config.scaleThesholdMemoryMiB ?? 0;
-                const diskThreshold = this.config.scaleThesholdDiskGB ?? 0;
-
-                exceedsThreshold =
-                    instance.current_cpu > cpuThreshold ||
-                    instance.current_memory_MiB > memoryThreshold ||
-                    instance.current_disk_GB > diskThreshold;
-            } else if (this.config.scaleThreshold !== undefined) {
-                const threshold = this.config.scaleThreshold;
-
-                exceedsThreshold =
-                    instance.current_cpu > threshold ||
-                    instance.current_memory_MiB > threshold ||
-                    instance.current_disk_GB > threshold;
+            // Discount instances still warming up
+            const weight = this.warmupWeight(instance, now);
+            if (weight === 0) {
+                continue;
             }
 
-            if (exceedsThreshold) {
+            if (this.exceedsScaleUpThresholds(instance, weight, thresholds)) {
                 // Mark this instance as having crossed
                 this.state.markThresholdCrossed(
                     instance.name,
@@ -154,6 +149,40 @@ export class Scaler {
         return false;
     }
 
+    crossedRecently(
+        instance: InstanceRecord,
+        now: number,
+        cooldown: number,
+    ): boolean {
+        return (
+            !!instance.threshold_crossed_at &&
+            now - new Date(instance.threshold_crossed_at).getTime() < cooldown
+        );
+    }
+
+    exceedsScaleUpThresholds(
+        instance: InstanceRecord,
+        weight: number,
+        thresholds: Thresholds,
+    ): boolean {
+        return (
+            instance.current_cpu * weight > thresholds.cpu ||
+            instance.current_memory_MiB * weight > thresholds.memory ||
+            instance.current_disk_GB * weight > thresholds.disk
+        );
+    }
+
+    warmupWeight(instance: InstanceRecord, now: number): number {
+        const warmupPeriod = this.config.warmupPeriod ?? 0;
+        const age = now - new Date(instance.created_at).getTime();
+        if (age >= warmupPeriod) {
+            return 1;
+        }
+
+        const weight = this.config.warmupWeight ?? 0;
+        return Math.min(Math.max(weight, 0), 1);
+    }
+
     isInScaleUpCooldown(): boolean {
         const lastScaleUp = this.state.getLastScaleUp();
         if (!lastScaleUp) {
@@ -170,7 +199,7 @@ export class Scaler {
 
     shouldScaleDown(): boolean {
         const minInstances = this.config.minInstances ?? 0;
-        const currentCount = this.state.getInstanceCount();
+        const currentCount = this.state.getServingCount();
 
         // Don't scale down below minimum
         if (currentCount <= minInstances) {
@@ -185,6 +214,7 @@ export class Scaler {
         const instances = this.state.getInstances({
             healthy: true,
             notDraining: true,
+            notCordoned: true,
         });
 
         if (instances.length === 0) {
@@ -220,14 +250,16 @@ export class Scaler {
     }
 
     selectInstancesForRemoval(): InstanceRecord[] {
+        // Cordoned instances are kept for debugging and don't count toward
+        // minInstances, since they serve nothing
         const minInstances = this.config.minInstances ?? 0;
-        const currentCount = this.state.getInstanceCount();
+        const currentCount = this.state.getServingCount();
 
         let instancesToRemove: InstanceRecord[] = [];
 
         // First, find unhealthy instances (not already draining)
         const unhealthyInstances = this.state
-            .getInstances({ healthy: false })
+            .getInstances({ healthy: false, notCordoned: true })
             .filter((inst) => !inst.draining);
         instancesToRemove.push(...unhealthyInstances);
 
@@ -235,15 +267,17 @@ export class Scaler {
         if (currentCount - instancesToRemove.length > minInstances) {
             const thresholds = this.calculateScaleDownThresholds();
 
-            // Get healthy, non-draining instances below thresholds
+            // Get healthy, non-draining, unprotected instances below thresholds
             const allInstances = this.state.getInstances({
                 healthy: true,
                 notDraining: true,
+                notCordoned: true,
             });
 
             const belowThreshold = allInstances
                 .filter(
                     (inst) =>
+                        !inst.protected &&
                         inst.current_cpu <= thresholds.cpu &&
                         inst.current_memory_MiB <= thresholds.memory &&
                         inst.current_disk_GB <= thresholds.disk,
@@ -269,13 +303,157 @@ export class Scaler {
         return instancesToRemove.slice(0, maxRemovals);
     }
 
+    // Explanation
+
+    // Evaluates what the next heartbeat would decide, without recording
+    // threshold crossings as the evaluation itself does
+    explain(now: number): Explanation {
+        const minInstances = this.config.minInstances ?? 0;
+        const maxInstances = this.config.maxInstances;
+        const healthyCount = this.state.getInstanceCount();
+        const servingCount = this.state.getServingCount();
+        const instances = this.state.getInstances({
+            healthy: true,
+            notDraining: true,
+            notCordoned: true,
+        });
+
+        const scaleUpCooldown = this.config.scaleUpCooldown ?? 60_000;
+        const upThresholds = this.calculateScaleUpThresholds();
+        const upInstances = instances.map((instance) => {
+            const weight = this.warmupWeight(instance, now);
+            const recentlyCrossed = this.crossedRecently(
+                instance,
+                now,
+                scaleUpCooldown,
+            );
+            return {
+                name: instance.name,
+                cpu: instance.current_cpu,
+                memory: instance.current_memory_MiB,
+                disk: instance.current_disk_GB,
+                weight,
+                recentlyCrossed,
+                exceeds:
+                    !!upThresholds &&
+                    weight > 0 &&
+                    this.exceedsScaleUpThresholds(
+                        instance,
+                        weight,
+                        upThresholds,
+                    ),
+            };
+        });
+        const maxPerInstance = this.config.maxRequestsPerInstance ?? null;
+        const averagePerInstance = this.averageRequestsPerInstance();
+        const requestsTriggered =
+            !!maxPerInstance && averagePerInstance > maxPerInstance;
+        const metricsTriggered = upInstances.some(
+            (instance) => instance.exceeds && !instance.recentlyCrossed,
+        );
+        const upCooldown = explainCooldown(
+            this.state.getLastScaleUp(),
+            scaleUpCooldown,
+            now,
+        );
+        // The heartbeat only scales up on metrics; request-based scale-up
+        // happens as requests arrive
+        let upBlockedBy: Explanation["scaleUp"]["blockedBy"] = null;
+        if (metricsTriggered) {
+            if (healthyCount >= maxInstances) {
+                upBlockedBy = "max_instances";
+            } else if (upCooldown.remaining > 0) {
+                upBlockedBy = "cooldown";
+            }
+        }
+
+        const downThresholds = this.calculateScaleDownThresholds();
+        const downInstances = instances.map((instance) => ({
+            name: instance.name,
+            cpu: instance.current_cpu,
+            memory: instance.current_memory_MiB,
+            disk: instance.current_disk_GB,
+            below: !this.metricsExceedThresholds(instance, downThresholds),
+        }));
+        const downTriggered =
+            downInstances.length > 0 &&
+            downInstances.every((instance) => instance.below);
+        const downCooldown = explainCooldown(
+            this.state.getLastScaleDown(),
+            this.config.scaleDownCooldown ?? 120_000,
+            now,
+        );
+        let downBlockedBy: Explanation["scaleDown"]["blockedBy"] = null;
+        if (downTriggered) {
+            if (servingCount <= minInstances) {
+                downBlockedBy = "min_instances";
+            } else if (downCooldown.remaining > 0) {
+                downBlockedBy = "cooldown";
+            }
+        }
+        const scaleDown = downTriggered && !downBlockedBy;
+
+        return {
+            at: new Date(now).toISOString(),
+            instances: {
+                serving: servingCount,
+                healthy: healthyCount,
+                min: minInstances,
+                max: maxInstances,
+            },
+            scaleUp: {
+                action: metricsTriggered && !upBlockedBy ? "scale_up" : "none",
+                blockedBy: upBlockedBy,
+                cooldown: upCooldown,
+                requests: {
+                    maxPerInstance,
+                    averagePerInstance,
+                    triggered: requestsTriggered,
+                },
+                metrics: {
+                    thresholds: upThresholds,
+                    instances: upInstances,
+                    triggered: metricsTriggered,
+                },
+            },
+            scaleDown: {
+                action: scaleDown ? "scale_down" : "none",
+                blockedBy: downBlockedBy,
+                cooldown: downCooldown,
+                thresholds: downThresholds,
+                instances: downInstances,
+                triggered: downTriggered,
+                candidates: scaleDown
+                    ? this.selectInstancesForRemoval().map(
+                          (instance) => instance.name,
+                      )
+                    : [],
+            },
+        };
+    }
+
     // Helpers
 
-    calculateScaleDownThresholds(): {
-        cpu: number;
-        memory: number;
-        disk: number;
-    } {
+    // Returns null when no scale-up threshold is configured
+    calculateScaleUpThresholds(): Thresholds | null {
+        if (!this.hasSpecificThresholds && !this.config.scaleThreshold) {
+            return null;
+        }
+        if (this.hasAllSpecificThresholds) {
+            return {
+                cpu: this.config.scaleThesholdCPU ?? 0,
+                memory: this.config.scaleThesholdMemoryMiB ?? 0,
+                disk: this.config.scaleThesholdDiskGB ?? 0,
+            };
+        }
+        if (this.config.scaleThreshold !== undefined) {
+            const threshold = this.config.scaleThreshold;
+            return { cpu: threshold, memory: threshold, disk: threshold };
+        }
+        return null;
+    }
+
+    calculateScaleDownThresholds(): Thresholds {
         if (this.hasAllSpecificThresholds) {
             const scaleUpCPU = this.config.scaleThesholdCPU ?? 0;
             const scaleUpMemory = this.config.scaleThesholdMemoryMiB ?? 0;
@@ -302,7 +480,7 @@ export class Scaler {
 
     metricsExceedThresholds(
         instance: InstanceRecord,
-        thresholds: { cpu: number; memory: number; disk: number },
+        thresholds: Thresholds,
     ): boolean {
         return (
             instance.current_cpu > thresholds.cpu ||
@@ -311,3 +489,12 @@ export class Scaler {
         );
     }
 }
+
+function explainCooldown(
+    last: string | null,
+    cooldown: number,
+    now: number,
+): CooldownExplanation {
+    const remaining = last ? cooldown - (now - new Date(last).getTime()) : 0;
+    return { cooldown, last, remaining: Math.max(0, remaining) };
+}
@@ -1,11 +1,27 @@
 import type { DurableObjectStorage } from "@cloudflare/workers-types";
 import type {
     InstanceRecord,
+    StandbyRecord,
     InstanceFilter,
     CapacityInfo,
     ScalingState,
+    PauseState,
+    DecisionAction,
+    DecisionReason,
+    DecisionRecord,
+    ReplicaRecord,
+    BootRecord,
 } from "./types.js";
 
+// History rows kept per table while exports are failing, so storage stays
+// bounded; the oldest are dropped first
+const MAX_HISTORY_ROWS = 50_000;
+
+// Boots kept for boot-time percentiles, the oldest dropped first
+const MAX_BOOT_ROWS = 1_000;
+
+export type HistoryTable = "decision_history" | "replica_history";
+
 export class AutoscalerState {
     constructor(
         private sql: DurableObjectStorage["sql"],
@@ -51,6 +67,7 @@ export class AutoscalerState {
 
     removeInstance(name: string): void {
         this.sql.exec(`DELETE FROM instances WHERE name = ?`, name);
+        this.sql.exec(`DELETE FROM booting_instances WHERE name = ?`, name);
     }
 
     getInstances(filter?: InstanceFilter): InstanceRecord[] {
@@ -65,6 +82,10 @@ export class AutoscalerState {
             conditions.push(`(draining IS NULL OR draining = 0)`);
         }
 
+        if (filter?.notCordoned) {
+            conditions.push(`(cordoned IS NULL OR cordoned = 0)`);
+        }
+
         if (filter?.belowCapacity !== undefined) {
             conditions.push(`active_requests < ${filter.belowCapacity}`);
         }
@@ -86,6 +107,17 @@ export class AutoscalerState {
         return result[0]?.count ?? 0;
     }
 
+    // Counts the healthy instances that take traffic, which is what the
+    // scaler plans capacity with
+    getServingCount(): number {
+        const cursor = this.sql.exec<{ count: number }>(
+            `SELECT COUNT(*) as count FROM instances
+             WHERE healthy = 1 AND (cordoned IS NULL OR cordoned = 0)`,
+        );
+        const result = cursor.toArray();
+        return result[0]?.count ?? 0;
+    }
+
     getInstanceByName(name: string): InstanceRecord | null {
         const cursor = this.sql.exec<InstanceRecord>(
             `SELECT * FROM instances WHERE name = ?`,
@@ -267,20 +299,49 @@ export class AutoscalerState {
         cpu: number,
         memory: number,
         disk: number,
+        isProtected: boolean,
     ): void {
         this.sql.exec(
             `UPDATE instances SET
                 current_cpu = ?,
                 current_memory_MiB = ?,
-                current_disk_GB = ?
+                current_disk_GB = ?,
+                protected = ?
              WHERE name = ?`,
             cpu,
             memory,
             disk,
+            isProtected ? 1 : 0,
             name,
         );
     }
 
+    getProtectedCount(): number {
+        const cursor = this.sql.exec<{ count: number }>(
+            `SELECT COUNT(*) as count FROM instances WHERE protected = 1`,
+        );
+        const result = cursor.toArray();
+        return result[0]?.count ?? 0;
+    }
+
+    // Returns false when there's no such instance
+    setCordoned(name: string, cordoned: boolean): boolean {
+        const cursor = this.sql.exec<{ name: string }>(
+            `UPDATE instances SET cordoned = ? WHERE name = ? RETURNING name`,
+            cordoned ? 1 : 0,
+            name,
+        );
+        return cursor.toArray().length > 0;
+    }
+
+    getCordonedInstances(): InstanceRecord[] {
+        return this.sql
+            .exec<InstanceRecord>(
+                `SELECT * FROM instances WHERE cordoned = 1 ORDER BY name`,
+            )
+            .toArray();
+    }
+
     updateHeartbeat(name: string, now: string): void {
         this.sql.exec(
             `UPDATE instances SET
@@ -328,6 +389,92 @@ export class AutoscalerState {
         return result[0] ?? null;
     }
 
+    // Returns the stored pause, which pausedByConfig overrides
+    getPause(pausedByConfig: boolean = false): PauseState {
+        const cursor = this.sql.exec<{
+            paused: number;
+            reason: string | null;
+            since: string | null;
+            held: number;
+        }>(
+            `SELECT paused, reason, since, held FROM scaling_pause WHERE id = 1`,
+        );
+        const result = cursor.toArray()[0];
+
+        const pause: PauseState = {
+            paused: result?.paused === 1,
+            reason: result?.reason ?? null,
+            since: result?.since ?? null,
+            held: result?.held ?? 0,
+        };
+        if (pausedByConfig && !pause.paused) {
+            return { ...pause, paused: true, reason: "paused config option" };
+        }
+        return pause;
+    }
+
+    // Pausing again keeps when the pause started and what it held
+    setPaused(paused: boolean, reason: string | null, now: string): void {
+        if (paused) {
+            this.sql.exec(
+                `UPDATE scaling_pause SET
+                    held = CASE WHEN paused = 1 THEN held ELSE 0 END,
+                    since = CASE WHEN paused = 1 THEN since ELSE ? END,
+                    paused = 1,
+                    reason = ?
+                 WHERE id = 1`,
+                now,
+                reason,
+            );
+        } else {
+            this.sql.exec(`UPDATE scaling_pause SET paused = 0 WHERE id = 1`);
+        }
+    }
+
+    recordHeldDecision(): void {
+        this.sql.exec(`UPDATE scaling_pause SET held = held + 1 WHERE id = 1`);
+    }
+
+    addStandbyInstance(name: string, now: string): void {
+        this.sql.exec(
+            `INSERT OR REPLACE INTO standby_instances (name, standby_since) VALUES (?, ?)`,
+            name,
+            now,
+        );
+    }
+
+    removeStandbyInstance(name: string): void {
+        this.sql.exec(`DELETE FROM standby_instances WHERE name = ?`, name);
+    }
+
+    getStandbyInstances(): StandbyRecord[] {
+        return this.sql
+            .exec<StandbyRecord>(
+                `SELECT * FROM standby_instances ORDER BY standby_since ASC`,
+            )
+            .toArray();
+    }
+
+    getStandbyCount(): number {
+        const cursor = this.sql.exec<{ count: number }>(
+            `SELECT COUNT(*) as count FROM standby_instances`,
+        );
+        const result = cursor.toArray();
+        return result[0]?.count ?? 0;
+    }
+
+    // Claims the most recently stopped standby instance, whose container is
+    // the likeliest to still be warm on its host
+    takeStandbyInstance(): StandbyRecord | null {
+        const cursor = this.sql.exec<StandbyRecord>(
+            `DELETE FROM standby_instances
+             WHERE name = (SELECT name FROM standby_instances ORDER BY standby_since DESC LIMIT 1)
+             RETURNING name, standby_since`,
+        );
+        const result = cursor.toArray();
+        return result[0] ?? null;
+    }
+
     markThresholdCrossed(name: string, now: string): void {
         this.sql.exec(
             `UPDATE instances SET threshold_crossed_at = ? WHERE name = ?`,
@@ -336,6 +483,140 @@ export class AutoscalerState {
         );
     }
 
+    recordDecision(
+        action: DecisionAction,
+        reason: DecisionReason,
+        instance: string,
+        resumed: boolean,
+        now: string,
+    ): void {
+        this.sql.exec(
+            `INSERT INTO decision_history (at, action, reason, instance, resumed, instance_count)
+             VALUES (?, ?, ?, ?, ?, (SELECT COUNT(*) FROM instances WHERE healthy = 1))`,
+            now,
+            action,
+            reason,
+            instance,
+            resumed ? 1 : 0,
+        );
+        this.trimHistory("decision_history");
+    }
+
+    // Records how many replicas there are and how loaded they are
+    recordReplicas(now: string): void {
+        this.sql.exec(
+            `INSERT INTO replica_history (at, instance_count, healthy_count, draining_count, standby_count, active_requests, avg_cpu, avg_memory, avg_disk)
+             SELECT ?,
+                 COUNT(*),
+                 COALESCE(SUM(healthy = 1), 0),
+                 COALESCE(SUM(draining = 1), 0),
+                 (SELECT COUNT(*) FROM standby_instances),
+                 COALESCE(SUM(active_requests), 0),
+                 AVG(CASE WHEN healthy = 1 THEN current_cpu END),
+                 AVG(CASE WHEN healthy = 1 THEN current_memory_MiB END),
+                 AVG(CASE WHEN healthy = 1 THEN current_disk_GB END)
+             FROM instances`,
+            now,
+        );
+        this.trimHistory("replica_history");
+    }
+
+    // Records when the create or resume call that started an instance was
+    // made, until the instance passes a health check
+    recordBootStart(name: string, startedAt: string, resumed: boolean): void {
+        this.sql.exec(
+            `INSERT OR REPLACE INTO booting_instances (name, started_at, resumed) VALUES (?, ?, ?)`,
+            name,
+            startedAt,
+            resumed ? 1 : 0,
+        );
+    }
+
+    // Records the boot of an instance that passed a health check, and
+    // returns it. Returns null if the instance isn't booting, so only the
+    // first check that passes counts
+    markReady(name: string, now: string): BootRecord | null {
+        const booting = this.sql
+            .exec<{ started_at: string; resumed: number }>(
+                `DELETE FROM booting_instances WHERE name = ? RETURNING started_at, resumed`,
+                name,
+            )
+            .toArray()[0];
+        if (!booting) {
+            return null;
+        }
+
+        const cursor = this.sql.exec<BootRecord>(
+            `INSERT INTO boot_history (instance, started_at, ready_at, boot_ms, resumed)
+             VALUES (?, ?, ?, ?, ?)
+             RETURNING *`,
+            name,
+            booting.started_at,
+            now,
+            Math.max(0, Date.parse(now) - Date.parse(booting.started_at)),
+            booting.resumed,
+        );
+        const boot = cursor.toArray()[0] ?? null;
+        this.sql.exec(
+            `DELETE FROM boot_history WHERE id <= (SELECT MAX(id) FROM boot_history) - ?`,
+            MAX_BOOT_ROWS,
+        );
+        return boot;
+    }
+
+    getBootHistory(): BootRecord[] {
+        return this.sql
+            .exec<BootRecord>(`SELECT * FROM boot_history ORDER BY id`)
+            .toArray();
+    }
+
+    getBootingCount(): number {
+        const cursor = this.sql.exec<{ count: number }>(
+            `SELECT COUNT(*) as count FROM booting_instances`,
+        );
+        const result = cursor.toArray();
+        return result[0]?.count ?? 0;
+    }
+
+    getDecisionHistory(): DecisionRecord[] {
+        return this.sql
+            .exec<DecisionRecord>(`SELECT * FROM decision_history ORDER BY id`)
+            .toArray();
+    }
+
+    getReplicaHistory(): ReplicaRecord[] {
+        return this.sql
+            .exec<ReplicaRecord>(`SELECT * FROM replica_history ORDER BY id`)
+            .toArray();
+    }
+
+    // Deletes rows that have been exported, up to and including throughId
+    deleteHistory(table: HistoryTable, throughId: number): void {
+        this.sql.exec(`DELETE FROM ${table} WHERE id <= ?`, throughId);
+    }
+
+    private trimHistory(table: HistoryTable): void {
+        this.sql.exec(
+            `DELETE FROM ${table} WHERE id <= (SELECT MAX(id) FROM ${table}) - ?`,
+            MAX_HISTORY_ROWS,
+        );
+    }
+
+    getLastHistoryExport(): string | null {
+        const cursor = this.sql.exec<{ last_export: string | null }>(
+            `SELECT last_export FROM history_export WHERE id = 1`,
+        );
+        const result = cursor.toArray();
+        return result[0]?.last_export ?? null;
+    }
+
+    recordHistoryExport(now: string): void {
+        this.sql.exec(
+            `UPDATE history_export SET last_export = ? WHERE id = 1`,
+            now,
+        );
+    }
+
     migrate(maxInstances: number): void {
         this.sql.exec(`
             CREATE TABLE IF NOT EXISTS instances (
@@ -352,7 +633,9 @@ export class AutoscalerState {
                 draining_since TEXT,
                 health_check_failures INTEGER DEFAULT 0,
                 last_health_check TEXT,
-                threshold_crossed_at TEXT
+                threshold_crossed_at TEXT,
+                protected INTEGER DEFAULT 0,
+                cordoned INTEGER DEFAULT 0
             );
 
             CREATE TABLE IF NOT EXISTS scaling_state (
@@ -364,16 +647,104 @@ export class AutoscalerState {
             INSERT OR IGNORE INTO scaling_state (id, last_scale_up, last_scale_down)
             VALUES (1, NULL, NULL);
 
+            CREATE TABLE IF NOT EXISTS scaling_pause (
+                id INTEGER PRIMARY KEY CHECK (id = 1),
+                paused INTEGER NOT NULL,
+                reason TEXT,
+                since TEXT,
+                held INTEGER NOT NULL
+            );
+
+            INSERT OR IGNORE INTO scaling_pause (id, paused, reason, since, held)
+            VALUES (1, 0, NULL, NULL, 0);
+
+            CREATE TABLE IF NOT EXISTS standby_instances (
+                name TEXT PRIMARY KEY,
+                standby_since TEXT NOT NULL
+            );
+
             CREATE TABLE IF NOT EXISTS instance_capacity (
                 id INTEGER PRIMARY KEY CHECK (id = 1),
                 current_count INTEGER NOT NULL,
                 max_count INTEGER NOT NULL
             );
 
+            CREATE TABLE IF NOT EXISTS decision_history (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                at TEXT NOT NULL,
+                action TEXT NOT NULL,
+                reason TEXT NOT NULL,
+                instance TEXT NOT NULL,
+                resumed INTEGER NOT NULL,
+                instance_count INTEGER NOT NULL
+            );
+
+            CREATE TABLE IF NOT EXISTS replica_history (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                at TEXT NOT NULL,
+                instance_count INTEGER NOT NULL,
+                healthy_count INTEGER NOT NULL,
+                draining_count INTEGER NOT NULL,
+                standby_count INTEGER NOT NULL,
+                active_requests INTEGER NOT NULL,
+                avg_cpu REAL,
+                avg_memory REAL,
+                avg_disk REAL
+            );
+
+            CREATE TABLE IF NOT EXISTS history_export (
+                id INTEGER PRIMARY KEY CHECK (id = 1),
+                last_export TEXT
+            );
+
+            INSERT OR IGNORE INTO history_export (id, last_export)
+            VALUES (1, NULL);
+
+            CREATE TABLE IF NOT EXISTS booting_instances (
+                name TEXT PRIMARY KEY,
+                started_at TEXT NOT NULL,
+                resumed INTEGER NOT NULL
+            );
+
+            CREATE TABLE IF NOT EXISTS boot_history (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                instance TEXT NOT NULL,
+                started_at TEXT NOT NULL,
+                ready_at TEXT NOT NULL,
+                boot_ms INTEGER NOT NULL,
+                resumed INTEGER NOT NULL
+            );
+
             CREATE INDEX IF NOT EXISTS idx_instances_healthy_ordering
             ON instances(healthy, active_requests, last_heartbeat)
         `);
 
+        // Tables created before instances could be protected or cordoned
+        // lack the columns
+        const columns = this.sql
+            .exec<{ name: string }>("PRAGMA table_info(instances)")
+            .toArray();
+        for (const added of ["protected", "cordoned"]) {
+            if (!columns.some((column) => column.name === added)) {
+                this.sql.exec(
+                    `ALTER TABLE instances ADD COLUMN ${added} INTEGER DEFAULT 0`,
+                );
+            }
+        }
+
+        // Boots recorded before standby instances could be resumed were all
+        // created
+        for (const table of ["booting_instances", "boot_history"]) {
+            const bootColumns = this.sql
+                .exec<{ name: string }>(`PRAGMA table_info(${table})`)
+                .toArray();
+            if (!bootColumns.some((column) => column.name === "resumed")) {
+                this.sql.exec(
+                    `ALTER TABLE ${table} ADD COLUMN resumed INTEGER NOT NULL DEFAULT 0`,
+                );
+            }
+        }
+
         const existingCount =
             this.sql
                 .exec<{
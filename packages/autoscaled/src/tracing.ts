@@ -0,0 +1,193 @@
+import type { AutoscalerConfig } from "./types.js";
+
+type AttributeValue = string | number | boolean;
+type Attributes = Record<string, AttributeValue>;
+
+// OTLP status codes
+const STATUS_OK = 1;
+const STATUS_ERROR = 2;
+
+interface TraceData {
+    traceId: string;
+    spans: Span[];
+    // Spans that end after the trace's root, such as instance.ready, which
+    // the export waits for
+    pending: Promise<unknown>[];
+}
+
+function randomHex(bytes: number): string {
+    return Array.from(crypto.getRandomValues(new Uint8Array(bytes)), (b) =>
+        b.toString(16).padStart(2, "0"),
+    ).join("");
+}
+
+function toUnixNano(ms: number): string {
+    return (BigInt(Math.round(ms)) * 1_000_000n).toString();
+}
+
+function toAttribute(key: string, value: AttributeValue) {
+    if (typeof value === "boolean") {
+        return { key, value: { boolValue: value } };
+    }
+    if (typeof value === "number") {
+        return Number.isInteger(value)
+            ? { key, value: { intValue: String(value) } }
+            : { key, value: { doubleValue: value } };
+    }
+    return { key, value: { stringValue: value } };
+}
+
+export class Span {
+    readonly spanId = randomHex(8);
+    private readonly startedAt = Date.now();
+    private endedAt: number | null = null;
+    private error: string | null = null;
+
+    constructor(
+        private trace: TraceData,
+        readonly name: string,
+        private parentSpanId: string | null,
+        private attributes: Attributes,
+    ) {
+        trace.spans.push(this);
+    }
+
+    setAttribute(key: string, value: AttributeValue): void {
+        this.attributes[key] = value;
+    }
+
+    child(name: string, attributes: Attributes = {}): Span {
+        return new Span(this.trace, name, this.spanId, attributes);
+    }
+
+    // Runs fn in a child span, which ends when fn settles and records the
+    // error if it throws
+    async run<T>(
+        name: string,
+        fn: (span: Span) => T | Promise<T>,
+        attributes: Attributes = {},
+    ): Promise<T> {
+        const span = this.child(name, attributes);
+        try {
+            return await fn(span);
+        } catch (error) {
+            span.fail(error);
+            throw error;
+        } finally {
+            span.end();
+        }
+    }
+
+    // Keeps the trace from being exported until promise settles
+    waitFor(promise: Promise<unknown>): void {
+        this.trace.pending.push(promise);
+    }
+
+    fail(error: unknown): void {
+        this.error = error instanceof Error ? error.message : String(error);
+    }
+
+    // Ending a span again keeps the first end
+    end(): void {
+        this.endedAt ??= Date.now();
+    }
+
+    toOTLP() {
+        return {
+            traceId: this.trace.traceId,
+            spanId: this.spanId,
+            ...(this.parentSpanId ? { parentSpanId: this.parentSpanId } : {}),
+            name: this.name,
+            kind: 1, // Internal
+            startTimeUnixNano: toUnixNano(this.startedAt),
+            endTimeUnixNano: toUnixNano(this.endedAt ?? Date.now()),
+            attributes: Object.entries(this.attributes).map(([key, value]) =>
+                toAttribute(key, value),
+            ),
+            status:
+                this.error === null
+                    ? { code: STATUS_OK }
+                    : { code: STATUS_ERROR, message: this.error },
+        };
+    }
+}
+
+// Records scaling work as traces and exports them to otlpEndpoint with
+// OTLP/HTTP JSON. Spans are recorded either way, and only sent when the
+// endpoint is set
+export class Tracer {
+    constructor(
+        // Read on each export, since subclasses set config after the base
+        // constructor
+        private config: () => AutoscalerConfig,
+        // Keeps the Durable Object alive until an export finishes
+        private waitUntil: (promise: Promise<unknown>) => void,
+    ) {}
+
+    // Runs fn in the root span of a new trace, which is exported in the
+    // background once fn and any spans it left running have settled
+    async trace<T>(
+        name: string,
+        fn: (span: Span) => T | Promise<T>,
+        attributes: Attributes = {},
+    ): Promise<T> {
+        const trace: TraceData = {
+            traceId: randomHex(16),
+            spans: [],
+            pending: [],
+        };
+        const root = new Span(trace, name, null, attributes);
+        try {
+            return await fn(root);
+        } catch (error) {
+            root.fail(error);
+            throw error;
+        } finally {
+            root.end();
+            this.waitUntil(this.export(trace));
+        }
+    }
+
+    private async export(trace: TraceData): Promise<void> {
+        const config = this.config();
+        if (!config.otlpEndpoint) {
+            return;
+        }
+
+        await Promise.allSettled(trace.pending);
+        const serviceName = config.otlpServiceName ?? "autoscaled";
+        const body = {
+            resourceSpans: [
+                {
+                    resource: {
+                        attributes: [toAttribute("service.name", serviceName)],
+                    },
+                    scopeSpans: [
+                        {
+                            scope: { name: "autoscaled" },
+                            spans: trace.spans.map((span) => span.toOTLP()),
+                        },
+                    ],
+                },
+            ],
+        };
+
+        try {
+            const response = await fetch(config.otlpEndpoint, {
+                method: "POST",
+                headers: {
+                    ...config.otlpHeaders,
+                    "Content-Type": "application/json",
+                },
+                body: JSON.stringify(body),
+            });
+            if (!response.ok) {
+                console.error(
+                    `Failed to export trace ${trace.traceId}: ${response.status}`,
+                );
+            }
+        } catch (error) {
+            console.error(`Failed to export trace ${trace.traceId}:`, error);
+        }
+    }
+}
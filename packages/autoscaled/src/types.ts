@@ -21,6 +21,178 @@ export interface InstanceRecord extends Record<string, string | number | null> {
     health_check_failures: number;
     last_health_check: string | null; // ISO 8601
     threshold_crossed_at: string | null; // ISO 8601
+    protected: 0 | 1 | null; // Asked not to be evicted
+    cordoned: 0 | 1 | null; // Taken out of rotation through the admin API
+}
+
+// Whether scaling is paused, through the paused config option or the admin
+// endpoint
+export interface PauseState {
+    paused: boolean;
+    reason: string | null;
+    since: string | null; // ISO 8601, null when paused by config
+    // Scaling decisions held since scaling was last paused
+    held: number;
+}
+
+// Percent thresholds per metric
+export interface Thresholds {
+    cpu: number;
+    memory: number;
+    disk: number;
+}
+
+// A cooldown as of an evaluation
+export interface CooldownExplanation {
+    cooldown: number; // Milliseconds
+    last: string | null; // ISO 8601, the last scaling it runs from
+    remaining: number; // Milliseconds, 0 once it's over
+}
+
+// What the next heartbeat would decide from the current metrics, as
+// GET <adminEndpoint>/explain reports it. A triggered action that a
+// constraint holds back names the constraint in blockedBy
+export interface Explanation {
+    at: string; // ISO 8601
+    instances: {
+        serving: number; // Healthy and not cordoned
+        healthy: number;
+        min: number;
+        max: number;
+    };
+    scaleUp: {
+        action: "scale_up" | "none";
+        blockedBy: "max_instances" | "cooldown" | "paused" | null;
+        cooldown: CooldownExplanation;
+        // Request-based scale-up happens as requests arrive rather than on
+        // the heartbeat, so it doesn't decide the action
+        requests: {
+            maxPerInstance: number | null;
+            averagePerInstance: number;
+            triggered: boolean;
+        };
+        metrics: {
+            // null when no scale-up threshold is configured
+            thresholds: Thresholds | null;
+            instances: {
+                name: string;
+                cpu: number;
+                memory: number;
+                disk: number;
+                // Discount while warming up, 0 to 1
+                weight: number;
+                // Crossed within scaleUpCooldown, so it can't trigger again
+                recentlyCrossed: boolean;
+                exceeds: boolean;
+            }[];
+            triggered: boolean;
+        };
+    };
+    scaleDown: {
+        action: "scale_down" | "none";
+        blockedBy: "min_instances" | "cooldown" | "paused" | null;
+        cooldown: CooldownExplanation;
+        thresholds: Thresholds;
+        instances: {
+            name: string;
+            cpu: number;
+            memory: number;
+            disk: number;
+            below: boolean;
+        }[];
+        // Every instance is below the thresholds
+        triggered: boolean;
+        // The instances that would be drained, in order
+        candidates: string[];
+    };
+}
+
+export interface StandbyRecord extends Record<string, string> {
+    name: string;
+    standby_since: string; // ISO 8601
+}
+
+export type DecisionAction = "scale_up" | "scale_down";
+
+// Why a decision was made: a compute threshold was crossed ("metrics"),
+// maxRequestsPerInstance was neared ("requests"), a request found no
+// instance ("no_instance") or an unhealthy one ("unhealthy")
+export type DecisionReason =
+    | "metrics"
+    | "requests"
+    | "no_instance"
+    | "unhealthy";
+
+// A scaling action, as the beforeScale and afterScale hooks see it
+export interface ScaleEvent {
+    action: DecisionAction;
+    reason: DecisionReason;
+    // The instance scaled down, or the one a scale-up started; null before a
+    // scale-up, whose instance doesn't exist yet
+    instance: string | null;
+    // Healthy instances when the hook is called
+    instanceCount: number;
+}
+
+export interface DecisionRecord extends Record<string, string | number | null> {
+    id: number;
+    at: string; // ISO 8601
+    action: DecisionAction;
+    reason: DecisionReason;
+    instance: string;
+    resumed: 0 | 1; // Scaled up by resuming a standby instance
+    instance_count: number; // Healthy instances after the decision
+}
+
+export interface ReplicaRecord extends Record<string, string | number | null> {
+    id: number;
+    at: string; // ISO 8601
+    instance_count: number;
+    healthy_count: number;
+    draining_count: number;
+    standby_count: number;
+    active_requests: number;
+    avg_cpu: number | null; // Percent, across healthy instances
+    avg_memory: number | null;
+    avg_disk: number | null;
+}
+
+// How long an instance took from the create or resume call to passing its
+// first health check
+export interface BootRecord extends Record<string, string | number> {
+    id: number;
+    instance: string;
+    started_at: string; // ISO 8601
+    ready_at: string; // ISO 8601
+    boot_ms: number;
+    resumed: number; // 1 if resumed from standby, 0 if created
+}
+
+export interface BootTimeSummary {
+    count: number;
+    p50: number | null; // Milliseconds
+    p95: number | null;
+    max: number | null;
+    // Share of boots within bootTimeObjective, or null without one
+    withinObjective: number | null;
+}
+
+export interface BootTimeStats {
+    created: BootTimeSummary;
+    resumed: BootTimeSummary;
+    // Instances started that haven't passed a health check yet
+    booting: number;
+}
+
+// Where decision and replica history is exported. An R2 bucket binding
+// satisfies it, and an adapter with the same put method can write to S3 or
+// GCS instead
+export interface AnalyticsBucket {
+    put(
+        key: string,
+        value: string,
+        options?: { httpMetadata?: { contentType?: string } },
+    ): Promise<unknown>;
 }
 
 export type MonitorzData = {
@@ -28,6 +200,13 @@ export type MonitorzData = {
     cpu_usage: number;
     memory_usage: number;
     disk_usage: number;
+    // Per-collector status ("ok", "error", or "timeout"); a metric that isn't
+    // "ok" was not measured and its value must not be read as idle
+    status?: Record<string, string>;
+    errors?: Record<string, string>;
+    degraded?: boolean;
+    // Set while the workload has asked not to be evicted (POST /protectz)
+    protected?: boolean;
 };
 
 export type InstanceType =
@@ -164,6 +343,18 @@ export interface AutoscalerConfig {
      * @default 120_000 (2 minutes)
      */
     scaleDownCooldown?: number;
+    /**
+     * Milliseconds after creation during which an instance counts as warming up
+     * New instances often spike CPU during JIT or cache warm-up, so their metrics are discounted by warmupWeight when evaluating compute-based scale-up
+     * @default 0 (disabled)
+     */
+    warmupPeriod?: number;
+    /**
+     * Weight (0-1) applied to a warming-up instance's metrics when evaluating compute-based scale-up
+     * 0 ignores warming-up instances entirely, 0.5 counts them at half their usage
+     * @default 0
+     */
+    warmupWeight?: number;
     /**
      * General threshold for scaling down (defaults to scaleThreshold - 45% for hysteresis)
      */
@@ -190,6 +381,113 @@ export interface AutoscalerConfig {
      * @default 60_000 (1 minute)
      */
     drainTimeout?: number;
+    /**
+     * The maximum number of drained containers kept stopped on standby instead of being destroyed
+     * Scale-up resumes a standby container before creating a new one, which skips provisioning and makes scale-from-zero much faster
+     * Standby containers don't count toward maxInstances
+     * @default 0 (disabled, drained containers are destroyed)
+     */
+    maxStandbyInstances?: number;
+    /**
+     * Milliseconds a container may stay on standby before it is destroyed
+     * @default 3_600_000 (1 hour)
+     */
+    standbyTimeout?: number;
+    /**
+     * Milliseconds an instance should take from being created or resumed to passing its first health check
+     * Slower boots are logged, and /healthz reports the share of boots within it, to tune minInstances and maxStandbyInstances against
+     * @default undefined (no objective)
+     */
+    bootTimeObjective?: number;
+    /**
+     * Bucket that decision and replica history is exported to as CSV, for capacity planning and postmortems over months of data
+     * History is only recorded when this is set, and is kept in Durable Object storage just until it's exported. Assign a binding from env, such as an R2 bucket, in your constructor
+     * @default undefined (history isn't recorded)
+     */
+    analyticsBucket?: AnalyticsBucket;
+    /**
+     * Milliseconds between exports to analyticsBucket
+     * @default 3_600_000 (1 hour)
+     */
+    analyticsExportInterval?: number;
+    /**
+     * Prefix of the keys exported to analyticsBucket
+     * @default "autoscaled/"
+     */
+    analyticsPrefix?: string;
+    /**
+     * OTLP/HTTP endpoint that each scaling evaluation is exported to as a trace, with spans for the metrics fetch, policy evaluation, provider calls, and instance readiness, e.g. "https://otel.example.com/v1/traces"
+     * Scale-ups for requests that found no instance, or an unhealthy one, are traced too
+     * @default undefined (traces aren't exported)
+     */
+    otlpEndpoint?: string;
+    /**
+     * Headers sent with each trace export, such as an API key for the collector
+     * @default undefined
+     */
+    otlpHeaders?: Record<string, string>;
+    /**
+     * The service.name traces are exported under
+     * @default "autoscaled"
+     */
+    otlpServiceName?: string;
+    /**
+     * Add an X-Capacity-Remaining header to every routed response, with the percent of the fleet's capacity, up to maxInstances, that's still free
+     * Well-behaved clients can back off as it nears 0, before requests start failing with 503s
+     * @default false
+     */
+    capacityHeaders?: boolean;
+    /**
+     * Average attempts per idempotency key within retryStormWindow at which requests count as a retry storm
+     * During a storm, a key's attempts beyond this many per window are answered with 429, and request-based scale-up is held, since the request rate is inflated by retries
+     * @default undefined (disabled)
+     */
+    retryStormThreshold?: number;
+    /**
+     * Milliseconds of requests that retryStormThreshold is measured over
+     * @default 10_000 (10 seconds)
+     */
+    retryStormWindow?: number;
+    /**
+     * Request header that identifies a logical request across its retries
+     * @default "Idempotency-Key"
+     */
+    retryKeyHeader?: string;
+    /**
+     * Called before each scale-up and scale-down the heartbeat or request load decides on, e.g. to warm a cache cluster before instances are added
+     * Resolving to false vetoes a scale-down, which leaves the instance serving; a scale-up can't be vetoed
+     * @default undefined
+     */
+    beforeScale?: (
+        event: ScaleEvent,
+    ) => boolean | void | Promise<boolean | void>;
+    /**
+     * Called after each scale-up the heartbeat or request load decides on, once the instance has started, and after each scaled-down instance is removed, e.g. to notify a connection pool manager
+     * @default undefined
+     */
+    afterScale?: (event: ScaleEvent) => void | Promise<void>;
+    /**
+     * Milliseconds beforeScale and afterScale may run before they're abandoned; a beforeScale that times out or throws doesn't veto
+     * @default 5_000 (5 seconds)
+     */
+    hookTimeout?: number;
+    /**
+     * Pause scaling: no instance is created, destroyed, or moved to or from standby, while metrics are still collected and traffic still served
+     * The admin endpoint can pause scaling too, but can't resume it while this is set
+     * @default false
+     */
+    paused?: boolean;
+    /**
+     * Bearer token required by the admin endpoints under adminEndpoint
+     * Without it, the admin endpoints are off and their paths are routed to containers like any other
+     * @default undefined (admin endpoints disabled)
+     */
+    adminToken?: string;
+    /**
+     * Path prefix of the admin endpoints, which the autoscaler answers instead of a container
+     * @default "/_autoscaler"
+     */
+    adminEndpoint?: string;
     /**
      * The endpoint to monitor the autoscaler's health
      * @default "/healthz"
@@ -205,6 +503,7 @@ export interface AutoscalerConfig {
 export interface InstanceFilter {
     healthy?: boolean;
     notDraining?: boolean;
+    notCordoned?: boolean;
     belowCapacity?: number;
 }
 
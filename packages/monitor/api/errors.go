@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes name why something failed, so automation can branch on the
+// cause instead of matching messages, which may change. They appear in
+// ErrorResponse, MonitorResponse.ErrorCodes, ChildStatus.ErrorCode, and
+// controller channel results.
+const (
+	// Request errors, in ErrorResponse
+	CodeInvalidArgument     = "INVALID_ARGUMENT"
+	CodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	CodeNotEnabled          = "NOT_ENABLED"
+	CodeUnauthenticated     = "UNAUTHENTICATED"
+	CodePermissionDenied    = "PERMISSION_DENIED"
+	CodeLoadShedding        = "LOAD_SHEDDING"
+	CodeUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	CodeUnavailable         = "UNAVAILABLE"
+	CodeInternal            = "INTERNAL"
+
+	// Collector errors, in MonitorResponse.ErrorCodes
+	CodeCollectorFailed  = "COLLECTOR_FAILED"
+	CodeCollectorTimeout = "COLLECTOR_TIMEOUT"
+	CodeNonFiniteValue   = "NON_FINITE_VALUE"
+	CodeMetricsStale     = "METRICS_STALE"
+	CodeClockSkew        = "CLOCK_SKEW"
+
+	// CodeDrainTimeout means the command didn't exit within -kill-timeout of
+	// being signalled and was killed
+	CodeDrainTimeout = "DRAIN_TIMEOUT"
+
+	// Scaler errors, from package scaler
+	CodeTargetUnreachable = "TARGET_UNREACHABLE"
+	CodeProviderThrottled = "PROVIDER_THROTTLED"
+	CodeHookFailed        = "HOOK_FAILED"
+	CodeHookTimeout       = "HOOK_TIMEOUT"
+)
+
+// Error is a failure with one of the codes above.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// ErrorResponse is the body of every non-2xx JSON response.
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+// WriteError responds with status and an ErrorResponse.
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: Error{Code: code, Message: message}})
+}
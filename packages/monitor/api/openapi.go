@@ -0,0 +1,208 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Endpoint describes one monitor endpoint in the OpenAPI spec.
+type Endpoint struct {
+	Method  string
+	Path    string
+	Summary string
+	Params  []Param
+	// Response is a value of the JSON response type, or nil when the
+	// endpoint doesn't return JSON
+	Response any
+	// ContentType defaults to application/json
+	ContentType string
+}
+
+// Param is a query parameter.
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// Endpoints lists every documented endpoint. Gossip between peers is an
+// internal protocol and is left out.
+var Endpoints = []Endpoint{
+	{Method: http.MethodGet, Path: "/monitorz", Summary: "Latest metric sample", Response: MonitorResponse{},
+		Params: []Param{{Name: "percpu", Type: "boolean", Description: "Include each core's usage in per_cpu"}}},
+	{Method: http.MethodGet, Path: "/monitorz/history", Summary: "Recent samples, oldest first", Response: HistoryResponse{},
+		Params: []Param{{Name: "window", Type: "string", Description: "How far back to return, as a Go duration (e.g. 5m)"}}},
+	{Method: http.MethodGet, Path: "/monitorz/stream", Summary: "Server-Sent Events stream of samples, one JSON document per event", ContentType: "text/event-stream",
+		Params: []Param{{Name: "interval", Type: "string", Description: "How often a sample is sent, as a Go duration; at least -interval"}}},
+	{Method: http.MethodGet, Path: "/statusz", Summary: "Metrics, child state, readiness, and idle time", Response: StatusResponse{}},
+	{Method: http.MethodGet, Path: "/readyz", Summary: "Readiness probe: fails while draining, or the child is down or unhealthy", ContentType: "text/plain"},
+	{Method: http.MethodGet, Path: "/livez", Summary: "Liveness probe: fails once a healthy child stops answering its health check", ContentType: "text/plain"},
+	{Method: http.MethodGet, Path: "/protectz", Summary: "Whether the instance is protected from eviction", Response: ProtectionStatus{}},
+	{Method: http.MethodPost, Path: "/protectz", Summary: "Protect the instance from eviction", Response: ProtectionStatus{},
+		Params: []Param{
+			{Name: "reason", Type: "string", Description: "Why the instance is protected"},
+			{Name: "ttl", Type: "string", Description: "Lift protection after this Go duration; protected until DELETE if omitted"},
+		}},
+	{Method: http.MethodDelete, Path: "/protectz", Summary: "Lift eviction protection", Response: ProtectionStatus{}},
+	{Method: http.MethodPost, Path: "/quitquitquit", Summary: "Drain and stop the instance, as if it had been sent SIGTERM", ContentType: "text/plain"},
+	{Method: http.MethodGet, Path: "/controlz", Summary: "Current load shedding and pause state", Response: ControlState{}},
+	{Method: http.MethodPost, Path: "/controlz", Summary: "Shed a fraction of requests or pause the instance", Response: ControlState{},
+		Params: []Param{
+			{Name: "shed_percent", Type: "number", Description: "Percentage of requests (0-100) to reject with 503"},
+			{Name: "paused", Type: "boolean", Description: "Reject every request with 503"},
+			{Name: "reason", Type: "string", Description: "Why load is being shed"},
+		}},
+	{Method: http.MethodDelete, Path: "/controlz", Summary: "Stop shedding load and unpause", Response: ControlState{}},
+	{Method: http.MethodGet, Path: "/processesz", Summary: "Top processes by CPU and memory", Response: ProcessesResponse{},
+		Params: []Param{{Name: "n", Type: "integer", Description: "Number of processes per list (default 10)"}}},
+	{Method: http.MethodGet, Path: "/fleetz", Summary: "Fleet members and averages learned through gossip", Response: FleetResponse{}},
+	{Method: http.MethodGet, Path: "/costz", Summary: "Cost and utilization over the current and last -cost-window", Response: CostResponse{}},
+	{Method: http.MethodGet, Path: "/metrics", Summary: "Latest sample in Prometheus text format", ContentType: "text/plain"},
+	{Method: http.MethodGet, Path: "/debug/vars", Summary: "Monitor internals and Go runtime statistics from expvar"},
+}
+
+// Spec builds an OpenAPI 3 document for the endpoints for which include
+// returns true, with schemas generated from the response types.
+func Spec(version string, include func(Endpoint) bool) map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]any{}
+	errorSchema := schemaFor(reflect.TypeOf(ErrorResponse{}), schemas)
+	for _, e := range Endpoints {
+		if !include(e) {
+			continue
+		}
+
+		contentType := e.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		content := map[string]any{}
+		if e.Response != nil {
+			content["schema"] = schemaFor(reflect.TypeOf(e.Response), schemas)
+		} else {
+			content["schema"] = map[string]any{"type": "string"}
+		}
+
+		op := map[string]any{
+			"summary": e.Summary,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     map[string]any{contentType: content},
+				},
+				"default": map[string]any{
+					"description": "Error with a code from the Code constants",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": errorSchema},
+					},
+				},
+			},
+		}
+		if len(e.Params) > 0 {
+			params := make([]any, 0, len(e.Params))
+			for _, p := range e.Params {
+				params = append(params, map[string]any{
+					"name":        p.Name,
+					"in":          "query",
+					"description": p.Description,
+					"schema":      map[string]any{"type": p.Type},
+				})
+			}
+			op["parameters"] = params
+		}
+
+		item, _ := paths[e.Path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[e.Path] = item
+		}
+		item[strings.ToLower(e.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "autoscaled monitor",
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				// Only enforced when the monitor runs with -tokens-file or -auth-token
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns the JSON schema for t, registering named structs in
+// schemas and referring to them by name.
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), schemas)
+	case reflect.Struct:
+		if _, ok := schemas[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			schemas[t.Name()] = nil
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	}
+	return map[string]any{}
+}
+
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaFor(field.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
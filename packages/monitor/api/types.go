@@ -0,0 +1,495 @@
+// Package api defines the JSON documents served by the monitor. The monitor
+// and the typed client in package client share these types, and the
+// OpenAPI spec served at /openapi.json is generated from them.
+package api
+
+import "time"
+
+// Collector statuses reported in MonitorResponse.Status.
+const (
+	StatusOK      = "ok"
+	StatusError   = "error"
+	StatusTimeout = "timeout"
+)
+
+// MonitorResponse is served by GET /monitorz.
+type MonitorResponse struct {
+	CPUUsage    float64 `json:"cpu_usage"`
+	MemoryUsage float64 `json:"memory_usage"`
+	// DiskUsage is the fullest filesystem in -disk-paths, which is the root
+	// filesystem by default
+	DiskUsage float64 `json:"disk_usage"`
+	// DiskSampleAgeSeconds is how old DiskUsage is, since disk usage is
+	// sampled in the background every -disk-interval
+	DiskSampleAgeSeconds float64 `json:"disk_sample_age_seconds"`
+	// InodeUsage is the highest percentage of inodes in use across
+	// -disk-paths, sampled with DiskUsage. Omitted on filesystems without an
+	// inode limit.
+	InodeUsage *float64 `json:"inode_usage,omitempty"`
+	// Disks reports each of -disk-paths separately
+	Disks []DiskStats `json:"disks,omitempty"`
+
+	// ClockOffsetSeconds is how far the local clock is ahead of NTP time
+	// (negative when behind). Only reported when a time source is available.
+	ClockOffsetSeconds *float64 `json:"clock_offset_seconds,omitempty"`
+
+	// PerCPU is each logical core's usage over the last -interval, only
+	// included with ?percpu=true
+	PerCPU []float64 `json:"per_cpu,omitempty"`
+
+	// LoadAverage is the run queue length, which exposes CPU contention
+	// usage percentages hide (not on Windows)
+	LoadAverage *LoadAverage `json:"load_average,omitempty"`
+	// CPUSteal is the percentage of CPU time the hypervisor gave to other
+	// guests over the last -interval (Linux only)
+	CPUSteal *float64 `json:"cpu_steal,omitempty"`
+
+	// EntropyAvailable is the kernel's available entropy in bits (Linux only)
+	EntropyAvailable *int `json:"entropy_available,omitempty"`
+	// Conntrack reports netfilter connection tracking table usage (Linux
+	// only, when nf_conntrack is loaded)
+	Conntrack *ConntrackStats `json:"conntrack,omitempty"`
+
+	// Status maps each collector name to StatusOK, StatusError, or
+	// StatusTimeout. A metric whose collector isn't ok was not measured and
+	// its value must not be read as zero load.
+	Status map[string]string `json:"status"`
+	// Errors maps collector names to the reason they produced no value, or
+	// why the value they produced can't be trusted
+	Errors map[string]string `json:"errors,omitempty"`
+	// ErrorCodes maps the same collector names to a code for the entry in
+	// Errors, such as CodeCollectorTimeout or CodeMetricsStale
+	ErrorCodes map[string]string `json:"error_codes,omitempty"`
+	// Degraded is set when any collector failed to produce a value, or the
+	// clock offset exceeds -max-clock-skew
+	Degraded bool `json:"degraded"`
+	// CollectorTimings maps collector names to how long their last call took
+	// and how often they run, to find collectors that are slow on this host
+	CollectorTimings map[string]CollectorTiming `json:"collector_timings,omitempty"`
+	// PressureScore is the highest utilisation (0-100) among the measured
+	// metrics; lower means more headroom for new work
+	PressureScore *float64 `json:"pressure_score,omitempty"`
+	// Protected is set while the workload has asked not to be evicted via
+	// POST /protectz; the scaler must skip it during scale-down
+	Protected bool `json:"protected"`
+
+	// Network is host network throughput, excluding loopback interfaces
+	Network *NetworkStats `json:"network,omitempty"`
+	// DiskIO is host disk activity, summed over physical disks
+	DiskIO *DiskIOStats `json:"disk_io,omitempty"`
+
+	// Process covers the child's process tree in exec mode
+	Process *ProcessStats `json:"process,omitempty"`
+	// ChildRestarts is how many times the child has been restarted under
+	// -restart, in exec mode
+	ChildRestarts *int `json:"child_restarts,omitempty"`
+	// Probes is the state of the -probe health checks, when any are set
+	Probes *ProbesStatus `json:"probes,omitempty"`
+	// Control is set while load shedding or a pause is in effect
+	Control *ControlState `json:"control,omitempty"`
+	// Degradation is where the instance is on the -degrade ladder
+	Degradation *DegradationState `json:"degradation,omitempty"`
+	// Proxy covers traffic the monitor proxies to the workload
+	Proxy *ProxyStats `json:"proxy,omitempty"`
+	// Cost is the last complete -cost-window, when -hourly-cost is set
+	Cost *CostReport `json:"cost,omitempty"`
+
+	Environment *Environment `json:"environment,omitempty"`
+	// Labels identify this instance to consumers, from -labels and the
+	// Kubernetes downward API
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// CollectorTiming is how long a collector's underlying call takes. For
+// background collectors that's the refresh a scraper never waits on.
+type CollectorTiming struct {
+	DurationSeconds float64 `json:"duration_seconds"`
+	// IntervalSeconds is how often the call is made (or, for on-demand
+	// collectors, how long its result is reused). Collectors that are slow
+	// on this host run up to 10x less often than configured.
+	IntervalSeconds float64 `json:"interval_seconds"`
+}
+
+// LoadAverage holds the 1, 5, and 15 minute load averages. Divide by Cores to
+// compare hosts of different sizes; above 1 per core, work is queueing.
+type LoadAverage struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+	Cores  int     `json:"cores"`
+}
+
+// DiskStats is the usage of one filesystem in -disk-paths.
+type DiskStats struct {
+	Path              string   `json:"path"`
+	UsagePercent      float64  `json:"usage_percent"`
+	InodeUsagePercent *float64 `json:"inode_usage_percent,omitempty"`
+}
+
+type ConntrackStats struct {
+	Count        int     `json:"count"`
+	Max          int     `json:"max"`
+	UsagePercent float64 `json:"usage_percent"`
+}
+
+// NetworkStats covers host network throughput, averaged over -interval.
+type NetworkStats struct {
+	// Total sums every non-loopback interface
+	Total      NetworkRates            `json:"total"`
+	Interfaces map[string]NetworkRates `json:"interfaces,omitempty"`
+}
+
+type NetworkRates struct {
+	BytesInPerSecond    float64 `json:"bytes_in_per_second"`
+	BytesOutPerSecond   float64 `json:"bytes_out_per_second"`
+	PacketsInPerSecond  float64 `json:"packets_in_per_second"`
+	PacketsOutPerSecond float64 `json:"packets_out_per_second"`
+	ErrorsInPerSecond   float64 `json:"errors_in_per_second"`
+	ErrorsOutPerSecond  float64 `json:"errors_out_per_second"`
+}
+
+// DiskIOStats covers host disk activity, averaged over -interval.
+type DiskIOStats struct {
+	// Total sums every physical disk, so partitions and device-mapper
+	// volumes aren't counted twice
+	Total   DiskIORates            `json:"total"`
+	Devices map[string]DiskIORates `json:"devices,omitempty"`
+}
+
+type DiskIORates struct {
+	ReadsPerSecond      float64 `json:"reads_per_second"`
+	WritesPerSecond     float64 `json:"writes_per_second"`
+	ReadBytesPerSecond  float64 `json:"read_bytes_per_second"`
+	WriteBytesPerSecond float64 `json:"write_bytes_per_second"`
+	// BusyPercent is the share of the interval the disk had I/O in flight
+	// (Linux only); 100 means saturated
+	BusyPercent float64 `json:"busy_percent"`
+}
+
+// ProcessStats covers the supervised child and all of its descendants, so
+// exec mode reports what the workload uses rather than the whole host.
+type ProcessStats struct {
+	PID int `json:"pid"`
+	// Processes is the number of processes in the tree, including the child
+	Processes int `json:"processes"`
+	// CPUPercent is the tree's CPU usage since the previous sample, where 100
+	// is one core
+	CPUPercent float64 `json:"cpu_percent"`
+	RSSBytes   uint64  `json:"rss_bytes"`
+	Threads    int32   `json:"threads"`
+	// OpenFDs is omitted on platforms that can't count file descriptors
+	OpenFDs *int32 `json:"open_fds,omitempty"`
+}
+
+// Environment describes where the monitor is running, so the scaler can
+// apply environment-specific logic (and use real limits instead of host
+// totals when computing headroom).
+type Environment struct {
+	// Runtime is "cloudflare", "kubernetes", "docker", "jail", or "host"
+	Runtime string `json:"runtime"`
+	// OS and Arch are the platform the monitor was built for, e.g. "linux"
+	// and "arm64"
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// CPULimit is the cgroup CPU quota in cores, if one is set
+	CPULimit float64 `json:"cpu_limit,omitempty"`
+	// MemoryLimitBytes is the cgroup memory limit, if one is set
+	MemoryLimitBytes uint64 `json:"memory_limit_bytes,omitempty"`
+	// Metadata holds orchestrator-provided identifiers (pod, namespace, node,
+	// Durable Object ID, location, ...)
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// StatusResponse is served by GET /statusz. It merges everything an
+// orchestrator or the scaler needs about an instance, so a single request
+// per interval is enough.
+type StatusResponse struct {
+	Metrics       MonitorResponse `json:"metrics"`
+	Child         *ChildStatus    `json:"child,omitempty"`
+	Ready         bool            `json:"ready"`
+	Draining      bool            `json:"draining"`
+	UptimeSeconds float64         `json:"uptime_seconds"`
+	IdleSeconds   float64         `json:"idle_seconds"`
+	// Port is the port the monitor is serving on, which differs from -port
+	// after falling back to an ephemeral one
+	Port int `json:"port"`
+	// Restarts is how many times the monitor has restarted, counted across
+	// runs with the same -state-file
+	Restarts uint64 `json:"restarts"`
+	// ChildHealth is the latest -child-health-url check, if one is set
+	ChildHealth *HealthStatus `json:"child_health,omitempty"`
+}
+
+// ChildStatus describes the command supervised in exec mode.
+type ChildStatus struct {
+	Command   []string `json:"command"`
+	PID       int      `json:"pid,omitempty"`
+	Running   bool     `json:"running"`
+	StartedAt string   `json:"started_at,omitempty"`
+	ExitCode  *int     `json:"exit_code,omitempty"`
+	// ErrorCode is CodeDrainTimeout when the last run was killed for not
+	// exiting within -kill-timeout
+	ErrorCode string `json:"error_code,omitempty"`
+	// Restarts is kept across monitor restarts with -state-file
+	Restarts int `json:"restarts"`
+}
+
+// ProbesStatus aggregates the -probe health checks.
+type ProbesStatus struct {
+	// Healthy is true while every probe is
+	Healthy bool                   `json:"healthy"`
+	Probes  map[string]ProbeStatus `json:"probes"`
+}
+
+// ProbeStatus is one probe's state. It only turns healthy after the
+// probe's success threshold of consecutive passes, and unhealthy after its
+// failure threshold of consecutive failures.
+type ProbeStatus struct {
+	// Kind is http, tcp, or exec
+	Kind                 string `json:"kind"`
+	Target               string `json:"target"`
+	Healthy              bool   `json:"healthy"`
+	Error                string `json:"error,omitempty"`
+	CheckedAt            string `json:"checked_at,omitempty"`
+	ConsecutiveSuccesses int    `json:"consecutive_successes"`
+	ConsecutiveFailures  int    `json:"consecutive_failures"`
+}
+
+// HealthStatus is the latest result of the child's health check.
+type HealthStatus struct {
+	URL                 string `json:"url"`
+	Healthy             bool   `json:"healthy"`
+	Error               string `json:"error,omitempty"`
+	CheckedAt           string `json:"checked_at,omitempty"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+}
+
+// ProtectionStatus is served by /protectz and reports whether the instance
+// has asked not to be evicted.
+type ProtectionStatus struct {
+	Protected bool   `json:"protected"`
+	Reason    string `json:"reason,omitempty"`
+	Since     string `json:"since,omitempty"`
+	// ExpiresAt is when protection lapses on its own, if a ttl was given
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// DegradationState is the instance's position on its -degrade ladder.
+type DegradationState struct {
+	// Level is how many steps are in effect, 0 when none are
+	Level int `json:"level"`
+	// Pressure is what the ladder climbs on, from Source: "fleet" for the
+	// average across gossip members, "instance" for the instance's own
+	Pressure float64 `json:"pressure"`
+	Source   string  `json:"source,omitempty"`
+	// Headers are added to every request the HTTP proxy forwards
+	Headers map[string]string `json:"headers,omitempty"`
+	// ShedPercent of proxied requests are rejected with 503, on top of
+	// any /controlz shedding
+	ShedPercent float64 `json:"shed_percent,omitempty"`
+	Alerting    bool    `json:"alerting,omitempty"`
+}
+
+// CostResponse is served by GET /costz.
+type CostResponse struct {
+	// Current covers the window in progress
+	Current CostReport `json:"current"`
+	// Last is the most recent complete window, omitted during the first
+	Last *CostReport `json:"last,omitempty"`
+}
+
+// CostReport weighs what the instance cost over a window against how much
+// of it was used. Consistently low utilization means the scaling target can
+// be raised; IdleCost is what that headroom costs.
+type CostReport struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// HourlyCost is -hourly-cost, in whatever currency it was given in
+	HourlyCost float64 `json:"hourly_cost"`
+	// Cost is HourlyCost prorated over the window
+	Cost float64 `json:"cost"`
+	// The averages cover the measured samples, and are omitted when none
+	// were
+	AvgCPUUsage    *float64 `json:"avg_cpu_usage,omitempty"`
+	AvgMemoryUsage *float64 `json:"avg_memory_usage,omitempty"`
+	// Utilization is the average PressureScore: how much of what was
+	// provisioned the busiest resource used, on a 0-100 scale
+	Utilization *float64 `json:"utilization,omitempty"`
+	// IdleCost is the part of Cost that paid for unused capacity
+	IdleCost *float64 `json:"idle_cost,omitempty"`
+	// Requests is how many requests the HTTP proxy received in the window,
+	// omitted without -proxy-port
+	Requests *uint64 `json:"requests,omitempty"`
+	// CostPer1kRequests is omitted when there were no requests
+	CostPer1kRequests *float64 `json:"cost_per_1k_requests,omitempty"`
+}
+
+// ControlState is served by /controlz. Proxies in front of the instance
+// reject ShedPercent of requests with 503, or all of them while Paused.
+type ControlState struct {
+	ShedPercent float64 `json:"shed_percent"`
+	Paused      bool    `json:"paused"`
+	Reason      string  `json:"reason,omitempty"`
+	UpdatedAt   string  `json:"updated_at,omitempty"`
+}
+
+// ProxyStats covers every proxy the monitor runs in front of the workload.
+type ProxyStats struct {
+	HTTP *HTTPProxyStats `json:"http,omitempty"`
+	TCP  []TCPProxyStats `json:"tcp,omitempty"`
+	UDP  []UDPProxyStats `json:"udp,omitempty"`
+}
+
+// HTTPProxyStats covers the -proxy-port reverse proxy. Rates and latency
+// cover the last -interval.
+type HTTPProxyStats struct {
+	Listen   string `json:"listen"`
+	Upstream string `json:"upstream"`
+	// TotalRequests includes rejected ones, since they are still demand
+	TotalRequests uint64 `json:"total_requests"`
+	// RejectedRequests got a 503 because of /controlz
+	RejectedRequests uint64 `json:"rejected_requests"`
+	// Errors are responses with a 5xx status from the upstream, or a 502
+	// because it couldn't be reached
+	Errors uint64 `json:"errors"`
+	// UpstreamErrors counts requests the upstream couldn't be reached for
+	UpstreamErrors uint64 `json:"upstream_errors"`
+	// InFlight is how many requests are being served right now
+	InFlight int64 `json:"in_flight"`
+	// Concurrency is the average of InFlight over the last -interval,
+	// omitted until a full interval has been measured
+	Concurrency       *float64 `json:"concurrency,omitempty"`
+	RequestsPerSecond float64  `json:"requests_per_second"`
+	ErrorsPerSecond   float64  `json:"errors_per_second"`
+	// ErrorPercent is the share of requests that errored, omitted when there
+	// were none
+	ErrorPercent *float64 `json:"error_percent,omitempty"`
+	// Latency is omitted when no request completed in the interval, rather
+	// than reported as 0
+	Latency *LatencyPercentiles `json:"latency,omitempty"`
+}
+
+// LatencyPercentiles are request durations, measured from when the proxy
+// received the request until the response was fully sent.
+type LatencyPercentiles struct {
+	P50Seconds float64 `json:"p50_seconds"`
+	P95Seconds float64 `json:"p95_seconds"`
+	P99Seconds float64 `json:"p99_seconds"`
+}
+
+// TCPProxyStats covers one -tcp-proxy listener. Rates are averaged over the
+// last -interval.
+type TCPProxyStats struct {
+	Listen            string `json:"listen"`
+	Upstream          string `json:"upstream"`
+	ActiveConnections int64  `json:"active_connections"`
+	TotalConnections  uint64 `json:"total_connections"`
+	// RejectedConnections were closed on accept because of /controlz
+	RejectedConnections uint64 `json:"rejected_connections"`
+	// UpstreamErrors counts connections the upstream refused or didn't accept
+	// in time
+	UpstreamErrors    uint64  `json:"upstream_errors"`
+	BytesIn           uint64  `json:"bytes_in"`
+	BytesOut          uint64  `json:"bytes_out"`
+	BytesInPerSecond  float64 `json:"bytes_in_per_second"`
+	BytesOutPerSecond float64 `json:"bytes_out_per_second"`
+}
+
+// UDPProxyStats covers one -udp-proxy listener. A flow is the traffic
+// between one client address and the upstream; it ends after
+// -udp-flow-timeout without packets in either direction. Rates are averaged
+// over the last -interval.
+type UDPProxyStats struct {
+	Listen      string `json:"listen"`
+	Upstream    string `json:"upstream"`
+	ActiveFlows int64  `json:"active_flows"`
+	TotalFlows  uint64 `json:"total_flows"`
+	// DroppedPackets were discarded because of /controlz or because the
+	// upstream couldn't be reached
+	DroppedPackets      uint64  `json:"dropped_packets"`
+	PacketsIn           uint64  `json:"packets_in"`
+	PacketsOut          uint64  `json:"packets_out"`
+	BytesIn             uint64  `json:"bytes_in"`
+	BytesOut            uint64  `json:"bytes_out"`
+	PacketsInPerSecond  float64 `json:"packets_in_per_second"`
+	PacketsOutPerSecond float64 `json:"packets_out_per_second"`
+	BytesInPerSecond    float64 `json:"bytes_in_per_second"`
+	BytesOutPerSecond   float64 `json:"bytes_out_per_second"`
+}
+
+// ChannelMessage is sent in either direction over the -controller-url
+// WebSocket, one JSON document per text frame. The monitor sends "hello"
+// once connected, then a "sample" every interval; the controller sends
+// "command", and the monitor answers each with a "result".
+type ChannelMessage struct {
+	Type string `json:"type"`
+	// Name identifies the monitor in "hello"
+	Name   string            `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Sample *MonitorResponse  `json:"sample,omitempty"`
+	// ID is chosen by the controller and echoed in the command's result
+	ID string `json:"id,omitempty"`
+	// Command is "drain", "restart", or "set_interval"
+	Command string `json:"command,omitempty"`
+	// Interval is how often samples are sent, as a Go duration, for
+	// "set_interval"
+	Interval string `json:"interval,omitempty"`
+	OK       bool   `json:"ok,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Code classifies Error in a failed "result"
+	Code string `json:"code,omitempty"`
+}
+
+// HistoryResponse is served by GET /monitorz/history.
+type HistoryResponse struct {
+	IntervalSeconds float64        `json:"interval_seconds"`
+	Samples         []HistoryPoint `json:"samples"`
+}
+
+// HistoryPoint is one sample in /monitorz/history. Metrics that weren't
+// measured are omitted rather than reported as 0.
+type HistoryPoint struct {
+	Time          time.Time `json:"time"`
+	CPUUsage      *float64  `json:"cpu_usage,omitempty"`
+	MemoryUsage   *float64  `json:"memory_usage,omitempty"`
+	DiskUsage     *float64  `json:"disk_usage,omitempty"`
+	PressureScore *float64  `json:"pressure_score,omitempty"`
+	Degraded      bool      `json:"degraded"`
+}
+
+// ProcessesResponse is served by GET /processesz.
+type ProcessesResponse struct {
+	ByCPU    []ProcessInfo `json:"by_cpu"`
+	ByMemory []ProcessInfo `json:"by_memory"`
+}
+
+type ProcessInfo struct {
+	PID           int32   `json:"pid"`
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryPercent float32 `json:"memory_percent"`
+	RSSBytes      uint64  `json:"rss_bytes"`
+}
+
+// FleetResponse is served by GET /fleetz.
+type FleetResponse struct {
+	Members []FleetMember `json:"members"`
+	// Count and the averages only include fresh, non-degraded members
+	Count          int     `json:"count"`
+	AvgCPUUsage    float64 `json:"avg_cpu_usage"`
+	AvgMemoryUsage float64 `json:"avg_memory_usage"`
+	AvgDiskUsage   float64 `json:"avg_disk_usage"`
+}
+
+// FleetMember is the latest sample known for one monitor in the fleet.
+type FleetMember struct {
+	Name        string  `json:"name"`
+	Addr        string  `json:"addr"`
+	CPUUsage    float64 `json:"cpu_usage"`
+	MemoryUsage float64 `json:"memory_usage"`
+	DiskUsage   float64 `json:"disk_usage"`
+	Degraded    bool    `json:"degraded"`
+	// PressureScore is omitted when the member measured nothing
+	PressureScore *float64  `json:"pressure_score,omitempty"`
+	SampledAt     time.Time `json:"sampled_at"`
+}
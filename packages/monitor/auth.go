@@ -0,0 +1,113 @@
+//go:build !minimal
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/abhi-arya1/autoscaled/monitor/rbac"
+)
+
+var (
+	tokensFile = flag.String("tokens-file", "", "JSON file of API tokens with roles and namespaces; enables RBAC on every endpoint")
+	auditLog   = flag.String("audit-log", "", "File to append audit records of mutating calls to (defaults to stderr)")
+	authToken  = flag.String("auth-token", os.Getenv("MONITOR_AUTH_TOKEN"), "Bearer token required on every endpoint, with full access (env MONITOR_AUTH_TOKEN)")
+)
+
+// authTokenName identifies -auth-token in audit records.
+const authTokenName = "auth-token"
+
+var authorizer *rbac.Authorizer
+
+func init() {
+	registerFeature(feature{
+		name:  "rbac",
+		start: loadAuthorizer,
+		wrap: func(next http.Handler) http.Handler {
+			if authorizer == nil {
+				return next
+			}
+			return withRBAC(authorizer, next)
+		},
+	})
+}
+
+func loadAuthorizer() error {
+	if *tokensFile == "" && *authToken == "" {
+		return nil
+	}
+
+	audit := io.Writer(os.Stderr)
+	if *auditLog != "" {
+		f, err := os.OpenFile(*auditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		audit = f
+	}
+
+	var tokens []rbac.Token
+	if *tokensFile != "" {
+		var err error
+		tokens, err = rbac.ReadTokens(*tokensFile)
+		if err != nil {
+			return fmt.Errorf("failed to load tokens: %w", err)
+		}
+	}
+	if *authToken != "" {
+		tokens = append(tokens, rbac.Token{Name: authTokenName, Secret: *authToken, Role: rbac.RoleAdmin})
+	}
+
+	a, err := rbac.New(tokens, audit)
+	if err != nil {
+		return fmt.Errorf("failed to load tokens: %w", err)
+	}
+	authorizer = a
+	return nil
+}
+
+// requiredRole returns the role needed to call an endpoint, or 0 if the
+// endpoint is authenticated some other way.
+func requiredRole(r *http.Request) rbac.Role {
+	switch r.URL.Path {
+	case "/gossipz":
+		// Peers authenticate with the gossip HMAC instead
+		return 0
+	case "/readyz", "/livez":
+		// Orchestrator probes can't be given a token, and only report
+		// pass or fail
+		return 0
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return rbac.RoleReadOnly
+	}
+	if adminEndpoints[r.URL.Path] {
+		return rbac.RoleAdmin
+	}
+	return rbac.RoleOperator
+}
+
+// adminEndpoints take the instance out of service when called with anything
+// but GET, so only admins may call them.
+var adminEndpoints = map[string]bool{
+	"/quitquitquit": true,
+	"/controlz":     true,
+}
+
+// withRBAC requires a token scoped to this instance's namespace on every
+// endpoint that needs one.
+func withRBAC(a *rbac.Authorizer, next http.Handler) http.Handler {
+	instanceNamespace := func(*http.Request) string { return labels[namespaceLabel] }
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := requiredRole(r)
+		if role == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		a.Require(role, instanceNamespace, next).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var errNoSampleYet = errors.New("no sample taken yet")
+
+// sampleCache wraps an expensive metric call so that concurrent callers share
+// a single in-flight sample, and results are reused for ttl afterwards, or
+// longer if the call is slow. Failed samples are not cached.
+type sampleCache[T any] struct {
+	ttl     time.Duration
+	timeout time.Duration
+	sample  func(context.Context) (T, error)
+	timing  *samplerTiming
+
+	group singleflight.Group
+
+	mu        sync.Mutex
+	value     T
+	sampledAt time.Time
+	// reuseFor is ttl, stretched for slow calls
+	reuseFor time.Duration
+}
+
+// newSampleCache builds a cache whose calls are timed under name.
+func newSampleCache[T any](name string, ttl, timeout time.Duration, sample func(context.Context) (T, error)) *sampleCache[T] {
+	return &sampleCache[T]{ttl: ttl, timeout: timeout, sample: sample, timing: timingFor(name), reuseFor: ttl}
+}
+
+// Get returns the cached value if it is fresh, otherwise it joins (or starts)
+// the in-flight sample. The sample itself runs detached from ctx so one
+// caller giving up doesn't fail the others; ctx only bounds how long this
+// caller waits.
+func (c *sampleCache[T]) Get(ctx context.Context) (T, error) {
+	c.mu.Lock()
+	if !c.sampledAt.IsZero() && time.Since(c.sampledAt) < c.reuseFor {
+		value := c.value
+		c.mu.Unlock()
+		return value, nil
+	}
+	c.mu.Unlock()
+
+	ch := c.group.DoChan("sample", func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+
+		start := time.Now()
+		value, err := c.sample(ctx)
+		took := time.Since(start)
+		reuseFor := stretchedInterval(c.ttl, took)
+		c.timing.record(took, err, reuseFor)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.value = value
+		c.sampledAt = time.Now()
+		c.reuseFor = reuseFor
+		c.mu.Unlock()
+
+		return value, nil
+	})
+
+	var zero T
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// asyncSample refreshes a metric in the background on its own interval, for
+// calls too slow to make while a scraper waits (e.g. disk usage on network
+// filesystems). Get never blocks on the underlying call.
+type asyncSample[T any] struct {
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	sample   func(context.Context) (T, error)
+	timing   *samplerTiming
+
+	mu        sync.Mutex
+	value     T
+	sampledAt time.Time
+	err       error
+	// current is interval, stretched for slow calls
+	current time.Duration
+}
+
+// newAsyncSample builds a background sampler for the collector called name.
+func newAsyncSample[T any](name string, interval, timeout time.Duration, sample func(context.Context) (T, error)) *asyncSample[T] {
+	return &asyncSample[T]{name: name, interval: interval, timeout: timeout, sample: sample, timing: timingFor(name), current: interval}
+}
+
+// run refreshes immediately and then every interval, measured from the
+// start of each refresh, until ctx is done.
+func (a *asyncSample[T]) run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		start := time.Now()
+		a.refresh(ctx)
+		timer.Reset(time.Until(start.Add(a.currentInterval())))
+	}
+}
+
+func (a *asyncSample[T]) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	start := time.Now()
+	value, err := a.sample(ctx)
+	took := time.Since(start)
+	current := stretchedInterval(a.interval, took)
+	a.timing.record(took, err, current)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.current = current
+	a.err = err
+	if err == nil {
+		a.value = value
+		a.sampledAt = time.Now()
+	}
+}
+
+// Get returns the last successful sample and its age. It only fails if no
+// sample has succeeded yet; after that a failing refresh shows up as a
+// growing age rather than a missing value.
+func (a *asyncSample[T]) Get() (T, time.Duration, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.sampledAt.IsZero() {
+		var zero T
+		if a.err != nil {
+			return zero, 0, a.err
+		}
+		return zero, 0, errNoSampleYet
+	}
+	return a.value, time.Since(a.sampledAt), nil
+}
+
+// currentInterval is the time between refreshes in effect, which grows past
+// interval while calls are slow. Staleness is judged against it, so a
+// collector that was slowed down on purpose isn't reported as hung.
+func (a *asyncSample[T]) currentInterval() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
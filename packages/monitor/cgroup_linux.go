@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupCPULimit returns the CPU quota in cores from cgroup v2 (cpu.max) or
+// v1 (cfs_quota_us / cfs_period_us), or 0 when unlimited or unknown.
+func cgroupCPULimit() float64 {
+	if fields := strings.Fields(readTrimmed(cgroupRoot + "/cpu.max")); len(fields) == 2 {
+		return quotaCores(fields[0], fields[1])
+	}
+	return quotaCores(
+		readTrimmed(cgroupRoot+"/cpu/cpu.cfs_quota_us"),
+		readTrimmed(cgroupRoot+"/cpu/cpu.cfs_period_us"),
+	)
+}
+
+func quotaCores(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		// "max" (v2) and -1 (v1) both mean no quota
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
+
+// cgroupMemoryLimit returns the memory limit from cgroup v2 (memory.max) or
+// v1 (memory.limit_in_bytes), or 0 when unlimited or unknown.
+func cgroupMemoryLimit() uint64 {
+	value := readTrimmed(cgroupRoot + "/memory.max")
+	if value == "" {
+		value = readTrimmed(cgroupRoot + "/memory/memory.limit_in_bytes")
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	// cgroup v1 reports "unlimited" as a huge page-aligned number
+	if limit >= 1<<62 {
+		return 0
+	}
+	return limit
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+// Resource limits are only read from cgroups, which exist only on Linux.
+
+func cgroupCPULimit() float64 {
+	return 0
+}
+
+func cgroupMemoryLimit() uint64 {
+	return 0
+}
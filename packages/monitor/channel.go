@@ -0,0 +1,202 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var (
+	controllerURL      = flag.String("controller-url", "", "WebSocket URL (ws:// or wss://) of a controller the monitor dials to stream samples and take commands")
+	controllerToken    = flag.String("controller-token", os.Getenv("MONITOR_CONTROLLER_TOKEN"), "Bearer token sent when dialing -controller-url (env MONITOR_CONTROLLER_TOKEN)")
+	controllerInterval = flag.Duration("controller-interval", 5*time.Second, "How often a sample is sent to -controller-url, until the controller changes it")
+)
+
+type ChannelMessage = api.ChannelMessage
+
+func init() {
+	registerFeature(feature{
+		name:  "channel",
+		start: startChannel,
+	})
+}
+
+func startChannel() error {
+	if *controllerURL == "" {
+		return nil
+	}
+	u, err := url.Parse(*controllerURL)
+	if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+		return fmt.Errorf("-controller-url must be a ws or wss URL")
+	}
+	if *controllerInterval <= 0 {
+		return fmt.Errorf("-controller-interval must be positive")
+	}
+
+	c := &controlChannel{url: u, token: *controllerToken}
+	c.interval.Store(int64(*controllerInterval))
+	go c.run(context.Background())
+	return nil
+}
+
+// controlChannel keeps a WebSocket open to a central controller, which
+// reaches monitors behind NAT through the connection they dialed. Samples
+// flow up it and commands flow down.
+type controlChannel struct {
+	url   *url.URL
+	token string
+	// interval is how often samples are sent, as a time.Duration; the
+	// controller can change it
+	interval atomic.Int64
+	// failing is set while the controller can't be reached, so redial
+	// errors are logged once per outage
+	failing bool
+}
+
+// run keeps the channel connected until ctx is done, redialing with
+// jittered exponential backoff.
+func (c *controlChannel) run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		connected, err := c.session(ctx)
+		if connected {
+			backoff = time.Second
+		}
+		switch {
+		case connected:
+			fmt.Fprintf(os.Stderr, "[monitor] Connection to controller %s lost: %v\n", c.url.Redacted(), err)
+			c.failing = true
+		case !c.failing:
+			fmt.Fprintf(os.Stderr, "[monitor] Failed to connect to controller %s: %v\n", c.url.Redacted(), err)
+			c.failing = true
+		}
+
+		// Full jitter keeps a fleet from redialing a restarted controller in
+		// lockstep
+		wait := time.Duration(rand.Int63n(int64(backoff)))
+		backoff = min(2*backoff, 30*time.Second)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// session dials the controller and serves the connection until it fails.
+// connected reports whether the dial succeeded.
+func (c *controlChannel) session(ctx context.Context) (connected bool, err error) {
+	origin := *c.url
+	origin.Scheme = strings.Replace(origin.Scheme, "ws", "http", 1)
+	config, err := websocket.NewConfig(c.url.String(), origin.String())
+	if err != nil {
+		return false, err
+	}
+	config.Dialer = &net.Dialer{Timeout: 10 * time.Second}
+	if c.token != "" {
+		config.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	ws, err := config.DialContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer ws.Close()
+	fmt.Fprintf(os.Stderr, "[monitor] Connected to controller %s\n", c.url.Redacted())
+	c.failing = false
+
+	name, _ := os.Hostname()
+	if err := websocket.JSON.Send(ws, ChannelMessage{Type: "hello", Name: name, Labels: exportedLabels}); err != nil {
+		return true, err
+	}
+
+	received := make(chan error, 1)
+	go func() {
+		for {
+			var m ChannelMessage
+			if err := websocket.JSON.Receive(ws, &m); err != nil {
+				received <- err
+				return
+			}
+			if m.Type != "command" {
+				continue
+			}
+			if err := websocket.JSON.Send(ws, c.handle(m)); err != nil {
+				received <- err
+				return
+			}
+		}
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case err := <-received:
+			return true, err
+		case <-timer.C:
+		}
+		s := sample(ctx)
+		if err := websocket.JSON.Send(ws, ChannelMessage{Type: "sample", Sample: &s}); err != nil {
+			return true, err
+		}
+		timer.Reset(time.Duration(c.interval.Load()))
+	}
+}
+
+// handle runs a command from the controller and returns its result.
+func (c *controlChannel) handle(m ChannelMessage) ChannelMessage {
+	err := c.execute(m)
+	result := ChannelMessage{Type: "result", ID: m.ID, Command: m.Command, OK: err == nil}
+	if err != nil {
+		result.Error, result.Code = err.Message, err.Code
+	}
+	return result
+}
+
+func (c *controlChannel) execute(m ChannelMessage) *api.Error {
+	switch m.Command {
+	case "drain":
+		// The same as POST /quitquitquit: the child's work drains, then the
+		// monitor stops
+		select {
+		case shutdownRequests <- shutdownSignals[len(shutdownSignals)-1]:
+		default:
+		}
+		fmt.Fprintf(os.Stderr, "[monitor] Draining as requested by the controller\n")
+		return nil
+	case "restart":
+		s := commandSupervisor.Load()
+		if s == nil {
+			return &api.Error{Code: api.CodeNotEnabled, Message: "not in exec mode"}
+		}
+		if !s.restart() {
+			return &api.Error{Code: api.CodeUnavailable, Message: "command is not running"}
+		}
+		fmt.Fprintf(os.Stderr, "[monitor] Restarting the command as requested by the controller\n")
+		return nil
+	case "set_interval":
+		interval, err := time.ParseDuration(m.Interval)
+		if err != nil || interval <= 0 {
+			return &api.Error{Code: api.CodeInvalidArgument, Message: "interval must be a positive duration"}
+		}
+		// CPU and memory don't change more often than they're sampled
+		c.interval.Store(int64(max(interval, *sampleInterval)))
+		return nil
+	}
+	return &api.Error{Code: api.CodeInvalidArgument, Message: fmt.Sprintf("unknown command %q", m.Command)}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+type ChildStatus = api.ChildStatus
+
+// childState tracks the supervised command. It is nil in standalone mode.
+type childState struct {
+	mu     sync.Mutex
+	status ChildStatus
+}
+
+var child *childState
+
+func newChildState(command []string) *childState {
+	return &childState{status: ChildStatus{Command: command, Restarts: restored.ChildRestarts}}
+}
+
+func (c *childState) started(pid int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.PID = pid
+	c.status.Running = true
+	c.status.StartedAt = time.Now().UTC().Format(time.RFC3339)
+	c.status.ExitCode = nil
+	c.status.ErrorCode = ""
+}
+
+func (c *childState) exited(code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.Running = false
+	c.status.ExitCode = &code
+}
+
+// killed records that the command was killed for not draining in time.
+func (c *childState) killed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.ErrorCode = api.CodeDrainTimeout
+}
+
+func (c *childState) restarting() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.Restarts++
+}
+
+func (c *childState) snapshot() *ChildStatus {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.status
+	return &s
+}
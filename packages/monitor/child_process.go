@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+type ProcessStats = api.ProcessStats
+
+// processTreeSampler measures the child's process tree. CPU usage is the
+// change in the tree's CPU time between samples, so it is tracked across
+// calls.
+type processTreeSampler struct {
+	mu      sync.Mutex
+	pid     int
+	cpuTime float64
+	at      time.Time
+}
+
+func childProcessCollector() collector {
+	tree := &processTreeSampler{}
+	return newCollector("process", *cacheTTL, *collectTimeout, tree.sample,
+		func(r *MonitorResponse, v ProcessStats) { r.Process = &v })
+}
+
+func (s *processTreeSampler) sample(ctx context.Context) (ProcessStats, error) {
+	status := child.snapshot()
+	if status == nil || !status.Running {
+		return ProcessStats{}, errors.New("child is not running")
+	}
+
+	procs, err := processTree(ctx, int32(status.PID))
+	if err != nil {
+		return ProcessStats{}, err
+	}
+
+	stats := ProcessStats{PID: status.PID, Processes: len(procs)}
+	var cpuTime float64
+	var fds int32
+	fdsCounted := false
+	for _, p := range procs {
+		// Processes can exit while the tree is walked; skip them
+		if t, err := p.TimesWithContext(ctx); err == nil {
+			cpuTime += cpuSeconds(t)
+		}
+		if m, err := p.MemoryInfoWithContext(ctx); err == nil {
+			stats.RSSBytes += m.RSS
+		}
+		if n, err := p.NumThreadsWithContext(ctx); err == nil {
+			stats.Threads += n
+		}
+		if n, err := p.NumFDsWithContext(ctx); err == nil {
+			fds += n
+			fdsCounted = true
+		}
+	}
+	if fdsCounted {
+		stats.OpenFDs = &fds
+	}
+
+	stats.CPUPercent = s.cpuPercent(ctx, status.PID, procs[0], cpuTime)
+	return stats, nil
+}
+
+// cpuPercent returns the tree's CPU usage since the previous sample. The
+// first sample after the child (re)starts averages over its lifetime.
+func (s *processTreeSampler) cpuPercent(ctx context.Context, pid int, root *process.Process, cpuTime float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.pid != pid {
+		s.pid = pid
+		s.cpuTime = 0
+		s.at = now
+		if created, err := root.CreateTimeWithContext(ctx); err == nil {
+			s.at = time.UnixMilli(created)
+		}
+	}
+
+	elapsed := now.Sub(s.at).Seconds()
+	used := cpuTime - s.cpuTime
+	s.cpuTime, s.at = cpuTime, now
+	// Descendants that exited take their CPU time with them
+	if elapsed <= 0 || used < 0 {
+		return 0
+	}
+	return used / elapsed * 100
+}
+
+// processTree returns root followed by all of its descendants. It walks
+// parent PIDs itself rather than using Children, which shells out to pgrep.
+func processTree(ctx context.Context, root int32) ([]*process.Process, error) {
+	rootProc, err := process.NewProcessWithContext(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[int32][]*process.Process{}
+	for _, p := range procs {
+		if ppid, err := p.PpidWithContext(ctx); err == nil && p.Pid != root {
+			children[ppid] = append(children[ppid], p)
+		}
+	}
+
+	tree := []*process.Process{rootProc}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i].Pid]...)
+	}
+	return tree, nil
+}
+
+func cpuSeconds(t *cpu.TimesStat) float64 {
+	return t.User + t.System
+}
@@ -0,0 +1,255 @@
+// Package client is a typed Go client for the monitor's HTTP API. It shares
+// its response types with the monitor through package api, so the two can't
+// drift apart.
+//
+//	c := client.New("http://localhost:81")
+//	sample, err := c.Monitor(ctx)
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+// Client calls one monitor.
+type Client struct {
+	// BaseURL is the monitor's address, e.g. http://10.0.0.2:81
+	BaseURL string
+	// Token is sent as a bearer token when the monitor runs with -tokens-file
+	// or -auth-token
+	Token string
+	// HTTPClient defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// Error is returned when the monitor responds with a non-2xx status.
+type Error struct {
+	StatusCode int
+	// Code is one of the api.Code constants, or empty when the response
+	// wasn't an api.ErrorResponse, e.g. from a proxy in front of the monitor
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("monitor returned %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("monitor returned %d: %s", e.StatusCode, e.Message)
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Monitor returns the latest sample from GET /monitorz.
+func (c *Client) Monitor(ctx context.Context) (*api.MonitorResponse, error) {
+	var resp api.MonitorResponse
+	return &resp, c.do(ctx, http.MethodGet, "/monitorz", nil, &resp)
+}
+
+// MonitorPerCPU is Monitor with each core's usage included in PerCPU.
+func (c *Client) MonitorPerCPU(ctx context.Context) (*api.MonitorResponse, error) {
+	var resp api.MonitorResponse
+	return &resp, c.do(ctx, http.MethodGet, "/monitorz", url.Values{"percpu": {"true"}}, &resp)
+}
+
+// History returns samples from the last window; 0 returns the monitor's
+// whole -history-window.
+func (c *Client) History(ctx context.Context, window time.Duration) (*api.HistoryResponse, error) {
+	query := url.Values{}
+	if window > 0 {
+		query.Set("window", window.String())
+	}
+	var resp api.HistoryResponse
+	return &resp, c.do(ctx, http.MethodGet, "/monitorz/history", query, &resp)
+}
+
+// Stream calls fn with each sample from GET /monitorz/stream, every
+// interval (or the monitor's -interval when 0), until ctx is done, fn
+// returns an error, or the monitor closes the stream. HTTPClient's timeout
+// doesn't apply, since the stream has no end.
+func (c *Client) Stream(ctx context.Context, interval time.Duration, fn func(*api.MonitorResponse) error) error {
+	var query url.Values
+	if interval > 0 {
+		query = url.Values{"interval": {interval.String()}}
+	}
+	req, err := c.request(ctx, http.MethodGet, "/monitorz/stream", query)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	httpClient := *c.httpClient()
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var sample api.MonitorResponse
+		if err := json.Unmarshal([]byte(data), &sample); err != nil {
+			return err
+		}
+		if err := fn(&sample); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Status returns GET /statusz.
+func (c *Client) Status(ctx context.Context) (*api.StatusResponse, error) {
+	var resp api.StatusResponse
+	return &resp, c.do(ctx, http.MethodGet, "/statusz", nil, &resp)
+}
+
+// Processes returns the top n processes by CPU and memory; 0 uses the
+// monitor's default.
+func (c *Client) Processes(ctx context.Context, n int) (*api.ProcessesResponse, error) {
+	query := url.Values{}
+	if n > 0 {
+		query.Set("n", strconv.Itoa(n))
+	}
+	var resp api.ProcessesResponse
+	return &resp, c.do(ctx, http.MethodGet, "/processesz", query, &resp)
+}
+
+// Fleet returns the members and averages the monitor learned through gossip.
+func (c *Client) Fleet(ctx context.Context) (*api.FleetResponse, error) {
+	var resp api.FleetResponse
+	return &resp, c.do(ctx, http.MethodGet, "/fleetz", nil, &resp)
+}
+
+// Cost returns what the instance cost over the current and last
+// -cost-window against how much of it was used.
+func (c *Client) Cost(ctx context.Context) (*api.CostResponse, error) {
+	var resp api.CostResponse
+	return &resp, c.do(ctx, http.MethodGet, "/costz", nil, &resp)
+}
+
+// Protection reports whether the instance is protected from eviction.
+func (c *Client) Protection(ctx context.Context) (*api.ProtectionStatus, error) {
+	var resp api.ProtectionStatus
+	return &resp, c.do(ctx, http.MethodGet, "/protectz", nil, &resp)
+}
+
+// Protect asks the scaler not to evict the instance. A ttl of 0 protects it
+// until Unprotect is called.
+func (c *Client) Protect(ctx context.Context, reason string, ttl time.Duration) (*api.ProtectionStatus, error) {
+	query := url.Values{}
+	if reason != "" {
+		query.Set("reason", reason)
+	}
+	if ttl > 0 {
+		query.Set("ttl", ttl.String())
+	}
+	var resp api.ProtectionStatus
+	return &resp, c.do(ctx, http.MethodPost, "/protectz", query, &resp)
+}
+
+// Unprotect lifts eviction protection.
+func (c *Client) Unprotect(ctx context.Context) (*api.ProtectionStatus, error) {
+	var resp api.ProtectionStatus
+	return &resp, c.do(ctx, http.MethodDelete, "/protectz", nil, &resp)
+}
+
+// Control returns the instance's load shedding state.
+func (c *Client) Control(ctx context.Context) (*api.ControlState, error) {
+	var resp api.ControlState
+	return &resp, c.do(ctx, http.MethodGet, "/controlz", nil, &resp)
+}
+
+// Shed asks the proxy in front of the instance to reject shedPercent (0-100)
+// of requests with 503, or all of them when paused, replacing any previous
+// state.
+func (c *Client) Shed(ctx context.Context, shedPercent float64, paused bool, reason string) (*api.ControlState, error) {
+	query := url.Values{}
+	query.Set("shed_percent", strconv.FormatFloat(shedPercent, 'f', -1, 64))
+	query.Set("paused", strconv.FormatBool(paused))
+	if reason != "" {
+		query.Set("reason", reason)
+	}
+	var resp api.ControlState
+	return &resp, c.do(ctx, http.MethodPost, "/controlz", query, &resp)
+}
+
+// ClearControl stops shedding load and unpauses the instance.
+func (c *Client) ClearControl(ctx context.Context) (*api.ControlState, error) {
+	var resp api.ControlState
+	return &resp, c.do(ctx, http.MethodDelete, "/controlz", nil, &resp)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, out any) error {
+	req, err := c.request(ctx, method, path, query)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) request(ctx context.Context, method, path string, query url.Values) (*http.Request, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		var envelope api.ErrorResponse
+		if json.Unmarshal(body, &envelope) == nil && envelope.Error.Code != "" {
+			return &Error{StatusCode: resp.StatusCode, Code: envelope.Error.Code, Message: envelope.Error.Message}
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+	return nil
+}
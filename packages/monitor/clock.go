@@ -0,0 +1,135 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var (
+	ntpServer    = flag.String("ntp-server", "", "NTP server to measure clock offset against (defaults to chrony if installed)")
+	ntpInterval  = flag.Duration("ntp-interval", 5*time.Minute, "How often the clock offset is re-measured")
+	maxClockSkew = flag.Duration("max-clock-skew", 500*time.Millisecond, "Clock offset beyond which samples are marked degraded")
+)
+
+func init() {
+	registerFeature(feature{
+		name: "clock",
+		collectors: func() []collector {
+			source := clockOffsetSource(*ntpServer)
+			if source == nil {
+				return nil
+			}
+			return []collector{newCollector("clock", *ntpInterval, *collectTimeout, source, setClockOffset)}
+		},
+	})
+}
+
+// setClockOffset records the offset and marks the sample degraded if it
+// exceeds -max-clock-skew.
+func setClockOffset(r *MonitorResponse, v float64) {
+	r.ClockOffsetSeconds = &v
+	if math.Abs(v) > maxClockSkew.Seconds() {
+		setError(r, "clock", api.CodeClockSkew, fmt.Sprintf("clock offset %.3fs exceeds %s", v, *maxClockSkew))
+	}
+}
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and
+// the Unix epoch (1970).
+const ntpEpochOffset = 2208988800
+
+// queryNTPOffset performs a single SNTP exchange with server and returns the
+// local clock's offset in seconds: positive means the local clock is ahead.
+func queryNTPOffset(ctx context.Context, server string) (float64, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// LI = 0, version = 4, mode = 3 (client)
+	req := make([]byte, 48)
+	req[0] = 0<<6 | 4<<3 | 3
+
+	sent := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	received := time.Now()
+	if err != nil {
+		return 0, err
+	}
+	if n < 48 {
+		return 0, fmt.Errorf("short NTP response (%d bytes)", n)
+	}
+	if mode := resp[0] & 0x7; mode != 4 {
+		return 0, fmt.Errorf("unexpected NTP mode %d", mode)
+	}
+	if stratum := resp[1]; stratum == 0 {
+		return 0, errors.New("NTP server sent kiss-of-death")
+	}
+
+	serverReceive := ntpTimestamp(resp[32:40])
+	serverTransmit := ntpTimestamp(resp[40:48])
+
+	// Standard NTP offset is (server - local); negate so positive means ahead
+	offset := (serverReceive.Sub(sent) + serverTransmit.Sub(received)) / 2
+	return -offset.Seconds(), nil
+}
+
+func ntpTimestamp(b []byte) time.Time {
+	seconds := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	fraction := int64(binary.BigEndian.Uint32(b[4:8]))
+	return time.Unix(seconds, fraction*1e9>>32)
+}
+
+// chronyOffset reads the offset from `chronyc -c tracking`. The "Last
+// offset" field is positive when the local clock was ahead of its sources.
+func chronyOffset(ctx context.Context) (float64, error) {
+	out, err := exec.CommandContext(ctx, "chronyc", "-c", "tracking").Output()
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), ",")
+	if len(fields) < 6 {
+		return 0, fmt.Errorf("unexpected chronyc output %q", out)
+	}
+	return strconv.ParseFloat(fields[5], 64)
+}
+
+// clockOffsetSource returns how the clock offset should be measured: an
+// SNTP query if a server is configured, otherwise chrony if it's installed.
+// It returns nil when neither is available.
+func clockOffsetSource(ntpServer string) func(context.Context) (float64, error) {
+	if ntpServer != "" {
+		return func(ctx context.Context) (float64, error) {
+			return queryNTPOffset(ctx, ntpServer)
+		}
+	}
+	if _, err := exec.LookPath("chronyc"); err == nil {
+		return chronyOffset
+	}
+	return nil
+}
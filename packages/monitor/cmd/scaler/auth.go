@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/abhi-arya1/autoscaled/monitor/rbac"
+)
+
+// adminTokenName identifies -admin-token in audit records.
+const adminTokenName = "admin-token"
+
+// adminAuthorizer builds access control for the admin API from
+// -admin-tokens-file and -admin-token, or returns nil if neither is set.
+func adminAuthorizer() (*rbac.Authorizer, error) {
+	if *adminTokens == "" && *adminToken == "" {
+		return nil, nil
+	}
+
+	audit := io.Writer(os.Stderr)
+	if *adminAuditLog != "" {
+		f, err := os.OpenFile(*adminAuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		audit = f
+	}
+
+	var tokens []rbac.Token
+	if *adminTokens != "" {
+		var err error
+		tokens, err = rbac.ReadTokens(*adminTokens)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tokens: %w", err)
+		}
+	}
+	if *adminToken != "" {
+		tokens = append(tokens, rbac.Token{Name: adminTokenName, Secret: *adminToken, Role: rbac.RoleAdmin})
+	}
+
+	a, err := rbac.New(tokens, audit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokens: %w", err)
+	}
+	return a, nil
+}
+
+// adminRole returns the role needed to call the admin API: reading takes
+// read-only, reloading rules takes an operator, and pausing or resuming
+// scaling, which stops the fleet reacting to load, takes an admin.
+func adminRole(r *http.Request) rbac.Role {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return rbac.RoleReadOnly
+	}
+	if r.URL.Path == "/pause" {
+		return rbac.RoleAdmin
+	}
+	return rbac.RoleOperator
+}
@@ -0,0 +1,200 @@
+// Command scaler polls monitors, evaluates threshold rules against their
+// samples, and emits scale decisions to a webhook or command.
+//
+//	scaler -targets http://10.0.0.2:81,http://10.0.0.3:81 \
+//		-rule "cpu > 80% for 2m -> scale up" \
+//		-rule "cpu < 20% for 10m -> scale down" \
+//		-webhook https://orchestrator.internal/scale
+//
+// With -admin-addr, `scaler pause [reason]`, `scaler resume`, and `scaler
+// status` control the pause switch of the scaler running there.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/client"
+	"github.com/abhi-arya1/autoscaled/monitor/config"
+	"github.com/abhi-arya1/autoscaled/monitor/scaler"
+)
+
+var (
+	targets       = flag.String("targets", "", "Comma-separated base URLs of the monitors to poll")
+	interval      = flag.Duration("interval", 15*time.Second, "How often every target is sampled")
+	token         = flag.String("token", os.Getenv("SCALER_MONITOR_TOKEN"), "Bearer token for monitors running with -tokens-file or -auth-token (env SCALER_MONITOR_TOKEN)")
+	webhookURL    = flag.String("webhook", "", "URL each decision is POSTed to as JSON")
+	webhookSecret = flag.String("webhook-secret", os.Getenv("SCALER_WEBHOOK_SECRET"), "Shared secret used to sign webhook calls (env SCALER_WEBHOOK_SECRET)")
+	execHook      = flag.String("exec", "", "Command run for each decision, with the decision as JSON on stdin")
+	hookTimeout   = flag.Duration("hook-timeout", 30*time.Second, "Maximum time each hook call may take")
+	caFile        = flag.String("ca-file", "", "PEM CA bundle used to verify monitors served over HTTPS")
+	certFile      = flag.String("cert", "", "PEM client certificate for monitors running with -tls-client-ca")
+	keyFile       = flag.String("key", "", "PEM private key for -cert")
+	configFile    = flag.String("config", os.Getenv("SCALER_CONFIG"), "YAML or TOML file of flag values, keyed by flag name; its rules are reloaded on SIGHUP (env SCALER_CONFIG)")
+	adminAddr     = flag.String("admin-addr", "", "Address for the admin API serving /rules, /pause, and /metrics, e.g. localhost:9091; off by default")
+	adminToken    = flag.String("admin-token", os.Getenv("SCALER_ADMIN_TOKEN"), "Bearer token with full access to the admin API, and sent by the pause, resume, and status commands (env SCALER_ADMIN_TOKEN)")
+	adminTokens   = flag.String("admin-tokens-file", "", "JSON file of admin API tokens with roles and namespaces, in the monitor's -tokens-file format")
+	adminAuditLog = flag.String("admin-audit-log", "", "File to append audit records of admin API calls that change something to (defaults to stderr)")
+	namespace     = flag.String("namespace", "default", "Namespace of the service being scaled, which admin tokens scoped to namespaces must include")
+	paused        = flag.Bool("paused", false, "Start with scaling paused: rules are evaluated and decisions logged, but none are sent to hooks until resumed")
+)
+
+func main() {
+	var rules []scaler.Rule
+	flag.Func("rule", `Scaling rule such as "cpu > 80% for 2m -> scale up" (repeatable)`, func(s string) error {
+		rule, err := scaler.ParseRule(s)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	flag.Parse()
+
+	commandLine := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { commandLine[f.Name] = true })
+	reloader := &rulesReloader{commandLine: commandLine}
+	if *configFile != "" {
+		file, err := config.Load(*configFile)
+		if err == nil {
+			err = file.Apply(flag.CommandLine, commandLine)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[scaler] %v\n", err)
+			os.Exit(2)
+		}
+		reloader.loaded = file
+	}
+
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "[scaler] %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *targets == "" || len(rules) == 0 {
+		fmt.Fprintln(os.Stderr, "[scaler] -targets and at least one -rule are required")
+		os.Exit(2)
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "[scaler] -interval must be positive")
+		os.Exit(2)
+	}
+
+	s := &scaler.Scaler{
+		Engine:   scaler.NewEngine(rules),
+		Interval: *interval,
+		Logf: func(format string, args ...any) {
+			fmt.Fprintf(os.Stderr, "[scaler] "+format+"\n", args...)
+		},
+	}
+	httpClient, err := monitorHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[scaler] %v\n", err)
+		os.Exit(2)
+	}
+	for _, target := range strings.Split(*targets, ",") {
+		if target = strings.TrimSpace(target); target == "" {
+			continue
+		}
+		c := client.New(target)
+		c.Token = *token
+		c.HTTPClient = httpClient
+		s.Targets = append(s.Targets, c)
+	}
+
+	if *webhookURL != "" {
+		s.Hooks = append(s.Hooks, &scaler.Webhook{URL: *webhookURL, Secret: []byte(*webhookSecret)})
+	}
+	if *execHook != "" {
+		s.Hooks = append(s.Hooks, &scaler.Exec{Command: strings.Fields(*execHook)})
+	}
+	s.Hooks = withTimeout(s.Hooks, *hookTimeout)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reloader.engine = s.Engine
+	reloader.pause = &pauseSwitch{scaler: s}
+	if *paused {
+		reloader.pause.pause("-paused", "")
+	}
+	reloader.watch(ctx)
+	if *adminAddr != "" {
+		authorizer, err := adminAuthorizer()
+		if err == nil {
+			err = serveAdmin(ctx, *adminAddr, adminHandler(reloader, authorizer))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[scaler] %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	for _, rule := range rules {
+		fmt.Fprintf(os.Stderr, "[scaler] rule: %s\n", rule)
+	}
+	if err := s.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "[scaler] %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// monitorHTTPClient returns the client used to poll monitors, with the CA
+// and client certificate from the flags.
+func monitorHTTPClient() (*http.Client, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if *caFile != "" {
+		pem, err := os.ReadFile(*caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", *caFile)
+		}
+	}
+	if *certFile != "" || *keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}, nil
+}
+
+// timeoutHook bounds each call to the hook it wraps.
+type timeoutHook struct {
+	hook    scaler.Hook
+	timeout time.Duration
+}
+
+func (h timeoutHook) Emit(ctx context.Context, d scaler.Decision) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	return h.hook.Emit(ctx, d)
+}
+
+func withTimeout(hooks []scaler.Hook, timeout time.Duration) []scaler.Hook {
+	wrapped := make([]scaler.Hook, len(hooks))
+	for i, hook := range hooks {
+		wrapped[i] = timeoutHook{hook, timeout}
+	}
+	return wrapped
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/abhi-arya1/autoscaled/monitor/rbac"
+	"github.com/abhi-arya1/autoscaled/monitor/scaler"
+)
+
+// pauseSwitch is the scaler's emergency stop. While it's on, monitors are
+// still polled and rules evaluated, but no decision reaches a hook, so
+// nothing is scaled during e.g. a provider incident.
+type pauseSwitch struct {
+	scaler *scaler.Scaler
+
+	mu     sync.Mutex
+	reason string
+	// by names the admin token that paused scaling, if it was paused
+	// through the admin API with access control on
+	by    string
+	since time.Time
+}
+
+// pauseStatus is served by the admin API's /pause.
+type pauseStatus struct {
+	Paused bool   `json:"paused"`
+	Reason string `json:"reason,omitempty"`
+	By     string `json:"by,omitempty"`
+	Since  string `json:"since,omitempty"`
+	// Held counts the decisions dropped while paused, since the scaler
+	// started
+	Held uint64 `json:"held"`
+}
+
+// pause pauses scaling for reason. by names who asked, and is empty when
+// the scaler's own flags did.
+func (p *pauseSwitch) pause(reason, by string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.scaler.Paused() {
+		p.since = time.Now()
+	}
+	p.reason = reason
+	p.by = by
+	p.scaler.SetPaused(true)
+	if reason == "" {
+		reason = "no reason given"
+	}
+	fmt.Fprintf(os.Stderr, "[scaler] Scaling paused%s (%s); decisions won't be sent until it's resumed\n", byClause(by), reason)
+}
+
+func (p *pauseSwitch) resume(by string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.scaler.Paused() {
+		return
+	}
+	p.scaler.SetPaused(false)
+	fmt.Fprintf(os.Stderr, "[scaler] Scaling resumed%s after %s\n", byClause(by), time.Since(p.since).Round(time.Second))
+}
+
+func byClause(by string) string {
+	if by == "" {
+		return ""
+	}
+	return " by " + by
+}
+
+func (p *pauseSwitch) status() pauseStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := pauseStatus{Held: p.scaler.Held()}
+	if p.scaler.Paused() {
+		s.Paused = true
+		s.Reason = p.reason
+		s.By = p.by
+		s.Since = p.since.UTC().Format(time.RFC3339)
+	}
+	return s
+}
+
+// ServeHTTP handles GET, POST ?reason=, and DELETE /pause. With access
+// control on, the caller's token is logged and reported as who paused.
+func (p *pauseSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, _ := rbac.TokenFromContext(r.Context())
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		p.pause(r.URL.Query().Get("reason"), token.Name)
+	case http.MethodDelete:
+		p.resume(token.Name)
+	default:
+		api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.status())
+}
+
+// metrics serves the pause switch in the Prometheus text format, so
+// dashboards and alerts can show that scaling is frozen.
+func (p *pauseSwitch) metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	s := p.status()
+	paused := 0
+	if s.Paused {
+		paused = 1
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprintf(w, "# HELP scaler_paused Whether scaling is paused, 1 or 0\n# TYPE scaler_paused gauge\nscaler_paused %d\n", paused)
+	fmt.Fprintf(w, "# HELP scaler_decisions_held_total Decisions dropped while scaling was paused\n# TYPE scaler_decisions_held_total counter\nscaler_decisions_held_total %d\n", s.Held)
+}
+
+// runCommand runs `scaler pause [reason]`, `scaler resume`, or `scaler
+// status` against the admin API of a running scaler at -admin-addr, and
+// prints the resulting pause status.
+func runCommand(args []string) error {
+	if *adminAddr == "" {
+		return fmt.Errorf("-admin-addr of the running scaler is required")
+	}
+	method := http.MethodGet
+	query := url.Values{}
+	switch args[0] {
+	case "pause":
+		method = http.MethodPost
+		if reason := strings.Join(args[1:], " "); reason != "" {
+			query.Set("reason", reason)
+		}
+	case "resume":
+		method = http.MethodDelete
+	case "status":
+	default:
+		return fmt.Errorf("unknown command %q; expected pause, resume, or status", args[0])
+	}
+	if args[0] != "pause" && len(args) > 1 {
+		return fmt.Errorf("%s takes no arguments", args[0])
+	}
+
+	host, port, err := net.SplitHostPort(*adminAddr)
+	if err != nil {
+		return fmt.Errorf("-admin-addr: %v", err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	u := url.URL{Scheme: "http", Host: net.JoinHostPort(host, port), Path: "/pause", RawQuery: query.Encode()}
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if *adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+*adminToken)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e api.ErrorResponse
+		if json.Unmarshal(body, &e) == nil && e.Error.Code != "" {
+			return &e.Error
+		}
+		return fmt.Errorf("%s %s: %s", method, u.Path, resp.Status)
+	}
+	os.Stdout.Write(body)
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/abhi-arya1/autoscaled/monitor/config"
+	"github.com/abhi-arya1/autoscaled/monitor/rbac"
+	"github.com/abhi-arya1/autoscaled/monitor/scaler"
+)
+
+// rulesReloader rereads -rule and -paused from -config, so thresholds can
+// be tuned during an incident without restarting the scaler and losing how
+// long each rule has held.
+type rulesReloader struct {
+	engine *scaler.Engine
+	pause  *pauseSwitch
+	// commandLine holds the flags given on the command line, which the file
+	// can't override
+	commandLine map[string]bool
+
+	mu     sync.Mutex
+	loaded *config.File
+}
+
+func (r *rulesReloader) reload() *api.Error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.loaded == nil {
+		return &api.Error{Code: api.CodeNotEnabled, Message: "rules can only be reloaded from -config"}
+	}
+	if r.commandLine["rule"] {
+		return &api.Error{Code: api.CodeNotEnabled, Message: "rules were set with -rule on the command line, which -config can't override"}
+	}
+	file, err := config.Load(r.loaded.Path)
+	if err != nil {
+		return &api.Error{Code: api.CodeInvalidArgument, Message: err.Error()}
+	}
+	var rules []scaler.Rule
+	if e := file.Lookup("rule"); e != nil {
+		for _, s := range e.Values {
+			rule, err := scaler.ParseRule(s)
+			if err != nil {
+				return &api.Error{Code: api.CodeInvalidArgument, Message: fmt.Sprintf("config: %s:%d: -rule: %v", file.Path, e.Line, err)}
+			}
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return &api.Error{Code: api.CodeInvalidArgument, Message: fmt.Sprintf("config: %s has no rules", file.Path)}
+	}
+
+	var setPaused func()
+	for _, name := range r.loaded.Changed(file) {
+		switch {
+		case name == "rule", r.commandLine[name]:
+		case name == "paused":
+			paused := false
+			if e := file.Lookup("paused"); e != nil && len(e.Values) > 0 {
+				v, err := strconv.ParseBool(e.Values[len(e.Values)-1])
+				if err != nil {
+					return &api.Error{Code: api.CodeInvalidArgument, Message: fmt.Sprintf("config: %s:%d: -paused: %v", file.Path, e.Line, err)}
+				}
+				paused = v
+			}
+			setPaused = func() {
+				if paused {
+					r.pause.pause("-paused in "+file.Path, "")
+				} else {
+					r.pause.resume("")
+				}
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "[scaler] -%s changed in %s; restart to apply it\n", name, file.Path)
+		}
+	}
+	r.engine.SetRules(rules)
+	if setPaused != nil {
+		setPaused()
+	}
+	r.loaded = file
+	fmt.Fprintf(os.Stderr, "[scaler] Reloaded %d rules from %s\n", len(rules), file.Path)
+	for _, rule := range rules {
+		fmt.Fprintf(os.Stderr, "[scaler] rule: %s\n", rule)
+	}
+	return nil
+}
+
+// watch reloads the rules on SIGHUP until ctx is done.
+func (r *rulesReloader) watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+			}
+			if err := r.reload(); err != nil {
+				fmt.Fprintf(os.Stderr, "[scaler] Failed to reload rules: %v\n", err)
+			}
+		}
+	}()
+}
+
+// rulesResponse is served by the admin API's GET /rules and POST
+// /rules/reload.
+type rulesResponse struct {
+	Rules []string `json:"rules"`
+}
+
+// adminHandler serves GET /rules, POST /rules/reload, /pause, and /metrics,
+// behind authorizer when it's set.
+func adminHandler(r *rulesReloader, authorizer *rbac.Authorizer) http.Handler {
+	writeRules := func(w http.ResponseWriter) {
+		var resp rulesResponse
+		for _, rule := range r.engine.Rules() {
+			resp.Rules = append(resp.Rules, rule.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		// Rules are full of > and <
+		enc.SetEscapeHTML(false)
+		enc.Encode(resp)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rules", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeRules(w)
+	})
+	mux.HandleFunc("/rules/reload", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		if err := r.reload(); err != nil {
+			status := http.StatusBadRequest
+			if err.Code == api.CodeNotEnabled {
+				status = http.StatusConflict
+			}
+			api.WriteError(w, status, err.Code, err.Message)
+			return
+		}
+		writeRules(w)
+	})
+	mux.Handle("/pause", r.pause)
+	mux.HandleFunc("/metrics", r.pause.metrics)
+
+	if authorizer == nil {
+		return mux
+	}
+	scope := func(*http.Request) string { return *namespace }
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorizer.Require(adminRole(req), scope, mux).ServeHTTP(w, req)
+	})
+}
+
+// serveAdmin runs the admin API on addr until ctx is done.
+func serveAdmin(ctx context.Context, addr string, handler http.Handler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "[scaler] Admin server error: %v\n", err)
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "[scaler] Admin API on %s: GET /rules, POST /rules/reload, GET|POST|DELETE /pause, GET /metrics\n", ln.Addr())
+	return nil
+}
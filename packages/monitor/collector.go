@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+// collector samples one group of metrics. collect returns a function that
+// writes the sample into a response, so results from collectors that finish
+// after their timeout can simply be dropped.
+type collector struct {
+	name    string
+	timeout time.Duration
+	collect func(ctx context.Context) (func(*MonitorResponse), error)
+}
+
+// newCollector builds a cached collector from a sampling function and a
+// setter for the response field it fills.
+func newCollector[T any](name string, ttl, timeout time.Duration, sample func(context.Context) (T, error), set func(*MonitorResponse, T)) collector {
+	cache := newSampleCache(name, ttl, timeout, sample)
+	return collector{
+		name:    name,
+		timeout: timeout,
+		collect: func(ctx context.Context) (func(*MonitorResponse), error) {
+			value, err := cache.Get(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return func(resp *MonitorResponse) { set(resp, value) }, nil
+		},
+	}
+}
+
+// newAsyncCollector builds a collector, named after its sampler, that serves
+// the latest value from a background sampler, passing its age to set. The
+// sampler must be started separately with run.
+func newAsyncCollector[T any](sampler *asyncSample[T], set func(*MonitorResponse, T, time.Duration)) collector {
+	return collector{
+		name: sampler.name,
+		// Reading the cached value never blocks
+		timeout: time.Second,
+		collect: func(ctx context.Context) (func(*MonitorResponse), error) {
+			value, age, err := sampler.Get()
+			if err != nil {
+				return nil, err
+			}
+			return func(resp *MonitorResponse) { set(resp, value, age) }, nil
+		},
+	}
+}
+
+// markStale marks a background sample degraded once it has missed a few
+// refreshes, e.g. because the sampler is failing or hung.
+func markStale(r *MonitorResponse, name string, age, interval time.Duration) {
+	if age <= 3*interval {
+		return
+	}
+	setError(r, name, api.CodeMetricsStale, fmt.Sprintf("sample is %s old", age.Round(time.Millisecond)))
+}
+
+// setError marks r degraded with message and code for collector name.
+func setError(r *MonitorResponse, name, code, message string) {
+	r.Degraded = true
+	if r.Errors == nil {
+		r.Errors = make(map[string]string)
+		r.ErrorCodes = make(map[string]string)
+	}
+	r.Errors[name] = message
+	r.ErrorCodes[name] = code
+}
+
+// collectAll runs every collector concurrently, each bounded by its own
+// timeout, and reports whatever finished alongside per-collector errors. A
+// collector that produced a NaN or infinite value counts as failed.
+func collectAll(ctx context.Context, collectors []collector) MonitorResponse {
+	type result struct {
+		name   string
+		apply  func(*MonitorResponse)
+		status string
+		code   string
+		err    error
+	}
+
+	results := make(chan result, len(collectors))
+	for _, c := range collectors {
+		c := c
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			apply, err := c.collect(ctx)
+			status, code := statusOK, ""
+			if errors.Is(err, context.DeadlineExceeded) {
+				status, code = statusTimeout, api.CodeCollectorTimeout
+				err = fmt.Errorf("timed out after %s", c.timeout)
+			} else if err != nil {
+				status, code = statusError, api.CodeCollectorFailed
+			}
+			results <- result{name: c.name, apply: apply, status: status, code: code, err: err}
+		}()
+	}
+
+	resp := MonitorResponse{Status: make(map[string]string, len(collectors))}
+	for range collectors {
+		r := <-results
+		if r.err == nil {
+			if field := nonFinite(r.apply); field != "" {
+				r.status, r.code, r.err = statusError, api.CodeNonFiniteValue, fmt.Errorf("%s is not a finite number", field)
+			}
+		}
+		resp.Status[r.name] = r.status
+		if r.err != nil {
+			setError(&resp, r.name, r.code, r.err.Error())
+			continue
+		}
+		r.apply(&resp)
+	}
+	return resp
+}
@@ -0,0 +1,443 @@
+// Package config reads flag values from a YAML or TOML file, for binaries
+// whose flags have outgrown the command line. Keys are flag names, and
+// values may refer to environment variables as ${NAME} or
+// ${NAME:-default}:
+//
+//	interval: 2s
+//	labels:
+//	  team: web
+//	probe:
+//	  - api=http://localhost:8080/healthz
+//	auth-token: ${MONITOR_TOKEN}
+//
+// Only the subset of YAML and TOML that maps onto flags is understood:
+// scalars, lists, and one level of mapping, which becomes the
+// comma-separated key=value form flags such as -labels take.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Entry is one flag's value from the file.
+type Entry struct {
+	Name string
+	// Values holds a list's items, or a single value
+	Values []string
+	Line   int
+}
+
+// File is a parsed configuration file.
+type File struct {
+	Path    string
+	Entries []Entry
+}
+
+// Load reads path, as TOML if it ends in .toml and as YAML otherwise.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return Parse(path, data)
+}
+
+// Parse parses data as if it had been read from path.
+func Parse(path string, data []byte) (*File, error) {
+	p := &parser{path: path, lines: strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")}
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = p.parseTOML()
+	} else {
+		err = p.parseYAML()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &File{Path: path, Entries: p.entries}, nil
+}
+
+// Lookup returns the entry for the flag name, or nil.
+func (f *File) Lookup(name string) *Entry {
+	for i := range f.Entries {
+		if f.Entries[i].Name == name {
+			return &f.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Apply sets each flag in fs from the file, except those in skip, which
+// are typically the flags given on the command line. A list sets a
+// repeatable flag once per item, and is joined with commas for a flag that
+// takes a comma-separated string.
+func (f *File) Apply(fs *flag.FlagSet, skip map[string]bool) error {
+	for _, e := range f.Entries {
+		if skip[e.Name] {
+			continue
+		}
+		fl := fs.Lookup(e.Name)
+		if fl == nil {
+			return fmt.Errorf("config: %s:%d: unknown flag -%s", f.Path, e.Line, e.Name)
+		}
+		for _, value := range Values(fl, e.Values) {
+			if err := fs.Set(e.Name, value); err != nil {
+				return fmt.Errorf("config: %s:%d: -%s: %v", f.Path, e.Line, e.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Values returns the values to pass to fl.Value.Set, one call each, for an
+// entry's values.
+func Values(fl *flag.Flag, values []string) []string {
+	if getter, ok := fl.Value.(flag.Getter); ok && len(values) > 1 {
+		if _, ok := getter.Get().(string); ok {
+			return []string{strings.Join(values, ",")}
+		}
+	}
+	return values
+}
+
+// Changed returns the names of flags whose entries differ between f and
+// next, including ones added or removed, in the order they appear.
+func (f *File) Changed(next *File) []string {
+	var names []string
+	for _, e := range next.Entries {
+		if old := f.Lookup(e.Name); old == nil || !slices.Equal(old.Values, e.Values) {
+			names = append(names, e.Name)
+		}
+	}
+	for _, e := range f.Entries {
+		if next.Lookup(e.Name) == nil {
+			names = append(names, e.Name)
+		}
+	}
+	return names
+}
+
+type parser struct {
+	path    string
+	lines   []string
+	entries []Entry
+}
+
+func (p *parser) errorf(line int, format string, args ...any) error {
+	return fmt.Errorf("config: %s:%d: %s", p.path, line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) add(line int, name string, values []string) error {
+	name = strings.ReplaceAll(strings.TrimPrefix(name, "-"), "_", "-")
+	if name == "" {
+		return p.errorf(line, "missing key")
+	}
+	for _, e := range p.entries {
+		if e.Name == name {
+			return p.errorf(line, "%s is already set on line %d", name, e.Line)
+		}
+	}
+	for i, v := range values {
+		expanded, err := expand(v)
+		if err != nil {
+			return p.errorf(line, "%s: %v", name, err)
+		}
+		values[i] = expanded
+	}
+	p.entries = append(p.entries, Entry{Name: name, Values: values, Line: line})
+	return nil
+}
+
+// parseYAML reads top-level "key: value" pairs, where the value is a
+// scalar, a flow list, or an indented block list or mapping.
+func (p *parser) parseYAML() error {
+	for i := 0; i < len(p.lines); i++ {
+		line := stripComment(p.lines[i])
+		if strings.TrimSpace(line) == "" || line == "---" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return p.errorf(i+1, "unexpected indentation")
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || (value != "" && value[0] != ' ') {
+			return p.errorf(i+1, `expected "key: value"`)
+		}
+		key, value = unquote(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		if value != "" {
+			values, err := parseInline(value, ":")
+			if err != nil {
+				return p.errorf(i+1, "%s: %v", key, err)
+			}
+			if err := p.add(i+1, key, values); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// A block: the indented lines that follow
+		start := i + 1
+		var items, pairs []string
+		for i+1 < len(p.lines) {
+			next := stripComment(p.lines[i+1])
+			if strings.TrimSpace(next) == "" {
+				i++
+				continue
+			}
+			if next[0] != ' ' && next[0] != '\t' {
+				break
+			}
+			i++
+			next = strings.TrimSpace(next)
+			if item, ok := strings.CutPrefix(next, "-"); ok && (item == "" || item[0] == ' ') {
+				items = append(items, unquote(strings.TrimSpace(item)))
+				continue
+			}
+			k, v, ok := strings.Cut(next, ":")
+			if !ok {
+				return p.errorf(i+1, `expected "- item" or "key: value"`)
+			}
+			pairs = append(pairs, unquote(strings.TrimSpace(k))+"="+unquote(strings.TrimSpace(v)))
+		}
+		switch {
+		case items != nil && pairs != nil:
+			return p.errorf(start, "%s mixes list items and keys", key)
+		case pairs != nil:
+			items = []string{strings.Join(pairs, ",")}
+		case items == nil:
+			items = []string{""}
+		}
+		if err := p.add(start, key, items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTOML reads top-level "key = value" pairs, and [tables] of them,
+// each of which becomes a mapping.
+func (p *parser) parseTOML() error {
+	var table string
+	var tableLine int
+	var pairs []string
+	closeTable := func() error {
+		if table == "" {
+			return nil
+		}
+		err := p.add(tableLine, table, []string{strings.Join(pairs, ",")})
+		table, pairs = "", nil
+		return err
+	}
+
+	for i := 0; i < len(p.lines); i++ {
+		line := strings.TrimSpace(stripComment(p.lines[i]))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") && !strings.Contains(line, "=") {
+			if err := closeTable(); err != nil {
+				return err
+			}
+			table, tableLine = unquote(strings.TrimSpace(line[1:len(line)-1])), i+1
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return p.errorf(i+1, `expected "key = value"`)
+		}
+		key, value = unquote(strings.TrimSpace(key)), strings.TrimSpace(value)
+		start := i + 1
+		// Arrays may span lines
+		for strings.HasPrefix(value, "[") && !balanced(value) && i+1 < len(p.lines) {
+			i++
+			value += " " + strings.TrimSpace(stripComment(p.lines[i]))
+		}
+
+		values, err := parseInline(value, "=")
+		if err != nil {
+			return p.errorf(start, "%s: %v", key, err)
+		}
+		if table != "" {
+			if len(values) != 1 {
+				return p.errorf(start, "%s.%s: expected a single value", table, key)
+			}
+			pairs = append(pairs, key+"="+values[0])
+			continue
+		}
+		if err := p.add(start, key, values); err != nil {
+			return err
+		}
+	}
+	return closeTable()
+}
+
+// parseInline parses a scalar, a [list], or a {mapping} whose pairs are
+// separated by sep.
+func parseInline(value, sep string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(value, "["):
+		if !strings.HasSuffix(value, "]") {
+			return nil, fmt.Errorf("unterminated list")
+		}
+		items := []string{}
+		for _, item := range splitList(value[1 : len(value)-1]) {
+			items = append(items, unquote(item))
+		}
+		return items, nil
+	case strings.HasPrefix(value, "{"):
+		if !strings.HasSuffix(value, "}") {
+			return nil, fmt.Errorf("unterminated mapping")
+		}
+		var pairs []string
+		for _, pair := range splitList(value[1 : len(value)-1]) {
+			k, v, ok := strings.Cut(pair, sep)
+			if !ok {
+				return nil, fmt.Errorf("expected \"key%s value\" in %q", sep, pair)
+			}
+			pairs = append(pairs, unquote(strings.TrimSpace(k))+"="+unquote(strings.TrimSpace(v)))
+		}
+		return []string{strings.Join(pairs, ",")}, nil
+	}
+	return []string{unquote(value)}, nil
+}
+
+// splitList splits s on commas outside quotes, dropping empty items so a
+// trailing comma is allowed.
+func splitList(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			switch c := s[i]; {
+			case quote != 0:
+				if c == '\\' && quote == '"' {
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+				continue
+			case c == '"' || c == '\'':
+				quote = c
+				continue
+			case c != ',':
+				continue
+			}
+		}
+		if item := strings.TrimSpace(s[start:i]); item != "" {
+			items = append(items, item)
+		}
+		start = i + 1
+	}
+	return items
+}
+
+// balanced reports whether every [ in s outside quotes is closed.
+func balanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// stripComment drops a # comment that starts the line or follows
+// whitespace, outside quotes, and any trailing whitespace.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+// unquote strips double quotes, interpreting escapes, or single quotes,
+// where a doubled quote stands for one. Anything else is returned as is.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+		return s[1 : len(s)-1]
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// expand replaces ${NAME} and ${NAME:-default} with the environment
+// variable's value, or the default when it's unset or empty. $$ is a
+// literal $.
+func expand(s string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:i])
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			s = s[i+2:]
+			continue
+		case '{':
+		default:
+			b.WriteByte('$')
+			s = s[i+1:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ in %q", s)
+		}
+		name, fallback, hasDefault := strings.Cut(s[i+2:i+end], ":-")
+		if name == "" {
+			return "", fmt.Errorf("empty variable name in %q", s)
+		}
+		value := os.Getenv(name)
+		if value == "" && hasDefault {
+			value = fallback
+		}
+		b.WriteString(value)
+		s = s[i+end+1:]
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+func FuzzParse(f *testing.F) {
+	f.Add("monitor.yaml", "interval: 2s\nlabels:\n  team: web\nprobe:\n  - api=http://localhost:8080/healthz\nauth-token: ${MONITOR_TOKEN:-dev}\n")
+	f.Add("monitor.yaml", "probe: [a, 'b, c', \"d\\\"e\"] # comment\nidle_cpu_threshold: 5\n")
+	f.Add("monitor.toml", "interval = \"2s\"\nprobe = [\"a\", \"b\",]\n[labels]\nteam = \"web\"\n")
+	f.Add("monitor.toml", "[labels\nx = ${UNSET\n")
+	f.Add("monitor.yaml", "a:\n  - 1\n  b: 2\na: 3\n")
+	f.Fuzz(func(t *testing.T, path, data string) {
+		file, err := Parse(path, []byte(data))
+		if err != nil {
+			return
+		}
+		lines := strings.Count(strings.ReplaceAll(data, "\r\n", "\n"), "\n") + 1
+		seen := map[string]bool{}
+		for _, e := range file.Entries {
+			if e.Name == "" || seen[e.Name] {
+				t.Fatalf("entry name %q is empty or repeated", e.Name)
+			}
+			seen[e.Name] = true
+			if e.Line < 1 || e.Line > lines {
+				t.Fatalf("%s is on line %d of %d", e.Name, e.Line, lines)
+			}
+		}
+		if changed := file.Changed(file); len(changed) != 0 {
+			t.Fatalf("file changed from itself: %v", changed)
+		}
+
+		// Apply must report bad values rather than panic, whatever the flag
+		fs := flag.NewFlagSet("fuzz", flag.ContinueOnError)
+		for _, e := range file.Entries {
+			if strings.HasPrefix(e.Name, "-") || strings.Contains(e.Name, "=") {
+				// Not a name a flag can have, so Apply reports it unknown
+				continue
+			}
+			switch len(e.Name) % 4 {
+			case 0:
+				fs.String(e.Name, "", "")
+			case 1:
+				fs.Float64(e.Name, 0, "")
+			case 2:
+				fs.Duration(e.Name, time.Second, "")
+			case 3:
+				fs.Bool(e.Name, false, "")
+			}
+		}
+		file.Apply(fs, nil)
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/abhi-arya1/autoscaled/monitor/config"
+)
+
+var configFile = flag.String("config", os.Getenv("MONITOR_CONFIG"), "YAML or TOML file of flag values, keyed by flag name; flags on the command line take precedence (env MONITOR_CONFIG)")
+
+var (
+	// loadedConfig is the file as last applied, to tell what a reload
+	// changes
+	loadedConfig *config.File
+	// commandLineFlags were set on the command line, so the file never
+	// overrides them
+	commandLineFlags map[string]bool
+	configMu         sync.Mutex
+)
+
+// reloader validates a flag's new values from -config and returns a
+// function that applies them, so a reload that's rejected part way through
+// changes nothing. nil values restore the flag's default.
+type reloader func(values []string) (apply func(), err error)
+
+// coreReloaders cover flags outside any feature that can change without a
+// restart.
+var coreReloaders = map[string]reloader{
+	"idle-cpu-threshold": func(values []string) (func(), error) {
+		threshold, err := floatValue("idle-cpu-threshold", values)
+		if err != nil {
+			return nil, err
+		}
+		if !(threshold >= 0 && threshold <= 100) {
+			return nil, fmt.Errorf("-idle-cpu-threshold must be between 0 and 100")
+		}
+		return func() { activity.setThreshold(threshold) }, nil
+	},
+}
+
+// loadConfig applies -config to the flags not given on the command line.
+// It runs before flags are validated, so the file's values are held to the
+// same rules.
+func loadConfig() error {
+	commandLineFlags = map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { commandLineFlags[f.Name] = true })
+	if *configFile == "" {
+		return nil
+	}
+	file, err := config.Load(*configFile)
+	if err != nil {
+		return err
+	}
+	if file.Lookup("config") != nil {
+		return fmt.Errorf("config: %s: config can't be set from the file itself", *configFile)
+	}
+	if err := file.Apply(flag.CommandLine, commandLineFlags); err != nil {
+		return err
+	}
+	loadedConfig = file
+	return nil
+}
+
+// watchConfig rereads -config on each reload signal.
+func watchConfig() {
+	if *configFile == "" || len(reloadSignals) == 0 {
+		return
+	}
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, reloadSignals...)
+	go func() {
+		for range reload {
+			if err := reloadConfig(); err != nil {
+				fmt.Fprintf(os.Stderr, "[monitor] Failed to reload %s: %v\n", *configFile, err)
+			}
+		}
+	}()
+}
+
+// reloadConfig applies the flags that changed in -config and can be applied
+// while running. Changes to the rest are logged and wait for a restart. A
+// file that fails to parse, or a value that's rejected, leaves everything
+// as it was.
+func reloadConfig() error {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	file, err := config.Load(*configFile)
+	if err != nil {
+		return err
+	}
+	reloaders := map[string]reloader{}
+	for name, reload := range coreReloaders {
+		reloaders[name] = reload
+	}
+	for _, f := range features {
+		for name, reload := range f.reload {
+			reloaders[name] = reload
+		}
+	}
+
+	var apply []func()
+	var applied []string
+	for _, name := range loadedConfig.Changed(file) {
+		fl := flag.Lookup(name)
+		if fl == nil {
+			e := file.Lookup(name)
+			return fmt.Errorf("config: %s:%d: unknown flag -%s", file.Path, e.Line, name)
+		}
+		if commandLineFlags[name] {
+			continue
+		}
+		reload, ok := reloaders[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "[monitor] -%s changed in %s; restart to apply it\n", name, file.Path)
+			continue
+		}
+		var values []string
+		if e := file.Lookup(name); e != nil {
+			values = config.Values(fl, e.Values)
+		}
+		f, err := reload(values)
+		if err != nil {
+			return err
+		}
+		apply = append(apply, f)
+		applied = append(applied, "-"+name)
+	}
+	for _, f := range apply {
+		f()
+	}
+	loadedConfig = file
+	if len(applied) == 0 {
+		fmt.Fprintf(os.Stderr, "[monitor] Reloaded %s; nothing to apply\n", file.Path)
+	} else {
+		fmt.Fprintf(os.Stderr, "[monitor] Reloaded %s, applied %s\n", file.Path, strings.Join(applied, ", "))
+	}
+	return nil
+}
+
+// floatValue parses the single value of a reloaded number flag, or its
+// default when it was removed from the file.
+func floatValue(name string, values []string) (float64, error) {
+	value := flag.Lookup(name).DefValue
+	if len(values) > 0 {
+		value = values[len(values)-1]
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("-%s: %v", name, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,114 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+type ControlState = api.ControlState
+
+// controller holds the load shedding state set through /controlz, so the
+// scaler or an operator can relieve a specific instance in an emergency.
+type controller struct {
+	mu    sync.Mutex
+	state ControlState
+}
+
+var controls = &controller{}
+
+func init() {
+	registerFeature(feature{
+		name:      "control",
+		endpoints: []string{"GET /controlz", "POST /controlz", "DELETE /controlz"},
+		collectors: func() []collector {
+			return []collector{{
+				name:    "control",
+				timeout: time.Second,
+				collect: func(context.Context) (func(*MonitorResponse), error) {
+					state, active := controls.snapshot()
+					return func(r *MonitorResponse) {
+						if active {
+							r.Control = &state
+						}
+					}, nil
+				},
+			}}
+		},
+		routes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/controlz", controlHandler)
+		},
+	})
+}
+
+func (c *controller) set(state ControlState) {
+	state.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = state
+}
+
+// snapshot returns the current state and whether any shedding is in effect.
+func (c *controller) snapshot() (ControlState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state, c.state.Paused || c.state.ShedPercent > 0
+}
+
+// admit reports whether a proxied request should be let through: none are
+// while paused, and ShedPercent of them are rejected at random otherwise.
+// The -degrade ladder's shedding applies when it is higher.
+func (c *controller) admit() bool {
+	c.mu.Lock()
+	paused, shed := c.state.Paused, c.state.ShedPercent
+	c.mu.Unlock()
+	if paused {
+		return false
+	}
+	shed = max(shed, ladder.shedPercent())
+	return shed <= 0 || rand.Float64()*100 >= shed
+}
+
+func controlHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		query := r.URL.Query()
+		state := ControlState{Reason: query.Get("reason")}
+		if v := query.Get("shed_percent"); v != "" {
+			pct, err := strconv.ParseFloat(v, 64)
+			// Written so NaN fails too
+			if err != nil || !(pct >= 0 && pct <= 100) {
+				api.WriteError(w, http.StatusBadRequest, api.CodeInvalidArgument, "shed_percent must be between 0 and 100")
+				return
+			}
+			state.ShedPercent = pct
+		}
+		if v := query.Get("paused"); v != "" {
+			paused, err := strconv.ParseBool(v)
+			if err != nil {
+				api.WriteError(w, http.StatusBadRequest, api.CodeInvalidArgument, "paused must be true or false")
+				return
+			}
+			state.Paused = paused
+		}
+		controls.set(state)
+	case http.MethodDelete:
+		controls.set(ControlState{})
+	default:
+		api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	state, _ := controls.snapshot()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
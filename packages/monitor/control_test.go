@@ -0,0 +1,51 @@
+//go:build !minimal
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func FuzzControlHandler(f *testing.F) {
+	f.Add("50", "false", "deploy")
+	f.Add("100", "true", "")
+	f.Add("NaN", "", "")
+	f.Add("-0", "1", "")
+	f.Add("1e309", "", "")
+	f.Add("0x1p-2", "T", "")
+	f.Fuzz(func(t *testing.T, shed, paused, reason string) {
+		defer controls.set(ControlState{})
+		before, _ := controls.snapshot()
+
+		query := url.Values{"shed_percent": {shed}, "paused": {paused}, "reason": {reason}}
+		req := httptest.NewRequest(http.MethodPost, "/controlz?"+query.Encode(), nil)
+		rec := httptest.NewRecorder()
+		controlHandler(rec, req)
+
+		state, _ := controls.snapshot()
+		switch rec.Code {
+		case http.StatusOK:
+			var got ControlState
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid response %q: %v", rec.Body, err)
+			}
+			// Compare what was parsed; an invalid UTF-8 reason is encoded lossily
+			if got.ShedPercent != state.ShedPercent || got.Paused != state.Paused {
+				t.Fatalf("response %+v differs from state %+v", got, state)
+			}
+		case http.StatusBadRequest:
+			if state != before {
+				t.Fatalf("rejected request changed the state to %+v", state)
+			}
+		default:
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		if !(state.ShedPercent >= 0 && state.ShedPercent <= 100) {
+			t.Fatalf("shed_percent %q set %g, outside 0-100", shed, state.ShedPercent)
+		}
+	})
+}
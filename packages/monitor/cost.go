@@ -0,0 +1,203 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var (
+	hourlyCost = flag.Float64("hourly-cost", 0, "What this instance costs per hour, from the provider's pricing; enables cost and efficiency reporting on /costz")
+	costWindow = flag.Duration("cost-window", time.Hour, "Period each cost and efficiency report covers")
+)
+
+type (
+	CostResponse = api.CostResponse
+	CostReport   = api.CostReport
+)
+
+// costs is nil without -hourly-cost.
+var costs *costTracker
+
+func init() {
+	registerFeature(feature{
+		name:      "cost",
+		endpoints: []string{"GET /costz"},
+		collectors: func() []collector {
+			if *hourlyCost == 0 {
+				return nil
+			}
+			return []collector{{
+				name:    "cost",
+				timeout: time.Second,
+				collect: func(context.Context) (func(*MonitorResponse), error) {
+					if costs == nil {
+						return func(*MonitorResponse) {}, nil
+					}
+					_, last := costs.snapshot(time.Now())
+					return func(r *MonitorResponse) { r.Cost = last }, nil
+				},
+			}}
+		},
+		routes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/costz", costHandler)
+		},
+		start: startCosts,
+	})
+}
+
+func startCosts() error {
+	if *hourlyCost == 0 {
+		return nil
+	}
+	if !(*hourlyCost > 0 && !math.IsInf(*hourlyCost, 0)) {
+		return fmt.Errorf("-hourly-cost must be a positive number")
+	}
+	if *costWindow <= 0 {
+		return fmt.Errorf("-cost-window must be positive")
+	}
+	costs = newCostTracker(*hourlyCost, *costWindow, time.Now())
+	go costs.run(context.Background(), *sampleInterval)
+	return nil
+}
+
+func costHandler(w http.ResponseWriter, r *http.Request) {
+	if costs == nil {
+		api.WriteError(w, http.StatusNotFound, api.CodeNotEnabled, "cost reporting is not enabled (-hourly-cost)")
+		return
+	}
+	current, last := costs.snapshot(time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CostResponse{Current: current, Last: last})
+}
+
+// costTracker averages utilization over each -cost-window and sets it
+// against what the window cost, so targets can be tuned on spend rather than
+// on utilization alone.
+type costTracker struct {
+	hourly float64
+	window time.Duration
+
+	mu    sync.Mutex
+	start time.Time
+	cpu   runningAverage
+	mem   runningAverage
+	util  runningAverage
+	// requestsAtStart is the HTTP proxy's total when the window started.
+	// It's read on the first sample rather than at startup, since the proxy
+	// starts (and restores its total from -state-file) after the tracker.
+	requestsAtStart *uint64
+	last            *CostReport
+}
+
+type runningAverage struct {
+	sum float64
+	n   int
+}
+
+func (a *runningAverage) add(v float64) {
+	a.sum += v
+	a.n++
+}
+
+func (a runningAverage) get() *float64 {
+	if a.n == 0 {
+		return nil
+	}
+	v := a.sum / float64(a.n)
+	return &v
+}
+
+func newCostTracker(hourly float64, window time.Duration, now time.Time) *costTracker {
+	return &costTracker{hourly: hourly, window: window, start: now}
+}
+
+func (c *costTracker) reset(now time.Time) {
+	c.start = now
+	c.cpu, c.mem, c.util = runningAverage{}, runningAverage{}, runningAverage{}
+	c.requestsAtStart = nil
+	if total, ok := httpProxyRequests(); ok {
+		c.requestsAtStart = &total
+	}
+}
+
+// run adds a sample every interval until ctx is done, closing the window
+// once it has run its length.
+func (c *costTracker) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.observe(sample(ctx), time.Now())
+	}
+}
+
+func (c *costTracker) observe(r MonitorResponse, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if total, ok := httpProxyRequests(); ok && c.requestsAtStart == nil {
+		c.requestsAtStart = &total
+	}
+	if r.Status["cpu"] == statusOK {
+		c.cpu.add(r.CPUUsage)
+	}
+	if r.Status["memory"] == statusOK {
+		c.mem.add(r.MemoryUsage)
+	}
+	if r.PressureScore != nil {
+		c.util.add(*r.PressureScore)
+	}
+	if now.Sub(c.start) >= c.window {
+		report := c.report(now)
+		c.last = &report
+		c.reset(now)
+	}
+}
+
+// snapshot returns the window in progress up to now, and the last complete
+// one.
+func (c *costTracker) snapshot(now time.Time) (current CostReport, last *CostReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.report(now), c.last
+}
+
+func (c *costTracker) report(now time.Time) CostReport {
+	r := CostReport{
+		Start:          c.start.UTC(),
+		End:            now.UTC(),
+		HourlyCost:     c.hourly,
+		Cost:           c.hourly * now.Sub(c.start).Hours(),
+		AvgCPUUsage:    c.cpu.get(),
+		AvgMemoryUsage: c.mem.get(),
+		Utilization:    c.util.get(),
+	}
+	if r.Utilization != nil {
+		idle := r.Cost * (100 - *r.Utilization) / 100
+		r.IdleCost = &idle
+	}
+	if c.requestsAtStart != nil {
+		total, _ := httpProxyRequests()
+		requests := total - *c.requestsAtStart
+		r.Requests = &requests
+		if requests > 0 {
+			perThousand := r.Cost / float64(requests) * 1000
+			r.CostPer1kRequests = &perThousand
+		}
+	}
+	return r
+}
@@ -0,0 +1,102 @@
+//go:build !minimal
+
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+func init() {
+	expvar.Publish("monitor", expvar.Func(monitorVars))
+	registerFeature(feature{
+		name:      "expvar",
+		endpoints: []string{"GET /debug/vars"},
+		routes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/debug/vars", debugVarsHandler)
+		},
+	})
+}
+
+// debugVarsHandler serves expvar's variables like expvar.Handler, minus
+// cmdline, which would hand -auth-token and other secrets to any read-only
+// token.
+func debugVarsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprint(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key == "cmdline" {
+			return
+		}
+		if !first {
+			fmt.Fprint(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprint(w, "\n}\n")
+}
+
+type collectorVars struct {
+	Runs        uint64  `json:"runs"`
+	Failures    uint64  `json:"failures"`
+	LastSeconds float64 `json:"last_seconds"`
+	MeanSeconds float64 `json:"mean_seconds"`
+	MaxSeconds  float64 `json:"max_seconds"`
+	// IntervalSeconds is 0 for collectors that only run on demand
+	IntervalSeconds float64 `json:"interval_seconds"`
+}
+
+type pushVars struct {
+	// QueueDepth is the number of samples waiting to be delivered
+	QueueDepth int64  `json:"queue_depth"`
+	Delivered  uint64 `json:"delivered"`
+	Dropped    uint64 `json:"dropped"`
+	Retries    uint64 `json:"retries"`
+}
+
+// monitorVars is the monitor's own state, published as the "monitor"
+// variable.
+func monitorVars() any {
+	vars := struct {
+		Samples       uint64                   `json:"samples"`
+		Collectors    map[string]collectorVars `json:"collectors"`
+		Push          *pushVars                `json:"push,omitempty"`
+		Goroutines    int                      `json:"goroutines"`
+		UptimeSeconds float64                  `json:"uptime_seconds"`
+	}{
+		Samples:       samplesTaken.Load(),
+		Collectors:    map[string]collectorVars{},
+		Goroutines:    runtime.NumGoroutine(),
+		UptimeSeconds: time.Since(startedAt).Seconds(),
+	}
+	for _, t := range samplerTimings() {
+		vars.Collectors[t.Name] = collectorVars{
+			Runs:            t.Runs,
+			Failures:        t.Failures,
+			LastSeconds:     t.Last.Seconds(),
+			MeanSeconds:     t.Mean.Seconds(),
+			MaxSeconds:      t.Max.Seconds(),
+			IntervalSeconds: t.Interval.Seconds(),
+		}
+	}
+	if p := activePusher; p != nil {
+		vars.Push = &pushVars{
+			QueueDepth: p.pending.Load(),
+			Delivered:  p.delivered.Load(),
+			Dropped:    p.dropped.Load(),
+			Retries:    p.retries.Load(),
+		}
+	}
+	return vars
+}
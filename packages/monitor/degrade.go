@@ -0,0 +1,288 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+type DegradationState = api.DegradationState
+
+// degradeHysteresis is how far pressure must fall below a step's threshold
+// before the step is left, so the ladder doesn't flap at a boundary.
+const degradeHysteresis = 5
+
+var degradeSteps []degradeStep
+
+func init() {
+	flag.Func("degrade", `Degradation step such as "80% -> header X-Degrade: reduced", "90% -> shed 20%", or "95% -> alert" (repeatable)`, func(s string) error {
+		step, err := parseDegradeStep(s)
+		if err != nil {
+			return err
+		}
+		degradeSteps = append(degradeSteps, step)
+		return nil
+	})
+	registerFeature(feature{
+		name: "degrade",
+		collectors: func() []collector {
+			if len(degradeSteps) == 0 {
+				return nil
+			}
+			return []collector{{
+				name:    "degradation",
+				timeout: time.Second,
+				collect: func(context.Context) (func(*MonitorResponse), error) {
+					state := ladder.snapshot()
+					return func(r *MonitorResponse) { r.Degradation = state }, nil
+				},
+			}}
+		},
+		start: func() error {
+			if len(degradeSteps) == 0 {
+				return nil
+			}
+			ladder = newDegradeLadder(degradeSteps)
+			go ladder.run(context.Background(), *sampleInterval)
+			return nil
+		},
+		reload: map[string]reloader{
+			"degrade": func(values []string) (func(), error) {
+				steps := make([]degradeStep, 0, len(values))
+				for _, v := range values {
+					step, err := parseDegradeStep(v)
+					if err != nil {
+						return nil, err
+					}
+					steps = append(steps, step)
+				}
+				if ladder == nil {
+					if len(steps) == 0 {
+						return func() {}, nil
+					}
+					return nil, fmt.Errorf("-degrade was off at startup; restart to enable it")
+				}
+				return func() { ladder.setSteps(steps) }, nil
+			},
+		},
+	})
+}
+
+// degradeStep is one rung of the -degrade ladder: once pressure reaches
+// threshold, the HTTP proxy adds a header the app understands, the proxies
+// shed a share of traffic, or the monitor alerts.
+type degradeStep struct {
+	threshold   float64
+	header      string
+	value       string
+	shedPercent float64
+	alert       bool
+}
+
+// parseDegradeStep parses "<pressure>[%] -> <action>", where the action is
+// "header <name>: <value>", "shed <percent>[%]", or "alert".
+func parseDegradeStep(s string) (degradeStep, error) {
+	threshold, action, ok := strings.Cut(strings.ReplaceAll(s, "→", "->"), "->")
+	if !ok {
+		return degradeStep{}, fmt.Errorf("degrade step %q: missing \"-> <action>\"", s)
+	}
+	var step degradeStep
+	pressure, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(threshold), "%"), 64)
+	if err != nil || !(pressure >= 0 && pressure <= 100) {
+		return degradeStep{}, fmt.Errorf("degrade step %q: pressure must be between 0 and 100", s)
+	}
+	step.threshold = pressure
+
+	verb, arg, _ := strings.Cut(strings.TrimSpace(action), " ")
+	arg = strings.TrimSpace(arg)
+	switch strings.ToLower(verb) {
+	case "header":
+		name, value, ok := strings.Cut(arg, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return degradeStep{}, fmt.Errorf("degrade step %q: expected \"header <name>: <value>\"", s)
+		}
+		step.header, step.value = http.CanonicalHeaderKey(name), value
+	case "shed":
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+		if err != nil || !(pct > 0 && pct <= 100) {
+			return degradeStep{}, fmt.Errorf("degrade step %q: shed percentage must be above 0 and at most 100", s)
+		}
+		step.shedPercent = pct
+	case "alert":
+		if arg != "" {
+			return degradeStep{}, fmt.Errorf("degrade step %q: alert takes no argument", s)
+		}
+		step.alert = true
+	default:
+		return degradeStep{}, fmt.Errorf("degrade step %q: unknown action %q", s, verb)
+	}
+	return step, nil
+}
+
+func (s degradeStep) String() string {
+	switch {
+	case s.header != "":
+		return fmt.Sprintf("header %s: %s", s.header, s.value)
+	case s.shedPercent > 0:
+		return fmt.Sprintf("shed %g%%", s.shedPercent)
+	}
+	return "alert"
+}
+
+// degradeLadder climbs the -degrade steps as pressure rises, so running out
+// of capacity degrades the service in planned stages instead of all at once.
+// It climbs on the fleet's average pressure with gossip, so every instance
+// of a service degrades together, and on its own pressure otherwise.
+type degradeLadder struct {
+	steps []degradeStep
+
+	mu    sync.Mutex
+	state DegradationState
+}
+
+// ladder is nil without -degrade.
+var ladder *degradeLadder
+
+func newDegradeLadder(steps []degradeStep) *degradeLadder {
+	return &degradeLadder{steps: sortedSteps(steps)}
+}
+
+func sortedSteps(steps []degradeStep) []degradeStep {
+	steps = append([]degradeStep(nil), steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].threshold < steps[j].threshold })
+	return steps
+}
+
+// setSteps replaces the ladder's steps, e.g. from a -config reload, and
+// climbs the new ones at the last pressure seen rather than waiting for the
+// next sample.
+func (l *degradeLadder) setSteps(steps []degradeStep) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.steps = sortedSteps(steps)
+	if l.state.Source == "" {
+		return
+	}
+	// The old level may not exist any more, and holding it as the new one
+	// would skip the hysteresis check
+	l.state.Level = 0
+	l.updateLocked(l.state.Pressure, l.state.Source)
+}
+
+func (l *degradeLadder) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if pressure, source, ok := currentPressure(ctx); ok {
+			l.update(pressure, source)
+		}
+	}
+}
+
+// currentPressure is the fleet's average pressure across healthy gossip
+// members, or the instance's own. ok is false when neither was measured, in
+// which case the ladder stays where it is: no measurement isn't relief.
+func currentPressure(ctx context.Context) (pressure float64, source string, ok bool) {
+	if gossip != nil {
+		var sum float64
+		var n int
+		for _, m := range gossip.snapshot() {
+			if !m.Degraded && m.PressureScore != nil {
+				sum += *m.PressureScore
+				n++
+			}
+		}
+		if n > 0 {
+			return sum / float64(n), "fleet", true
+		}
+	}
+	if p := sample(ctx).PressureScore; p != nil {
+		return *p, "instance", true
+	}
+	return 0, "", false
+}
+
+func (l *degradeLadder) update(pressure float64, source string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updateLocked(pressure, source)
+}
+
+func (l *degradeLadder) updateLocked(pressure float64, source string) {
+	level := 0
+	for i, step := range l.steps {
+		held := i < l.state.Level && pressure > step.threshold-degradeHysteresis
+		if pressure < step.threshold && !held {
+			break
+		}
+		level = i + 1
+	}
+
+	previous := l.state.Level
+	state := DegradationState{Level: level, Pressure: pressure, Source: source}
+	for _, step := range l.steps[:level] {
+		if step.header != "" {
+			if state.Headers == nil {
+				state.Headers = map[string]string{}
+			}
+			state.Headers[step.header] = step.value
+		}
+		state.ShedPercent = max(state.ShedPercent, step.shedPercent)
+		state.Alerting = state.Alerting || step.alert
+	}
+	l.state = state
+
+	switch {
+	case level > previous:
+		for _, step := range l.steps[previous:level] {
+			fmt.Fprintf(os.Stderr, "[monitor] Degrading at %s pressure %.1f: %s\n", source, pressure, step)
+		}
+	case level < previous:
+		for _, step := range l.steps[level:previous] {
+			fmt.Fprintf(os.Stderr, "[monitor] Recovered at %s pressure %.1f: no longer %s\n", source, pressure, step)
+		}
+	}
+}
+
+func (l *degradeLadder) snapshot() *DegradationState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.state
+	return &s
+}
+
+// headers are added to every request the HTTP proxy forwards.
+func (l *degradeLadder) headers() map[string]string {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state.Headers
+}
+
+func (l *degradeLadder) shedPercent() float64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state.ShedPercent
+}
@@ -0,0 +1,11 @@
+package main
+
+import "os"
+
+// diskPath returns the system drive, which isn't always C: on Windows.
+func diskPath() string {
+	if drive := os.Getenv("SystemDrive"); drive != "" {
+		return drive + `\`
+	}
+	return `C:\`
+}
@@ -0,0 +1,99 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+type (
+	DiskIOStats = api.DiskIOStats
+	DiskIORates = api.DiskIORates
+)
+
+func init() {
+	registerFeature(feature{
+		name: "diskio",
+		collectors: func() []collector {
+			sampler := newAsyncSample("diskio", *sampleInterval, *collectTimeout, (&diskIOSampler{}).sample)
+			go sampler.run(context.Background())
+			return []collector{newAsyncCollector(sampler, func(r *MonitorResponse, v DiskIOStats, age time.Duration) {
+				r.DiskIO = &v
+				markStale(r, "diskio", age, sampler.currentInterval())
+			})}
+		},
+	})
+}
+
+// diskIOSampler turns the kernel's cumulative disk counters into rates
+// between consecutive samples, so workloads that saturate disk bandwidth
+// show up long before the disk fills.
+// Only the background sampler calls it, so it needs no locking.
+type diskIOSampler struct {
+	prev   map[string]disk.IOCountersStat
+	prevAt time.Time
+}
+
+func (s *diskIOSampler) sample(ctx context.Context) (DiskIOStats, error) {
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return DiskIOStats{}, err
+	}
+	now := time.Now()
+
+	prev, elapsed := s.prev, now.Sub(s.prevAt).Seconds()
+	s.prev, s.prevAt = counters, now
+	if prev == nil {
+		// Rates need two samples
+		return DiskIOStats{}, errNoSampleYet
+	}
+
+	stats := DiskIOStats{Devices: map[string]DiskIORates{}}
+	for name, c := range counters {
+		p, ok := prev[name]
+		if !ok || !physicalDisk(name) {
+			continue
+		}
+		rate := func(cur, old uint64) float64 {
+			// Counters reset when a device is reattached
+			if cur < old {
+				return 0
+			}
+			return float64(cur-old) / elapsed
+		}
+		rates := DiskIORates{
+			ReadsPerSecond:      rate(c.ReadCount, p.ReadCount),
+			WritesPerSecond:     rate(c.WriteCount, p.WriteCount),
+			ReadBytesPerSecond:  rate(c.ReadBytes, p.ReadBytes),
+			WriteBytesPerSecond: rate(c.WriteBytes, p.WriteBytes),
+			// IoTime is in milliseconds
+			BusyPercent: min(rate(c.IoTime, p.IoTime)/10, 100),
+		}
+		stats.Devices[name] = rates
+		stats.Total.ReadsPerSecond += rates.ReadsPerSecond
+		stats.Total.WritesPerSecond += rates.WritesPerSecond
+		stats.Total.ReadBytesPerSecond += rates.ReadBytesPerSecond
+		stats.Total.WriteBytesPerSecond += rates.WriteBytesPerSecond
+		stats.Total.BusyPercent = max(stats.Total.BusyPercent, rates.BusyPercent)
+	}
+	return stats, nil
+}
+
+// physicalDisk reports whether a device is backed by hardware. On Linux the
+// kernel also counts partitions and stacked devices (device-mapper, md,
+// loop), whose I/O is already counted by the disks beneath them; only
+// physical disks have a device link in sysfs.
+func physicalDisk(name string) bool {
+	if runtime.GOOS != "linux" {
+		return true
+	}
+	_, err := os.Stat("/sys/block/" + strings.ReplaceAll(name, "/", "!") + "/device")
+	return err == nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	drainFile    = flag.String("drain-file", "", "While this file exists, stopping waits for the child's work to finish")
+	drainURL     = flag.String("drain-url", "", "URL returning the child's outstanding work as a number; stopping waits until it reads 0")
+	drainTimeout = flag.Duration("drain-timeout", 5*time.Minute, "Longest stopping waits for the child's work to finish before signalling it")
+)
+
+const drainPollInterval = time.Second
+
+// draining is set once a shutdown signal arrives, so /statusz stops
+// reporting the instance as ready while its work finishes.
+var draining atomic.Bool
+
+// waitForDrain blocks until the child reports its work complete, ctx is
+// cancelled, or -drain-timeout elapses. Work is pending while the instance
+// is protected, -drain-file exists, or -drain-url reports a count above 0.
+func waitForDrain(ctx context.Context) {
+	draining.Store(true)
+
+	ctx, cancel := context.WithTimeout(ctx, *drainTimeout)
+	defer cancel()
+	client := &http.Client{Timeout: drainPollInterval}
+
+	var last string
+	for {
+		reason := pendingWork(ctx, client)
+		if reason == "" {
+			return
+		}
+		if reason != last {
+			fmt.Fprintf(os.Stderr, "[monitor] Waiting for work to finish: %s\n", reason)
+			last = reason
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(os.Stderr, "[monitor] Stopping with work pending: %s\n", reason)
+			return
+		case <-time.After(drainPollInterval):
+		}
+	}
+}
+
+// pendingWork returns why the child can't be stopped yet, or "" once its
+// work is complete.
+func pendingWork(ctx context.Context, client *http.Client) string {
+	if protection.protected() {
+		return "instance is protected"
+	}
+	if *drainFile != "" && fileExists(*drainFile) {
+		return *drainFile + " exists"
+	}
+	if *drainURL != "" {
+		n, err := outstandingWork(ctx, client, *drainURL)
+		if err != nil {
+			// Work can't be confirmed complete, so keep waiting
+			return err.Error()
+		}
+		if n > 0 {
+			return fmt.Sprintf("%g outstanding", n)
+		}
+	}
+	return ""
+}
+
+func outstandingWork(ctx context.Context, client *http.Client, url string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(string(body)), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, errors.New(url + " did not return a number")
+	}
+	return n, nil
+}
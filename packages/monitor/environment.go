@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+type Environment = api.Environment
+
+const (
+	runtimeCloudflare = "cloudflare"
+	runtimeKubernetes = "kubernetes"
+	runtimeDocker     = "docker"
+	runtimeJail       = "jail"
+	runtimeHost       = "host"
+
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// cloudflareEnvVars are set by Cloudflare for every container instance.
+var cloudflareEnvVars = map[string]string{
+	"CLOUDFLARE_DURABLE_OBJECT_ID": "durable_object_id",
+	"CLOUDFLARE_APPLICATION_ID":    "application_id",
+	"CLOUDFLARE_DEPLOYMENT_ID":     "deployment_id",
+	"CLOUDFLARE_LOCATION":          "location",
+	"CLOUDFLARE_REGION":            "region",
+	"CLOUDFLARE_COUNTRY_A2":        "country",
+}
+
+// kubernetesEnvVars are commonly exposed through the downward API.
+var kubernetesEnvVars = map[string]string{
+	"POD_NAME":      "pod",
+	"POD_NAMESPACE": "namespace",
+	"POD_IP":        "pod_ip",
+	"NODE_NAME":     "node",
+}
+
+// detectEnvironment inspects env vars, cgroup files, and well-known paths.
+// It only reads local files and is meant to be called once at startup.
+func detectEnvironment() *Environment {
+	env := &Environment{
+		Runtime:  runtimeHost,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Metadata: map[string]string{},
+	}
+
+	switch {
+	case os.Getenv("CLOUDFLARE_DURABLE_OBJECT_ID") != "":
+		env.Runtime = runtimeCloudflare
+		readEnvMetadata(env.Metadata, cloudflareEnvVars)
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "" || fileExists(serviceAccountDir):
+		env.Runtime = runtimeKubernetes
+		readEnvMetadata(env.Metadata, kubernetesEnvVars)
+		if _, ok := env.Metadata["pod"]; !ok {
+			// Pods get their name as the hostname by default
+			if hostname, err := os.Hostname(); err == nil {
+				env.Metadata["pod"] = hostname
+			}
+		}
+		if _, ok := env.Metadata["namespace"]; !ok {
+			if ns := readTrimmed(serviceAccountDir + "/namespace"); ns != "" {
+				env.Metadata["namespace"] = ns
+			}
+		}
+	case fileExists("/.dockerenv") || strings.Contains(readTrimmed("/proc/1/cgroup"), "docker"):
+		env.Runtime = runtimeDocker
+		if hostname, err := os.Hostname(); err == nil {
+			env.Metadata["container_id"] = hostname
+		}
+	case inJail():
+		env.Runtime = runtimeJail
+		if hostname, err := os.Hostname(); err == nil {
+			env.Metadata["jail"] = hostname
+		}
+	}
+
+	env.CPULimit = cgroupCPULimit()
+	env.MemoryLimitBytes = cgroupMemoryLimit()
+
+	if len(env.Metadata) == 0 {
+		env.Metadata = nil
+	}
+	return env
+}
+
+func readEnvMetadata(metadata map[string]string, vars map[string]string) {
+	for envVar, key := range vars {
+		if value := os.Getenv(envVar); value != "" {
+			metadata[key] = value
+		}
+	}
+}
+
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import "net/http"
+
+// feature is an optional part of the monitor. Each one lives in files
+// guarded by build tags and registers itself from init, so a build with
+// -tags minimal leaves it out entirely, flags included.
+type feature struct {
+	name string
+	// endpoints are logged at startup
+	endpoints []string
+	// collectors returns extra collectors for /monitorz
+	collectors func() []collector
+	// routes registers the feature's HTTP handlers
+	routes func(mux *http.ServeMux)
+	// start runs once flags are parsed and labels are resolved
+	start func() error
+	// wrap decorates the server's handler, e.g. to require auth
+	wrap func(http.Handler) http.Handler
+	// reload covers the feature's flags that -config can change without a
+	// restart, keyed by flag name
+	reload map[string]reloader
+}
+
+var features []feature
+
+func registerFeature(f feature) {
+	features = append(features, f)
+}
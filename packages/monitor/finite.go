@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// nonFinite returns the JSON path of the first NaN or infinite value a
+// collector would write, or "" if there is none. Such a value can't be
+// encoded as JSON, so it would fail the whole response, and it compares
+// false with every threshold.
+func nonFinite(apply func(*MonitorResponse)) string {
+	var scratch MonitorResponse
+	apply(&scratch)
+	return nonFiniteField(reflect.ValueOf(scratch), "")
+}
+
+func nonFiniteField(v reflect.Value, path string) string {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return path
+		}
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return nonFiniteField(v.Elem(), path)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if p := nonFiniteField(v.Field(i), joinPath(path, name)); p != "" {
+				return p
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if p := nonFiniteField(v.Index(i), joinPath(path, strconv.Itoa(i))); p != "" {
+				return p
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if p := nonFiniteField(iter.Value(), joinPath(path, fmt.Sprint(iter.Key()))); p != "" {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -2,7 +2,11 @@ module github.com/abhi-arya1/autoscaled/monitor
 
 go 1.21.5
 
-require github.com/shirou/gopsutil/v3 v3.24.5
+require (
+	github.com/shirou/gopsutil/v3 v3.24.5
+	golang.org/x/net v0.29.0
+	golang.org/x/sync v0.8.0
+)
 
 require (
 	github.com/go-ole/go-ole v1.3.0 // indirect
@@ -13,4 +17,5 @@ require (
 	github.com/tklauser/numcpus v0.8.0 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
 	golang.org/x/sys v0.25.0 // indirect
+	golang.org/x/text v0.18.0 // indirect
 )
@@ -0,0 +1,357 @@
+//go:build !minimal
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/abhi-arya1/autoscaled/monitor/signing"
+)
+
+// gossipEntry is the latest sample known for one monitor in the fleet.
+type gossipEntry = api.FleetMember
+
+type gossipMessage struct {
+	From      string        `json:"from"`
+	Namespace string        `json:"namespace"`
+	Service   string        `json:"service,omitempty"`
+	Entries   []gossipEntry `json:"entries"`
+}
+
+type FleetResponse = api.FleetResponse
+
+// gossiper periodically pushes its view of the fleet to a few random peers
+// and merges the views it receives, so any monitor can answer questions
+// about the whole service without a central aggregator.
+type gossiper struct {
+	// Only peers in the same namespace and service are merged
+	namespace string
+	service   string
+
+	name     string
+	addr     string
+	seeds    []string
+	secret   []byte
+	interval time.Duration
+	ttl      time.Duration
+	fanout   int
+	client   *http.Client
+	// scheme is https when the monitors serve TLS
+	scheme string
+
+	mu      sync.Mutex
+	members map[string]gossipEntry
+}
+
+var (
+	gossipPeers     = flag.String("gossip-peers", "", "Comma-separated host:port of peer monitors; enables gossip of fleet samples")
+	gossipName      = flag.String("gossip-name", "", "Unique name for this monitor in gossip (defaults to hostname)")
+	gossipAdvertise = flag.String("gossip-advertise", "", "host:port peers use to reach this monitor (defaults to hostname and -port)")
+	gossipInterval  = flag.Duration("gossip-interval", 5*time.Second, "How often samples are gossiped to peers")
+	gossipFanout    = flag.Int("gossip-fanout", 3, "Number of peers contacted per gossip round")
+	gossipSecret    = flag.String("gossip-secret", os.Getenv("MONITOR_GOSSIP_SECRET"), "Shared secret used to sign gossip messages; required with -gossip-peers (env MONITOR_GOSSIP_SECRET)")
+)
+
+var gossip *gossiper
+
+func init() {
+	registerFeature(feature{
+		name:      "gossip",
+		endpoints: []string{"POST /gossipz", "GET /fleetz"},
+		routes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/gossipz", func(w http.ResponseWriter, r *http.Request) {
+				if gossip == nil {
+					api.WriteError(w, http.StatusNotFound, api.CodeNotEnabled, "gossip is not enabled (-gossip-peers)")
+					return
+				}
+				gossip.handleGossip(w, r)
+			})
+			mux.HandleFunc("/fleetz", func(w http.ResponseWriter, r *http.Request) {
+				if gossip == nil {
+					api.WriteError(w, http.StatusNotFound, api.CodeNotEnabled, "gossip is not enabled (-gossip-peers)")
+					return
+				}
+				gossip.handleFleet(w, r)
+			})
+		},
+		start: startGossip,
+	})
+}
+
+func startGossip() error {
+	if *gossipPeers == "" {
+		return nil
+	}
+
+	hostname, _ := os.Hostname()
+	name := *gossipName
+	if name == "" {
+		name = hostname
+	}
+	advertise := *gossipAdvertise
+	if advertise == "" {
+		advertise = fmt.Sprintf("%s:%d", hostname, listenPort)
+	}
+	if *gossipInterval <= 0 {
+		return fmt.Errorf("-gossip-interval must be positive")
+	}
+	// Members' addresses become gossip targets, so unsigned gossip would let
+	// anyone who can reach /gossipz point the monitor at any host
+	if *gossipSecret == "" {
+		return fmt.Errorf("-gossip-secret is required with -gossip-peers")
+	}
+	var seeds []string
+	for _, peer := range strings.Split(*gossipPeers, ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			seeds = append(seeds, peer)
+		}
+	}
+
+	gossip = newGossiper(name, advertise, seeds, []byte(*gossipSecret), *gossipInterval, *gossipFanout)
+	if servingTLS() {
+		gossip.scheme = "https"
+		if *tlsSelfSigned {
+			// Peers' certificates are generated at startup and can't be
+			// verified; gossip is authenticated with -gossip-secret instead
+			gossip.client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		}
+	}
+	gossip.namespace = labels[namespaceLabel]
+	gossip.service = labels[serviceLabel]
+	go gossip.run(context.Background())
+	return nil
+}
+
+func newGossiper(name, addr string, seeds []string, secret []byte, interval time.Duration, fanout int) *gossiper {
+	return &gossiper{
+		name:     name,
+		addr:     addr,
+		seeds:    seeds,
+		secret:   secret,
+		interval: interval,
+		// Members that haven't been heard from in a few rounds are dropped
+		ttl:     5 * interval,
+		fanout:  fanout,
+		client:  &http.Client{Timeout: interval},
+		scheme:  "http",
+		members: make(map[string]gossipEntry),
+	}
+}
+
+func (g *gossiper) run(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		g.round(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// round refreshes the local entry and sends the current view to up to
+// fanout peers.
+func (g *gossiper) round(ctx context.Context) {
+	s := sample(ctx)
+	g.merge([]gossipEntry{{
+		Name:          g.name,
+		Addr:          g.addr,
+		CPUUsage:      s.CPUUsage,
+		MemoryUsage:   s.MemoryUsage,
+		DiskUsage:     s.DiskUsage,
+		Degraded:      s.Degraded,
+		PressureScore: s.PressureScore,
+		SampledAt:     time.Now().UTC(),
+	}})
+
+	body, err := json.Marshal(gossipMessage{
+		From:      g.name,
+		Namespace: g.namespace,
+		Service:   g.service,
+		Entries:   g.snapshot(),
+	})
+	if err != nil {
+		return
+	}
+	for _, peer := range g.pickPeers() {
+		if err := g.send(ctx, peer, body); err != nil {
+			fmt.Fprintf(os.Stderr, "[monitor] Gossip to %s failed: %v\n", peer, err)
+		}
+	}
+}
+
+func (g *gossiper) send(ctx context.Context, peer string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.scheme+"://"+peer+"/gossipz", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signing.SignRequest(req, g.secret, body)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// pickPeers returns up to fanout random addresses from the known members
+// and seeds, excluding this monitor.
+func (g *gossiper) pickPeers() []string {
+	candidates := map[string]bool{}
+	for _, seed := range g.seeds {
+		candidates[seed] = true
+	}
+	g.mu.Lock()
+	for _, m := range g.members {
+		candidates[m.Addr] = true
+	}
+	g.mu.Unlock()
+	delete(candidates, g.addr)
+
+	peers := make([]string, 0, len(candidates))
+	for addr := range candidates {
+		peers = append(peers, addr)
+	}
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > g.fanout {
+		peers = peers[:g.fanout]
+	}
+	return peers
+}
+
+// merge keeps the newest entry per member and drops expired ones.
+func (g *gossiper) merge(entries []gossipEntry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, e := range entries {
+		if current, ok := g.members[e.Name]; !ok || e.SampledAt.After(current.SampledAt) {
+			g.members[e.Name] = e
+		}
+	}
+	for name, m := range g.members {
+		if time.Since(m.SampledAt) > g.ttl {
+			delete(g.members, name)
+		}
+	}
+}
+
+func (g *gossiper) snapshot() []gossipEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entries := make([]gossipEntry, 0, len(g.members))
+	for _, m := range g.members {
+		if time.Since(m.SampledAt) <= g.ttl {
+			entries = append(entries, m)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+func (g *gossiper) fleet() FleetResponse {
+	resp := FleetResponse{Members: g.snapshot()}
+	for _, m := range resp.Members {
+		if m.Degraded {
+			continue
+		}
+		resp.Count++
+		resp.AvgCPUUsage += m.CPUUsage
+		resp.AvgMemoryUsage += m.MemoryUsage
+		resp.AvgDiskUsage += m.DiskUsage
+	}
+	if resp.Count > 0 {
+		n := float64(resp.Count)
+		resp.AvgCPUUsage /= n
+		resp.AvgMemoryUsage /= n
+		resp.AvgDiskUsage /= n
+	}
+	return resp
+}
+
+func (g *gossiper) handleGossip(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if len(g.secret) == 0 {
+		api.WriteError(w, http.StatusForbidden, api.CodePermissionDenied, "gossip requires -gossip-secret")
+		return
+	}
+	if err := signing.VerifyRequest(r, g.secret, g.ttl); err != nil {
+		api.WriteError(w, http.StatusUnauthorized, api.CodeUnauthenticated, err.Error())
+		return
+	}
+
+	var msg gossipMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		api.WriteError(w, http.StatusBadRequest, api.CodeInvalidArgument, "invalid gossip message")
+		return
+	}
+	if msg.Namespace != g.namespace || msg.Service != g.service {
+		api.WriteError(w, http.StatusForbidden, api.CodePermissionDenied, "gossip from a different namespace or service")
+		return
+	}
+	entries := make([]gossipEntry, 0, len(msg.Entries))
+	for _, e := range msg.Entries {
+		if err := validEntry(e, g.ttl); err != nil {
+			api.WriteError(w, http.StatusBadRequest, api.CodeInvalidArgument, "invalid gossip entry: "+err.Error())
+			return
+		}
+		// Peers echo this monitor's own entry back; only it knows its sample
+		if e.Name != g.name {
+			entries = append(entries, e)
+		}
+	}
+	g.merge(entries)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validEntry rejects entries that would poison the fleet averages, or a
+// timestamp so far ahead that the entry would never be replaced or expire.
+func validEntry(e gossipEntry, ttl time.Duration) error {
+	if e.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	usages := map[string]float64{"cpu_usage": e.CPUUsage, "memory_usage": e.MemoryUsage, "disk_usage": e.DiskUsage}
+	if e.PressureScore != nil {
+		usages["pressure_score"] = *e.PressureScore
+	}
+	for name, v := range usages {
+		if !(v >= 0 && v <= 100) {
+			return fmt.Errorf("%s: %s is %g, outside 0-100", e.Name, name, v)
+		}
+	}
+	if e.SampledAt.After(time.Now().Add(ttl)) {
+		return fmt.Errorf("%s: sampled_at is in the future", e.Name)
+	}
+	return nil
+}
+
+func (g *gossiper) handleFleet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.fleet())
+}
@@ -0,0 +1,54 @@
+//go:build !minimal
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/signing"
+)
+
+func FuzzHandleGossip(f *testing.F) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	f.Add([]byte(fmt.Sprintf(`{"from":"peer","namespace":"","entries":[{"name":"peer","addr":"10.0.0.2:9090","cpu_usage":40,"memory_usage":50,"disk_usage":10,"pressure_score":20,"sampled_at":%q}]}`, now)))
+	f.Add([]byte(fmt.Sprintf(`{"from":"peer","entries":[{"name":"self","addr":"169.254.169.254:80","cpu_usage":0,"memory_usage":0,"disk_usage":0,"sampled_at":%q}]}`, now)))
+	f.Add([]byte(`{"from":"peer","entries":[{"name":"peer","cpu_usage":1e308,"sampled_at":"9999-01-01T00:00:00Z"}]}`))
+	f.Add([]byte(`{"from":"peer","namespace":"other","entries":[]}`))
+	f.Add([]byte(`{"entries":[{"name":"","cpu_usage":-1}]}`))
+	f.Add([]byte(`not json`))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		secret := []byte("secret")
+		g := newGossiper("self", "10.0.0.1:9090", nil, secret, time.Second, 3)
+
+		req := httptest.NewRequest(http.MethodPost, "/gossipz", bytes.NewReader(body))
+		signing.SignRequest(req, secret, body)
+		rec := httptest.NewRecorder()
+		g.handleGossip(rec, req)
+
+		switch rec.Code {
+		case http.StatusNoContent, http.StatusBadRequest, http.StatusForbidden:
+		default:
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		for _, m := range g.snapshot() {
+			if m.Name == g.name {
+				t.Fatalf("merged a peer's entry for this monitor: %+v", m)
+			}
+			if err := validEntry(m, g.ttl); err != nil {
+				t.Fatalf("merged an invalid entry: %v", err)
+			}
+		}
+		fleet := g.fleet()
+		for _, avg := range []float64{fleet.AvgCPUUsage, fleet.AvgMemoryUsage, fleet.AvgDiskUsage} {
+			if math.IsNaN(avg) || avg < 0 || avg > 100 {
+				t.Fatalf("fleet average %g is outside 0-100", avg)
+			}
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var (
+	childHealthURL      = flag.String("child-health-url", "", "URL of the child's health check; while it doesn't return 2xx, /readyz fails")
+	childHealthInterval = flag.Duration("child-health-interval", 5*time.Second, "How often -child-health-url is checked")
+	livenessFailures    = flag.Int("child-liveness-failures", 3, "Consecutive failed health checks after which /livez fails; 0 never fails it")
+)
+
+type HealthStatus = api.HealthStatus
+
+// healthChecker polls -child-health-url in the background, so probes are
+// answered instantly and a slow child can't time out the orchestrator's
+// probe.
+type healthChecker struct {
+	url    string
+	client *http.Client
+
+	mu     sync.Mutex
+	status HealthStatus
+	// passed is set once a check has succeeded, so a child that is still
+	// starting isn't reported as hung
+	passed bool
+}
+
+// childHealth is nil without -child-health-url.
+var childHealth *healthChecker
+
+func startChildHealth() {
+	if *childHealthURL == "" {
+		return
+	}
+	childHealth = &healthChecker{
+		url:    *childHealthURL,
+		client: &http.Client{Timeout: *childHealthInterval},
+		status: HealthStatus{URL: *childHealthURL, Error: "not checked yet"},
+	}
+	go func() {
+		ticker := time.NewTicker(*childHealthInterval)
+		defer ticker.Stop()
+		for {
+			childHealth.check(context.Background())
+			<-ticker.C
+		}
+	}()
+}
+
+func (h *healthChecker) check(ctx context.Context) {
+	err := h.get(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.CheckedAt = time.Now().UTC().Format(time.RFC3339)
+	if err != nil {
+		h.status.Healthy = false
+		h.status.Error = err.Error()
+		h.status.ConsecutiveFailures++
+		return
+	}
+	h.status.Healthy = true
+	h.status.Error = ""
+	h.status.ConsecutiveFailures = 0
+	h.passed = true
+}
+
+func (h *healthChecker) get(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", h.url, resp.Status)
+	}
+	return nil
+}
+
+func (h *healthChecker) snapshot() *HealthStatus {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.status
+	return &s
+}
+
+// hung reports whether a child that has been healthy has since failed
+// -child-liveness-failures checks in a row.
+func (h *healthChecker) hung() bool {
+	if h == nil || *livenessFailures <= 0 {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.passed && h.status.ConsecutiveFailures >= *livenessFailures
+}
+
+// healthCheck is one line of a /readyz or /livez response.
+type healthCheck struct {
+	name string
+	ok   bool
+}
+
+// readyzHandler succeeds while the instance can take traffic: the monitor
+// isn't draining, the child is running in exec mode, and the child's health
+// check passes.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	c := child.snapshot()
+	checks := []healthCheck{{"draining", !draining.Load()}}
+	if c != nil {
+		checks = append(checks, healthCheck{"child", c.Running})
+	}
+	if h := childHealth.snapshot(); h != nil {
+		checks = append(checks, healthCheck{"child-health", h.Healthy})
+	}
+	writeHealth(w, "readyz", checks)
+}
+
+// livezHandler succeeds unless the instance should be restarted: it fails
+// only once a child that was healthy has stopped answering its health check.
+// A crashed child is left to -restart, and a draining one to finish.
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	checks := []healthCheck{{"monitor", true}}
+	if childHealth != nil {
+		checks = append(checks, healthCheck{"child-health", !childHealth.hung()})
+	}
+	writeHealth(w, "livez", checks)
+}
+
+// writeHealth lists each check in the Kubernetes probe format. Reasons are
+// left to /statusz, since probes are served without authentication.
+func writeHealth(w http.ResponseWriter, name string, checks []healthCheck) {
+	var b strings.Builder
+	healthy := true
+	for _, c := range checks {
+		if c.ok {
+			fmt.Fprintf(&b, "[+]%s ok\n", c.name)
+		} else {
+			fmt.Fprintf(&b, "[-]%s failed\n", c.name)
+			healthy = false
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if healthy {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "%s%s check passed\n", b.String(), name)
+		return
+	}
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprintf(w, "%s%s check failed\n", b.String(), name)
+}
@@ -0,0 +1,145 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var historyWindow = flag.Duration("history-window", 15*time.Minute, "How much sample history /monitorz/history keeps in memory (0 disables it)")
+
+type (
+	HistoryPoint    = api.HistoryPoint
+	HistoryResponse = api.HistoryResponse
+)
+
+// sampleHistory is a fixed-size ring buffer of recent samples, so
+// controllers can compute trends instead of reacting to single readings.
+type sampleHistory struct {
+	mu     sync.Mutex
+	points []HistoryPoint
+	next   int
+	full   bool
+}
+
+var history *sampleHistory
+
+func init() {
+	registerFeature(feature{
+		name:      "history",
+		endpoints: []string{"GET /monitorz/history"},
+		routes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/monitorz/history", func(w http.ResponseWriter, r *http.Request) {
+				if history == nil {
+					api.WriteError(w, http.StatusNotFound, api.CodeNotEnabled, "history is not enabled (-history-window)")
+					return
+				}
+				history.handle(w, r)
+			})
+		},
+		start: startHistory,
+	})
+}
+
+func startHistory() error {
+	if *historyWindow <= 0 {
+		return nil
+	}
+	size := int(*historyWindow / *sampleInterval)
+	history = newSampleHistory(max(size, 1))
+	go history.record(context.Background(), *sampleInterval)
+	return nil
+}
+
+func newSampleHistory(size int) *sampleHistory {
+	return &sampleHistory{points: make([]HistoryPoint, size)}
+}
+
+// record appends a sample every interval until ctx is done.
+func (h *sampleHistory) record(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		h.add(historyPoint(sample(ctx)))
+	}
+}
+
+func historyPoint(r MonitorResponse) HistoryPoint {
+	point := HistoryPoint{
+		Time:          time.Now().UTC(),
+		PressureScore: r.PressureScore,
+		Degraded:      r.Degraded,
+	}
+	measured := func(collector string, value float64) *float64 {
+		if r.Status[collector] != statusOK {
+			return nil
+		}
+		return &value
+	}
+	point.CPUUsage = measured("cpu", r.CPUUsage)
+	point.MemoryUsage = measured("memory", r.MemoryUsage)
+	point.DiskUsage = measured("disk", r.DiskUsage)
+	return point
+}
+
+func (h *sampleHistory) add(p HistoryPoint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.points[h.next] = p
+	h.next = (h.next + 1) % len(h.points)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// since returns the points newer than cutoff, oldest first.
+func (h *sampleHistory) since(cutoff time.Time) []HistoryPoint {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var ordered []HistoryPoint
+	if h.full {
+		ordered = append(ordered, h.points[h.next:]...)
+	}
+	ordered = append(ordered, h.points[:h.next]...)
+
+	points := make([]HistoryPoint, 0, len(ordered))
+	for _, p := range ordered {
+		if p.Time.After(cutoff) {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+func (h *sampleHistory) handle(w http.ResponseWriter, r *http.Request) {
+	window := *historyWindow
+	if value := r.URL.Query().Get("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			api.WriteError(w, http.StatusBadRequest, api.CodeInvalidArgument, "window must be a positive duration")
+			return
+		}
+		window = min(parsed, *historyWindow)
+	}
+
+	resp := HistoryResponse{
+		IntervalSeconds: sampleInterval.Seconds(),
+		Samples:         h.since(time.Now().Add(-window)),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,338 @@
+//go:build !minimal && !noproxy
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var (
+	proxyPort    = flag.Int("proxy-port", 0, "Port to reverse-proxy HTTP to -upstream on, recording request rate, latency, and errors")
+	upstreamFlag = flag.String("upstream", "", "URL of the workload that -proxy-port forwards to, e.g. http://127.0.0.1:8080")
+)
+
+type (
+	HTTPProxyStats     = api.HTTPProxyStats
+	LatencyPercentiles = api.LatencyPercentiles
+)
+
+// latencySamples bounds how many request durations are kept per interval.
+// Beyond it, durations are sampled uniformly, which keeps percentiles
+// accurate at any request rate.
+const latencySamples = 4096
+
+var httpProxy *reverseProxy
+
+// httpProxyRequests returns how many requests the HTTP proxy has forwarded,
+// and false when it isn't running.
+func httpProxyRequests() (uint64, bool) {
+	if httpProxy == nil {
+		return 0, false
+	}
+	return httpProxy.total.Load(), true
+}
+
+func init() {
+	registerFeature(feature{
+		name: "http-proxy",
+		collectors: func() []collector {
+			if *proxyPort == 0 {
+				return nil
+			}
+			return []collector{{
+				name:    "http_proxy",
+				timeout: time.Second,
+				collect: func(context.Context) (func(*MonitorResponse), error) {
+					stats := httpProxy.stats()
+					return func(r *MonitorResponse) {
+						if r.Proxy == nil {
+							r.Proxy = &ProxyStats{}
+						}
+						r.Proxy.HTTP = &stats
+					}, nil
+				},
+			}}
+		},
+		start: startHTTPProxy,
+	})
+}
+
+func startHTTPProxy() error {
+	if *proxyPort == 0 && *upstreamFlag == "" {
+		return nil
+	}
+	if *proxyPort == 0 || *upstreamFlag == "" {
+		return errors.New("-proxy-port and -upstream must be set together")
+	}
+	upstream, err := url.Parse(*upstreamFlag)
+	if err != nil || (upstream.Scheme != "http" && upstream.Scheme != "https") || upstream.Host == "" {
+		return fmt.Errorf("invalid -upstream %q, expected an http:// or https:// URL", *upstreamFlag)
+	}
+	listen := ":" + strconv.Itoa(*proxyPort)
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+
+	p := &reverseProxy{listen: listen, upstream: upstream.String()}
+	p.proxy = httputil.NewSingleHostReverseProxy(upstream)
+	p.proxy.ErrorHandler = p.upstreamError
+	p.persist()
+	p.rates = newRateMeter(&p.total, &p.errors)
+	httpProxy = p
+
+	server := &http.Server{Handler: p, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		err := server.Serve(ln)
+		fmt.Fprintf(os.Stderr, "[monitor] HTTP proxy on %s stopped: %v\n", listen, err)
+	}()
+	go p.rates.run(context.Background(), *sampleInterval)
+	go p.latency.run(context.Background(), *sampleInterval)
+	go p.concurrency.run(context.Background(), *sampleInterval)
+	return nil
+}
+
+// reverseProxy forwards HTTP to the workload and measures the traffic. For
+// I/O-bound services, request rate and latency show load long before CPU
+// does.
+type reverseProxy struct {
+	listen   string
+	upstream string
+	proxy    *httputil.ReverseProxy
+
+	total          atomic.Uint64
+	rejected       atomic.Uint64
+	errors         atomic.Uint64
+	upstreamErrors atomic.Uint64
+	rates          *rateMeter
+	latency        latencyWindow
+	concurrency    concurrencyMeter
+}
+
+// persist keeps the proxy's totals across monitor restarts with
+// -state-file.
+func (p *reverseProxy) persist() {
+	prefix := "http_proxy/" + p.listen + "=" + p.upstream + "/"
+	persistCounter(prefix+"total_requests", &p.total)
+	persistCounter(prefix+"rejected_requests", &p.rejected)
+	persistCounter(prefix+"errors", &p.errors)
+	persistCounter(prefix+"upstream_errors", &p.upstreamErrors)
+}
+
+func (p *reverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.total.Add(1)
+	if !controls.admit() {
+		p.rejected.Add(1)
+		w.Header().Set("Retry-After", "1")
+		api.WriteError(w, http.StatusServiceUnavailable, api.CodeLoadShedding, "instance is shedding load")
+		return
+	}
+
+	for name, value := range ladder.headers() {
+		r.Header.Set(name, value)
+	}
+	p.concurrency.add(1)
+	defer p.concurrency.add(-1)
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	p.proxy.ServeHTTP(sw, r)
+	p.latency.observe(time.Since(start))
+	if sw.status >= 500 {
+		p.errors.Add(1)
+	}
+}
+
+// upstreamError answers requests the upstream couldn't be reached for.
+func (p *reverseProxy) upstreamError(w http.ResponseWriter, r *http.Request, err error) {
+	p.upstreamErrors.Add(1)
+	if errors.Is(err, context.Canceled) {
+		// The client went away, so nobody reads the response
+		return
+	}
+	api.WriteError(w, http.StatusBadGateway, api.CodeUpstreamUnavailable, "upstream unavailable")
+}
+
+func (p *reverseProxy) stats() HTTPProxyStats {
+	rates := p.rates.get()
+	inFlight, average := p.concurrency.get()
+	stats := HTTPProxyStats{
+		Listen:            p.listen,
+		Upstream:          p.upstream,
+		TotalRequests:     p.total.Load(),
+		RejectedRequests:  p.rejected.Load(),
+		Errors:            p.errors.Load(),
+		UpstreamErrors:    p.upstreamErrors.Load(),
+		InFlight:          inFlight,
+		Concurrency:       average,
+		RequestsPerSecond: rates[0],
+		ErrorsPerSecond:   rates[1],
+		Latency:           p.latency.get(),
+	}
+	if rates[0] > 0 {
+		percent := min(100*rates[1]/rates[0], 100)
+		stats.ErrorPercent = &percent
+	}
+	return stats
+}
+
+// statusWriter records the status code sent to the client. Unwrap lets the
+// reverse proxy flush streamed responses and hijack upgraded connections.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// latencyWindow computes latency percentiles over fixed intervals, so they
+// don't depend on how often the monitor is scraped. Each interval keeps a
+// uniform sample of at most latencySamples durations.
+type latencyWindow struct {
+	mu      sync.Mutex
+	seen    int
+	samples []time.Duration
+	last    *LatencyPercentiles
+}
+
+func (l *latencyWindow) observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seen++
+	if len(l.samples) < latencySamples {
+		l.samples = append(l.samples, d)
+	} else if i := rand.Intn(l.seen); i < latencySamples {
+		l.samples[i] = d
+	}
+}
+
+// run recomputes the percentiles every interval until ctx is done. An
+// interval without requests has no latency rather than a latency of 0.
+func (l *latencyWindow) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		l.mu.Lock()
+		samples := l.samples
+		l.samples, l.seen = nil, 0
+		l.mu.Unlock()
+
+		var last *LatencyPercentiles
+		if len(samples) > 0 {
+			sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+			last = &LatencyPercentiles{
+				P50Seconds: percentile(samples, 50).Seconds(),
+				P95Seconds: percentile(samples, 95).Seconds(),
+				P99Seconds: percentile(samples, 99).Seconds(),
+			}
+		}
+		l.mu.Lock()
+		l.last = last
+		l.mu.Unlock()
+	}
+}
+
+func (l *latencyWindow) get() *LatencyPercentiles {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.last == nil {
+		return nil
+	}
+	last := *l.last
+	return &last
+}
+
+// concurrencyMeter tracks the requests in flight and their time-weighted
+// average over each interval. The instantaneous count jumps with every
+// request, so the average is what concurrency-based scaling should target.
+type concurrencyMeter struct {
+	mu       sync.Mutex
+	inFlight int64
+	changed  time.Time
+	// area is the request-seconds spent in flight since since
+	area    float64
+	since   time.Time
+	average *float64
+}
+
+func (c *concurrencyMeter) add(delta int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accumulate(time.Now())
+	c.inFlight += delta
+}
+
+func (c *concurrencyMeter) accumulate(now time.Time) {
+	if !c.changed.IsZero() {
+		c.area += float64(c.inFlight) * now.Sub(c.changed).Seconds()
+	}
+	c.changed = now
+}
+
+// run recomputes the average every interval until ctx is done. It is only
+// reported once a full interval has been measured.
+func (c *concurrencyMeter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	c.mu.Lock()
+	c.since = time.Now()
+	c.mu.Unlock()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			c.accumulate(now)
+			average := c.area / now.Sub(c.since).Seconds()
+			c.average = &average
+			c.area, c.since = 0, now
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (c *concurrencyMeter) get() (inFlight int64, average *float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.average != nil {
+		v := *c.average
+		average = &v
+	}
+	return c.inFlight, average
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// inJail reports whether the monitor is running inside a FreeBSD jail.
+func inJail() bool {
+	jailed, err := syscall.SysctlUint32("security.jail.jailed")
+	return err == nil && jailed == 1
+}
@@ -0,0 +1,7 @@
+//go:build !freebsd
+
+package main
+
+func inJail() bool {
+	return false
+}
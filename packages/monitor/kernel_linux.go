@@ -0,0 +1,70 @@
+//go:build linux && !minimal
+
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	entropyAvailPath   = "/proc/sys/kernel/random/entropy_avail"
+	conntrackCountPath = "/proc/sys/net/netfilter/nf_conntrack_count"
+	conntrackMaxPath   = "/proc/sys/net/netfilter/nf_conntrack_max"
+)
+
+func init() {
+	registerFeature(feature{
+		name:       "kernel",
+		collectors: kernelCollectors,
+	})
+}
+
+// kernelCollectors returns collectors for Linux kernel resources that can be
+// exhausted independently of CPU and memory. Collectors whose /proc files
+// don't exist (e.g. nf_conntrack isn't loaded) are skipped.
+func kernelCollectors() []collector {
+	ttl, timeout := *cacheTTL, *collectTimeout
+
+	var collectors []collector
+	if fileExists(entropyAvailPath) {
+		collectors = append(collectors, newCollector("entropy", ttl, timeout, getEntropyAvailable,
+			func(r *MonitorResponse, v int) { r.EntropyAvailable = &v }))
+	}
+	if fileExists(conntrackCountPath) && fileExists(conntrackMaxPath) {
+		collectors = append(collectors, newCollector("conntrack", ttl, timeout, getConntrackStats,
+			func(r *MonitorResponse, v ConntrackStats) { r.Conntrack = &v }))
+	}
+	return collectors
+}
+
+func getEntropyAvailable(ctx context.Context) (int, error) {
+	return readProcInt(entropyAvailPath)
+}
+
+func getConntrackStats(ctx context.Context) (ConntrackStats, error) {
+	count, err := readProcInt(conntrackCountPath)
+	if err != nil {
+		return ConntrackStats{}, err
+	}
+	limit, err := readProcInt(conntrackMaxPath)
+	if err != nil {
+		return ConntrackStats{}, err
+	}
+
+	stats := ConntrackStats{Count: count, Max: limit}
+	if limit > 0 {
+		stats.UsagePercent = float64(count) / float64(limit) * 100
+	}
+	return stats, nil
+}
+
+func readProcInt(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
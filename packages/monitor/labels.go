@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	namespaceLabel   = "namespace"
+	serviceLabel     = "service"
+	defaultNamespace = "default"
+)
+
+// applyScope sets the namespace and service labels that scope this instance
+// to a tenant. Explicit flags win, then labels configured with -labels, then
+// the Kubernetes namespace; the namespace falls back to "default".
+func applyScope(labels map[string]string, namespace, service string, env *Environment) map[string]string {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	if namespace == "" {
+		namespace = labels[namespaceLabel]
+	}
+	if namespace == "" && env != nil {
+		namespace = env.Metadata[namespaceLabel]
+	}
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	labels[namespaceLabel] = namespace
+
+	if service != "" {
+		labels[serviceLabel] = service
+	}
+	return labels
+}
+
+// parseLabels parses a comma-separated list of key=value pairs.
+func parseLabels(value string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return labels, nil
+}
+
+// readDownwardAPIFile parses a Kubernetes downward API labels or annotations
+// file, which holds one key="quoted value" pair per line.
+func readDownwardAPIFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, quoted, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || key == "" {
+			continue
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			value = quoted
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// loadLabels merges labels from the downward API volume at dir (if present)
+// with the explicitly configured ones, which take precedence. Annotations
+// are opt-in since they often carry large values such as
+// kubectl.kubernetes.io/last-applied-configuration.
+func loadLabels(configured map[string]string, dir string, includeAnnotations bool) map[string]string {
+	labels := map[string]string{}
+
+	if dir != "" {
+		if podLabels, err := readDownwardAPIFile(filepath.Join(dir, "labels")); err == nil {
+			for k, v := range podLabels {
+				labels[k] = v
+			}
+		}
+		if includeAnnotations {
+			if annotations, err := readDownwardAPIFile(filepath.Join(dir, "annotations")); err == nil {
+				for k, v := range annotations {
+					labels[k] = v
+				}
+			}
+		}
+	}
+
+	for k, v := range configured {
+		labels[k] = v
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	portConflict = flag.String("port-conflict", "fail", `What to do when -port can't be bound: "fail" exits with code 3, "fallback" listens on an ephemeral port instead`)
+	portFile     = flag.String("port-file", "", "File the port actually listened on is written to once bound (- for stdout)")
+)
+
+// exitListenFailed is the exit code when the monitor can't bind its port, so
+// orchestrators can tell a port conflict from a crash.
+const exitListenFailed = 3
+
+// listenPort is the port actually being served, which differs from -port
+// after a fallback, with -port 0, or under socket activation.
+var listenPort int
+
+// listenFDsStart is the first file descriptor systemd passes to an activated
+// service.
+const listenFDsStart = 3
+
+// listen binds the monitor's port, falling back to an ephemeral port if
+// -port-conflict allows it, and records the port it got. A socket passed by
+// systemd socket activation takes precedence over -port.
+func listen(port int) (net.Listener, error) {
+	ln, err := activatedListener()
+	if err != nil {
+		return nil, err
+	}
+	if ln == nil {
+		ln, err = net.Listen("tcp", fmt.Sprintf(":%d", port))
+		// Any bind failure falls back, since a port below 1024 without
+		// privileges is as fatal as one already in use
+		if err != nil && *portConflict == "fallback" {
+			fmt.Fprintf(os.Stderr, "[monitor] Can't listen on port %d (%v), falling back to an ephemeral port\n", port, err)
+			ln, err = net.Listen("tcp", ":0")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+		listenPort = addr.Port
+	}
+	if err := writePortFile(*portFile, listenPort); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to write port file: %w", err)
+	}
+	return ln, nil
+}
+
+// writePortFile records the bound port for sidecars and scripts; "-" writes
+// it to stdout instead.
+func writePortFile(path string, port int) error {
+	line := strconv.Itoa(port) + "\n"
+	switch path {
+	case "":
+		return nil
+	case "-":
+		_, err := os.Stdout.WriteString(line)
+		return err
+	}
+	return os.WriteFile(path, []byte(line), 0o644)
+}
+
+// activatedListener returns the socket systemd passed in, or nil if the
+// monitor wasn't socket-activated. With several sockets, the one named
+// "monitor" in LISTEN_FDNAMES is used, or else the first. The variables are
+// cleared so a child in exec mode doesn't think it was activated too.
+func activatedListener() (net.Listener, error) {
+	pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	count, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	if pid != os.Getpid() || count < 1 {
+		return nil, nil
+	}
+
+	index := 0
+	for i, name := range names {
+		if name == "monitor" && i < count {
+			index = i
+		}
+	}
+	f := os.NewFile(uintptr(listenFDsStart+index), "LISTEN_FD_"+strconv.Itoa(listenFDsStart+index))
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use activated socket: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,81 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+type LoadAverage = api.LoadAverage
+
+func init() {
+	registerFeature(feature{
+		name:       "load",
+		collectors: loadCollectors,
+	})
+}
+
+// loadCollectors returns collectors for CPU contention that usage alone
+// hides: run queue length on Unix, and time stolen by the hypervisor on
+// Linux.
+func loadCollectors() []collector {
+	var collectors []collector
+	if runtime.GOOS != "windows" {
+		collectors = append(collectors, newCollector("load", *cacheTTL, *collectTimeout, getLoadAverage,
+			func(r *MonitorResponse, v LoadAverage) { r.LoadAverage = &v }))
+	}
+	if runtime.GOOS == "linux" {
+		sampler := newAsyncSample("steal", *sampleInterval, *collectTimeout, (&stealSampler{}).sample)
+		go sampler.run(context.Background())
+		collectors = append(collectors, newAsyncCollector(sampler, func(r *MonitorResponse, v float64, age time.Duration) {
+			r.CPUSteal = &v
+			markStale(r, "steal", age, sampler.currentInterval())
+		}))
+	}
+	return collectors
+}
+
+func getLoadAverage(ctx context.Context) (LoadAverage, error) {
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return LoadAverage{}, err
+	}
+	return LoadAverage{Load1: avg.Load1, Load5: avg.Load5, Load15: avg.Load15, Cores: runtime.NumCPU()}, nil
+}
+
+// stealSampler measures the share of CPU time the hypervisor gave to other
+// guests between consecutive samples. On oversubscribed hosts it rises
+// while cpu_usage stays flat, because stolen time isn't counted as usage.
+// Only the background sampler calls it, so it needs no locking.
+type stealSampler struct {
+	prev *cpu.TimesStat
+}
+
+func (s *stealSampler) sample(ctx context.Context) (float64, error) {
+	times, err := cpu.TimesWithContext(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(times) == 0 {
+		return 0, errNoSampleYet
+	}
+
+	cur, prev := times[0], s.prev
+	s.prev = &cur
+	if prev == nil {
+		// Steal is a share of the time between two samples
+		return 0, errNoSampleYet
+	}
+	// Guest time is already counted in user time, so Total double-counts it
+	total := (cur.Total() - cur.Guest - cur.GuestNice) - (prev.Total() - prev.Guest - prev.GuestNice)
+	if total <= 0 {
+		return 0, nil
+	}
+	return min(max((cur.Steal-prev.Steal)/total*100, 0), 100), nil
+}
@@ -1,92 +1,251 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
-	"syscall"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/disk"
-	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/abhi-arya1/autoscaled/monitor/api"
 )
 
-type MonitorResponse struct {
-	CPUUsage    float64 `json:"cpu_usage"`
-	MemoryUsage float64 `json:"memory_usage"`
-	DiskUsage   float64 `json:"disk_usage"`
-}
+var (
+	port                   = flag.Int("port", 81, "Port to listen on (0 lets the OS pick a free one)")
+	cacheTTL               = flag.Duration("cache-ttl", time.Second, "How long on-demand samples (kernel, clock, child process) are reused across requests")
+	collectTimeout         = flag.Duration("collect-timeout", time.Second, "Maximum time each collector may take per sample")
+	sampleInterval         = flag.Duration("interval", time.Second, "How often CPU and memory are sampled in the background")
+	diskInterval           = flag.Duration("disk-interval", 30*time.Second, "How often disk usage is sampled in the background")
+	diskPaths              = flag.String("disk-paths", "", "Comma-separated mount points whose usage is reported (defaults to / or the system drive)")
+	idleCPUThreshold       = flag.Float64("idle-cpu-threshold", 5, "CPU percentage below which the instance counts as idle")
+	namespace              = flag.String("namespace", "", "Tenant namespace this instance belongs to (defaults to the Kubernetes namespace, then \"default\")")
+	service                = flag.String("service", "", "Service name this instance belongs to")
+	labelsFlag             = flag.String("labels", "", "Comma-separated key=value labels attached to every sample")
+	downwardAPIDir         = flag.String("downward-api-dir", "/etc/podinfo", "Kubernetes downward API volume to read pod labels from")
+	downwardAPIAnnotations = flag.Bool("downward-api-annotations", false, "Also attach pod annotations from the downward API volume as labels")
+)
+
+var (
+	collectors  []collector
+	environment *Environment
+	labels      map[string]string
+	// exportedLabels are labels with -label-allow and -label-deny applied;
+	// labels itself stays intact for scoping and access control
+	exportedLabels map[string]string
+	// endpoints lists the "METHOD /path" routes served by this build
+	endpoints []string
+	// perCPUCollector reports per-core usage, only when asked for with
+	// ?percpu=true
+	perCPUCollector collector
+)
 
-func getCPUUsage() float64 {
-	percent, err := cpu.Percent(100*time.Millisecond, false)
-	if err != nil || len(percent) == 0 {
-		return 0.0
+func monitorHandler(w http.ResponseWriter, r *http.Request) {
+	var extra []collector
+	if value := r.URL.Query().Get("percpu"); value != "" {
+		perCPU, err := strconv.ParseBool(value)
+		if err != nil {
+			api.WriteError(w, http.StatusBadRequest, api.CodeInvalidArgument, "percpu must be true or false")
+			return
+		}
+		if perCPU {
+			extra = append(extra, perCPUCollector)
+		}
 	}
-	return percent[0]
+	resp := sample(r.Context(), extra...)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
-func getMemoryUsage() float64 {
-	v, err := mem.VirtualMemory()
-	if err != nil {
-		return 0.0
+// validateFlags rejects values that would otherwise surface much later as
+// a panic or a nonsensical sample: intervals drive tickers, and a NaN
+// threshold compares false with everything.
+func validateFlags() error {
+	positive := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"interval", *sampleInterval},
+		{"disk-interval", *diskInterval},
+		{"collect-timeout", *collectTimeout},
+		{"state-interval", *stateInterval},
+		{"child-health-interval", *childHealthInterval},
+	}
+	for _, f := range positive {
+		if f.value <= 0 {
+			return fmt.Errorf("-%s must be positive", f.name)
+		}
+	}
+	if !(*idleCPUThreshold >= 0 && *idleCPUThreshold <= 100) {
+		return fmt.Errorf("-idle-cpu-threshold must be between 0 and 100")
 	}
-	return v.UsedPercent
+	return nil
 }
 
-func getDiskUsage() float64 {
-	root := "/"
-	if _, err := os.Stat("/"); os.IsNotExist(err) {
-		root = "C:\\"
+func main() {
+	flag.Parse()
+	if err := loadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "[monitor] %v\n", err)
+		os.Exit(2)
 	}
-	u, err := disk.Usage(root)
+
+	configuredLabels, err := parseLabels(*labelsFlag)
 	if err != nil {
-		return 0.0
+		fmt.Fprintf(os.Stderr, "[monitor] %v\n", err)
+		os.Exit(2)
 	}
-	return u.UsedPercent
-}
 
-func monitorHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.URL.Path {
-	case "/monitorz":
-		resp := MonitorResponse{
-			CPUUsage:    getCPUUsage(),
-			MemoryUsage: getMemoryUsage(),
-			DiskUsage:   getDiskUsage(),
+	if err := validateFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "[monitor] %v\n", err)
+		os.Exit(2)
+	}
+
+	loadState()
+	startChildHealth()
+
+	// CPU and memory are sampled in the background and served from memory, so
+	// /monitorz never waits on a measurement window under scrape pressure.
+	// Idle time is tracked from the CPU samples rather than from scrapes, so
+	// it follows the workload instead of how often something asks
+	cpuSampler := newAsyncSample("cpu", *sampleInterval, *collectTimeout, func(ctx context.Context) (float64, error) {
+		cpu, err := getCPUUsage(ctx)
+		if err == nil {
+			activity.observe(cpu)
 		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
+		return cpu, err
+	})
+	memorySampler := newAsyncSample("memory", *sampleInterval, *collectTimeout, getMemoryUsage)
+	perCPUSampler := newAsyncSample("percpu", *sampleInterval, *collectTimeout, getPerCPUUsage)
+	go cpuSampler.run(context.Background())
+	go memorySampler.run(context.Background())
+	go perCPUSampler.run(context.Background())
+	perCPUCollector = newAsyncCollector(perCPUSampler, func(r *MonitorResponse, v []float64, age time.Duration) {
+		r.PerCPU = v
+		markStale(r, "percpu", age, perCPUSampler.currentInterval())
+	})
+	collectors = []collector{
+		newAsyncCollector(cpuSampler, func(r *MonitorResponse, v float64, age time.Duration) {
+			r.CPUUsage = v
+			markStale(r, "cpu", age, cpuSampler.currentInterval())
+		}),
+		newAsyncCollector(memorySampler, func(r *MonitorResponse, v float64, age time.Duration) {
+			r.MemoryUsage = v
+			markStale(r, "memory", age, memorySampler.currentInterval())
+		}),
+	}
+	// disk.Usage can take hundreds of milliseconds on network filesystems, so
+	// it's sampled in the background rather than while a scraper waits
+	paths := parseDiskPaths(*diskPaths)
+	diskSampler := newAsyncSample("disk", *diskInterval, *diskInterval, func(ctx context.Context) (diskUsage, error) {
+		return getDiskUsage(ctx, paths)
+	})
+	go diskSampler.run(context.Background())
+	collectors = append(collectors, newAsyncCollector(diskSampler,
+		func(r *MonitorResponse, v diskUsage, age time.Duration) {
+			r.DiskUsage = v.Percent
+			r.InodeUsage = v.InodesPercent
+			r.Disks = v.Disks
+			r.DiskSampleAgeSeconds = age.Seconds()
+		}))
+	if len(flag.Args()) > 0 {
+		collectors = append(collectors, childProcessCollector())
+	}
+	for _, f := range features {
+		if f.collectors != nil {
+			collectors = append(collectors, f.collectors()...)
+		}
+	}
 
-	default:
-		http.NotFound(w, r)
+	environment = detectEnvironment()
+	activity.setThreshold(*idleCPUThreshold)
+	labels = loadLabels(configuredLabels, *downwardAPIDir, *downwardAPIAnnotations)
+	labels = applyScope(labels, *namespace, *service, environment)
+	redact := newRedactor(*labelAllow, *labelDeny)
+	exportedLabels = redact.apply(labels)
+	environment = redact.environment(environment)
+
+	if !validRestartPolicy(*restartPolicy) {
+		fmt.Fprintf(os.Stderr, "[monitor] -restart must be \"never\", \"on-failure\", or \"always\"\n")
+		os.Exit(2)
+	}
+	if *portConflict != "fail" && *portConflict != "fallback" {
+		fmt.Fprintf(os.Stderr, "[monitor] -port-conflict must be \"fail\" or \"fallback\"\n")
+		os.Exit(2)
+	}
+	// Bind before starting features, so the server never runs without a port
+	// and gossip advertises the port actually in use
+	ln, err := listen(*port)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[monitor] Failed to listen: %v\n", err)
+		os.Exit(exitListenFailed)
 	}
-}
 
-func main() {
-	port := flag.Int("port", 81, "Port to listen on")
-	flag.Parse()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/monitorz", monitorHandler)
+	mux.HandleFunc("/statusz", statusHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	mux.HandleFunc("/livez", livezHandler)
+	mux.HandleFunc("/protectz", protectHandler)
+	mux.HandleFunc("/quitquitquit", quitHandler)
+	endpoints = []string{"GET /monitorz", "GET /statusz", "GET /readyz", "GET /livez", "GET /protectz", "POST /protectz", "DELETE /protectz", "POST /quitquitquit"}
+
+	var handler http.Handler = mux
+	for _, f := range features {
+		if f.start != nil {
+			if err := f.start(); err != nil {
+				fmt.Fprintf(os.Stderr, "[monitor] %s: %v\n", f.name, err)
+				os.Exit(2)
+			}
+		}
+		if f.routes != nil {
+			f.routes(mux)
+		}
+		if f.wrap != nil {
+			handler = f.wrap(handler)
+		}
+		endpoints = append(endpoints, f.endpoints...)
+	}
+
+	handler = requireClientCert(handler)
 
 	// Check if we need to exec a command
 	args := flag.Args()
 
-	addr := fmt.Sprintf(":%d", *port)
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[monitor] %v\n", err)
+		os.Exit(2)
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
 
 	// Start HTTP server in background
 	server := &http.Server{
-		Addr:         addr,
-		Handler:      http.HandlerFunc(monitorHandler),
+		Handler:      handler,
+		TLSConfig:    tlsConfig,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
 	go func() {
-		fmt.Fprintf(os.Stderr, "[monitor] Starting on port %d\n", *port)
-		fmt.Fprintf(os.Stderr, "[monitor] Endpoint: GET http://localhost:%d/monitorz\n", *port)
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		fmt.Fprintf(os.Stderr, "[monitor] Starting on port %d\n", listenPort)
+		for _, endpoint := range endpoints {
+			method, path, _ := strings.Cut(endpoint, " ")
+			fmt.Fprintf(os.Stderr, "[monitor] Endpoint: %s %s://localhost:%d%s\n", method, scheme, listenPort, path)
+		}
+		var err error
+		if tlsConfig != nil {
+			err = server.ServeTLS(ln, "", "")
+		} else {
+			err = server.Serve(ln)
+		}
+		if err != http.ErrServerClosed {
 			fmt.Fprintf(os.Stderr, "[monitor] Server error: %v\n", err)
 		}
 	}()
@@ -94,40 +253,49 @@ func main() {
 	// Give server time to start
 	time.Sleep(100 * time.Millisecond)
 
+	signal.Notify(shutdownRequests, shutdownSignals...)
+	watchConfig()
+
 	if len(args) > 0 {
-		// Exec mode: run the provided command
-		cmd := exec.Command(args[0], args[1:]...)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		// Forward signals to child process
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-		if err := cmd.Start(); err != nil {
-			fmt.Fprintf(os.Stderr, "[monitor] Failed to start command: %v\n", err)
-			os.Exit(1)
-		}
+		// Exec mode: run and supervise the provided command
+		child = newChildState(args)
+		s := newSupervisor(args)
+		commandSupervisor.Store(s)
 
-		// Handle signals
+		// Handle signals, letting the child finish its current work first. A
+		// second signal skips the wait.
 		go func() {
-			sig := <-sigChan
-			cmd.Process.Signal(sig)
+			sig := <-shutdownRequests
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-shutdownRequests
+				cancel()
+			}()
+			waitForDrain(ctx)
+			s.stop(sig)
 		}()
 
-		// Wait for command to finish
-		if err := cmd.Wait(); err != nil {
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				os.Exit(exitErr.ExitCode())
-			}
-			os.Exit(1)
+		if len(passthroughSignals) > 0 {
+			passthrough := make(chan os.Signal, 1)
+			signal.Notify(passthrough, passthroughSignals...)
+			go func() {
+				for sig := range passthrough {
+					s.forward(sig)
+				}
+			}()
 		}
+		startReaper(s.supervised)
+
+		code := s.run()
+		saveState()
+		shutdownServer(server)
+		os.Exit(code)
 	} else {
 		// Standalone mode: just run the server
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+		<-shutdownRequests
 		fmt.Fprintf(os.Stderr, "\n[monitor] Shutting down...\n")
+		draining.Store(true)
+		saveState()
+		shutdownServer(server)
 	}
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+type (
+	MonitorResponse = api.MonitorResponse
+	ConntrackStats  = api.ConntrackStats
+	DiskStats       = api.DiskStats
+)
+
+const (
+	statusOK      = api.StatusOK
+	statusError   = api.StatusError
+	statusTimeout = api.StatusTimeout
+)
+
+// getCPUUsage returns CPU usage since the previous call, so it must only be
+// called from the background sampler.
+func getCPUUsage(ctx context.Context) (float64, error) {
+	percent, err := cpu.PercentWithContext(ctx, 0, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(percent) == 0 {
+		return 0, errors.New("no cpu samples returned")
+	}
+	return percent[0], nil
+}
+
+// getPerCPUUsage returns each core's usage since the previous call, so it
+// must only be called from the background sampler.
+func getPerCPUUsage(ctx context.Context) ([]float64, error) {
+	percent, err := cpu.PercentWithContext(ctx, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(percent) == 0 {
+		return nil, errors.New("no cpu samples returned")
+	}
+	return percent, nil
+}
+
+func getMemoryUsage(ctx context.Context) (float64, error) {
+	v, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return v.UsedPercent, nil
+}
+
+// diskUsage is the space and inode usage of the filesystems in -disk-paths.
+// Percent and InodesPercent are the fullest filesystem's, since whichever
+// fills first stops the workload.
+type diskUsage struct {
+	Percent       float64
+	InodesPercent *float64
+	Disks         []DiskStats
+}
+
+// getDiskUsage samples every path, failing if any of them can't be read so
+// a broken mount isn't mistaken for a healthy one.
+func getDiskUsage(ctx context.Context, paths []string) (diskUsage, error) {
+	var usage diskUsage
+	for _, path := range paths {
+		u, err := disk.UsageWithContext(ctx, path)
+		if err != nil {
+			return diskUsage{}, fmt.Errorf("%s: %w", path, err)
+		}
+		stats := DiskStats{Path: path, UsagePercent: u.UsedPercent}
+		usage.Percent = max(usage.Percent, u.UsedPercent)
+		// Filesystems without a fixed inode table (NTFS, btrfs) report none
+		if u.InodesTotal > 0 {
+			inodes := u.InodesUsedPercent
+			stats.InodeUsagePercent = &inodes
+			if usage.InodesPercent == nil || inodes > *usage.InodesPercent {
+				usage.InodesPercent = &inodes
+			}
+		}
+		usage.Disks = append(usage.Disks, stats)
+	}
+	return usage, nil
+}
+
+// parseDiskPaths splits -disk-paths, defaulting to the platform's root
+// filesystem.
+func parseDiskPaths(s string) []string {
+	var paths []string
+	for _, path := range strings.Split(s, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return []string{diskPath()}
+	}
+	return paths
+}
@@ -0,0 +1,109 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+type (
+	NetworkStats = api.NetworkStats
+	NetworkRates = api.NetworkRates
+)
+
+func init() {
+	registerFeature(feature{
+		name: "network",
+		collectors: func() []collector {
+			sampler := newAsyncSample("network", *sampleInterval, *collectTimeout, (&networkSampler{}).sample)
+			go sampler.run(context.Background())
+			return []collector{newAsyncCollector(sampler, func(r *MonitorResponse, v NetworkStats, age time.Duration) {
+				r.Network = &v
+				markStale(r, "network", age, sampler.currentInterval())
+			})}
+		},
+	})
+}
+
+// networkSampler turns the kernel's cumulative interface counters into
+// rates between consecutive samples.
+// Only the background sampler calls it, so it needs no locking.
+type networkSampler struct {
+	prev   map[string]psnet.IOCountersStat
+	prevAt time.Time
+}
+
+func (s *networkSampler) sample(ctx context.Context) (NetworkStats, error) {
+	counters, err := psnet.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return NetworkStats{}, err
+	}
+	now := time.Now()
+	loopback := loopbackInterfaces()
+
+	prev, elapsed := s.prev, now.Sub(s.prevAt).Seconds()
+	s.prev = make(map[string]psnet.IOCountersStat, len(counters))
+	for _, c := range counters {
+		s.prev[c.Name] = c
+	}
+	s.prevAt = now
+	if prev == nil {
+		// Rates need two samples
+		return NetworkStats{}, errNoSampleYet
+	}
+
+	stats := NetworkStats{Interfaces: map[string]NetworkRates{}}
+	for _, c := range counters {
+		if loopback[c.Name] {
+			continue
+		}
+		p, ok := prev[c.Name]
+		if !ok {
+			continue
+		}
+		rate := func(cur, old uint64) float64 {
+			// Counters reset when an interface is recreated
+			if cur < old {
+				return 0
+			}
+			return float64(cur-old) / elapsed
+		}
+		rates := NetworkRates{
+			BytesInPerSecond:    rate(c.BytesRecv, p.BytesRecv),
+			BytesOutPerSecond:   rate(c.BytesSent, p.BytesSent),
+			PacketsInPerSecond:  rate(c.PacketsRecv, p.PacketsRecv),
+			PacketsOutPerSecond: rate(c.PacketsSent, p.PacketsSent),
+			ErrorsInPerSecond:   rate(c.Errin, p.Errin),
+			ErrorsOutPerSecond:  rate(c.Errout, p.Errout),
+		}
+		stats.Interfaces[c.Name] = rates
+		stats.Total.BytesInPerSecond += rates.BytesInPerSecond
+		stats.Total.BytesOutPerSecond += rates.BytesOutPerSecond
+		stats.Total.PacketsInPerSecond += rates.PacketsInPerSecond
+		stats.Total.PacketsOutPerSecond += rates.PacketsOutPerSecond
+		stats.Total.ErrorsInPerSecond += rates.ErrorsInPerSecond
+		stats.Total.ErrorsOutPerSecond += rates.ErrorsOutPerSecond
+	}
+	return stats, nil
+}
+
+// loopbackInterfaces returns the names of loopback interfaces, whose
+// traffic never leaves the host.
+func loopbackInterfaces() map[string]bool {
+	loopback := map[string]bool{}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return loopback
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			loopback[iface.Name] = true
+		}
+	}
+	return loopback
+}
@@ -0,0 +1,9 @@
+//go:build !minimal && noproxy
+
+package main
+
+// httpProxyRequests stands in for the HTTP proxy, which the noproxy build
+// tag leaves out.
+func httpProxyRequests() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,36 @@
+//go:build !minimal
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"slices"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+func init() {
+	registerFeature(feature{
+		name:      "openapi",
+		endpoints: []string{"GET /openapi.json"},
+		routes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/openapi.json", openapiHandler)
+		},
+	})
+}
+
+// openapiHandler serves the spec for the endpoints compiled into this build.
+func openapiHandler(w http.ResponseWriter, r *http.Request) {
+	version := "(devel)"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+	}
+	spec := api.Spec(version, func(e api.Endpoint) bool {
+		return slices.Contains(endpoints, e.Method+" "+e.Path)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(spec)
+}
@@ -0,0 +1,357 @@
+//go:build !minimal && !nootlp
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+var (
+	otlpEndpoint = flag.String("otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "URL of an OpenTelemetry collector samples are exported to over OTLP, e.g. http://localhost:4318 (env OTEL_EXPORTER_OTLP_ENDPOINT)")
+	otlpProtocol = flag.String("otlp-protocol", envDefault("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf"), `OTLP transport: "http/protobuf" or "grpc" (env OTEL_EXPORTER_OTLP_PROTOCOL)`)
+	otlpHeaders  = flag.String("otlp-headers", os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), "Comma-separated key=value headers sent with every export, e.g. an API key (env OTEL_EXPORTER_OTLP_HEADERS)")
+	otlpResource = flag.String("otlp-resource-attributes", os.Getenv("OTEL_RESOURCE_ATTRIBUTES"), "Comma-separated key=value resource attributes, e.g. cloud.region=us-east-1 (env OTEL_RESOURCE_ATTRIBUTES)")
+	otlpInterval = flag.Duration("otlp-interval", 15*time.Second, "How often a sample is exported to -otlp-endpoint")
+)
+
+// otlpScope is the instrumentation scope metrics are exported under.
+const otlpScope = "github.com/abhi-arya1/autoscaled/monitor"
+
+func init() {
+	registerFeature(feature{
+		name:  "otlp",
+		start: startOTLP,
+	})
+}
+
+func envDefault(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func startOTLP() error {
+	if *otlpEndpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(*otlpEndpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("-otlp-endpoint must be an http or https URL")
+	}
+	if *otlpInterval <= 0 {
+		return fmt.Errorf("-otlp-interval must be positive")
+	}
+	headers, err := parseKeyValues(*otlpHeaders)
+	if err != nil {
+		return fmt.Errorf("-otlp-headers: %v", err)
+	}
+	resource, err := otlpResourceAttributes(*otlpResource)
+	if err != nil {
+		return fmt.Errorf("-otlp-resource-attributes: %v", err)
+	}
+
+	e := &otlpExporter{
+		headers:  headers,
+		resource: resource,
+		interval: *otlpInterval,
+	}
+	base := strings.TrimSuffix(u.String(), "/")
+	switch *otlpProtocol {
+	case "http/protobuf":
+		e.url = base + "/v1/metrics"
+		e.client = &http.Client{Timeout: e.interval}
+	case "grpc":
+		e.url = base + "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+		e.grpc = true
+		transport := &http2.Transport{}
+		if u.Scheme == "http" {
+			// Collectors usually accept plaintext gRPC on a local port, which
+			// is HTTP/2 without TLS
+			transport.AllowHTTP = true
+			transport.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			}
+		}
+		e.client = &http.Client{Timeout: e.interval, Transport: transport}
+	default:
+		return fmt.Errorf(`-otlp-protocol must be "http/protobuf" or "grpc"`)
+	}
+	go e.run(context.Background())
+	return nil
+}
+
+// parseKeyValues parses a comma-separated list of key=value pairs, with
+// values percent-decoded as OTEL_EXPORTER_OTLP_HEADERS and
+// OTEL_RESOURCE_ATTRIBUTES specify.
+func parseKeyValues(s string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", pair, err)
+		}
+		pairs[key] = decoded
+	}
+	return pairs, nil
+}
+
+// otlpResourceAttributes describes the instance to the collector. The
+// service, namespace, instance ID, and region come from -service, -namespace,
+// and the detected environment, and explicit attributes override them.
+func otlpResourceAttributes(explicit string) (map[string]string, error) {
+	attrs := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+	set("service.name", envDefault("OTEL_SERVICE_NAME", exportedLabels[serviceLabel]))
+	set("service.namespace", exportedLabels[namespaceLabel])
+	hostname, _ := os.Hostname()
+	set("host.name", hostname)
+	set("service.instance.id", hostname)
+	if environment != nil {
+		set("service.instance.id", environment.Metadata["pod"])
+		set("cloud.region", environment.Metadata["region"])
+		set("k8s.pod.name", environment.Metadata["pod"])
+		set("k8s.node.name", environment.Metadata["node"])
+		set("os.type", environment.OS)
+		set("host.arch", environment.Arch)
+	}
+
+	overrides, err := parseKeyValues(explicit)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range overrides {
+		attrs[key] = value
+	}
+	return attrs, nil
+}
+
+// otlpExporter sends samples to an OpenTelemetry collector as OTLP metrics,
+// with the same metrics /metrics serves.
+type otlpExporter struct {
+	url      string
+	grpc     bool
+	headers  map[string]string
+	resource map[string]string
+	interval time.Duration
+	client   *http.Client
+	// failing is set while exports fail, so errors are logged once per outage
+	failing bool
+}
+
+func (e *otlpExporter) run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// A failed export isn't retried: counters are cumulative, so the
+		// next one catches up
+		err := e.export(ctx, metricFamilies(sample(ctx)), time.Now())
+		switch {
+		case err != nil && !e.failing:
+			fmt.Fprintf(os.Stderr, "[monitor] OTLP export to %s failed: %v\n", e.url, err)
+			e.failing = true
+		case err == nil && e.failing:
+			fmt.Fprintf(os.Stderr, "[monitor] OTLP export to %s recovered\n", e.url)
+			e.failing = false
+		}
+	}
+}
+
+func (e *otlpExporter) export(ctx context.Context, families []metricFamily, now time.Time) error {
+	body := encodeOTLPMetrics(e.resource, families, startedAt, now)
+	contentType := "application/x-protobuf"
+	if e.grpc {
+		// A gRPC message is prefixed with an uncompressed flag and its length
+		framed := make([]byte, 5, 5+len(body))
+		binary.BigEndian.PutUint32(framed[1:], uint32(len(body)))
+		body, contentType = append(framed, body...), "application/grpc"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.grpc {
+		req.Header.Set("TE", "trailers")
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Trailers are only available once the body has been read
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if e.grpc {
+		status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+		if status == "" {
+			// A response without a body carries its status in the headers
+			status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+		}
+		if status != "0" {
+			msg, _ := url.PathUnescape(message)
+			return fmt.Errorf("gRPC status %s: %s", status, msg)
+		}
+	}
+	return nil
+}
+
+// encodeOTLPMetrics encodes an ExportMetricsServiceRequest with one
+// resource. Gauges are exported as gauges and counters as cumulative sums
+// since start, named as on /metrics but without the _total suffix, which
+// Prometheus exporters add back.
+func encodeOTLPMetrics(resource map[string]string, families []metricFamily, start, now time.Time) []byte {
+	var res protoBuffer
+	keys := make([]string, 0, len(resource))
+	for key := range resource {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		res.message(1, otlpKeyValue(key, resource[key]))
+	}
+
+	var scope protoBuffer
+	var scopeName protoBuffer
+	scopeName.string(1, otlpScope)
+	scope.message(1, scopeName)
+
+	for _, f := range families {
+		var points protoBuffer
+		for _, s := range f.samples {
+			var point protoBuffer
+			for i := 0; i+1 < len(s.labels); i += 2 {
+				point.message(7, otlpKeyValue(s.labels[i], s.labels[i+1]))
+			}
+			if f.typ == "counter" {
+				point.fixed64(2, uint64(start.UnixNano()))
+			}
+			point.fixed64(3, uint64(now.UnixNano()))
+			point.fixed64(4, math.Float64bits(s.value))
+			points.message(1, point)
+		}
+
+		var metric protoBuffer
+		if f.typ == "counter" {
+			metric.string(1, strings.TrimSuffix(f.name, "_total"))
+			metric.string(2, f.help)
+			metric.string(3, otlpUnit(f.name))
+			// Cumulative, monotonic
+			points.varint(2, 2)
+			points.varint(3, 1)
+			metric.message(7, points)
+		} else {
+			metric.string(1, f.name)
+			metric.string(2, f.help)
+			metric.string(3, otlpUnit(f.name))
+			metric.message(5, points)
+		}
+		scope.message(2, metric)
+	}
+
+	var rm protoBuffer
+	rm.message(1, res)
+	rm.message(2, scope)
+	var req protoBuffer
+	req.message(1, rm)
+	return req
+}
+
+// otlpUnit derives a UCUM unit from the Prometheus naming convention.
+func otlpUnit(name string) string {
+	name = strings.TrimSuffix(name, "_total")
+	switch {
+	case strings.HasSuffix(name, "_seconds"):
+		return "s"
+	case strings.HasSuffix(name, "_bytes"):
+		return "By"
+	case strings.HasSuffix(name, "_percent"):
+		return "%"
+	}
+	return ""
+}
+
+func otlpKeyValue(key, value string) protoBuffer {
+	var anyValue protoBuffer
+	anyValue.string(1, value)
+	var kv protoBuffer
+	kv.string(1, key)
+	kv.message(2, anyValue)
+	return kv
+}
+
+// protoBuffer appends fields in the protobuf wire format, which is all OTLP
+// needs without pulling in generated code.
+type protoBuffer []byte
+
+func (b *protoBuffer) tag(field int, wireType byte) {
+	*b = binary.AppendUvarint(*b, uint64(field)<<3|uint64(wireType))
+}
+
+func (b *protoBuffer) varint(field int, v uint64) {
+	b.tag(field, 0)
+	*b = binary.AppendUvarint(*b, v)
+}
+
+func (b *protoBuffer) fixed64(field int, v uint64) {
+	b.tag(field, 1)
+	*b = binary.LittleEndian.AppendUint64(*b, v)
+}
+
+func (b *protoBuffer) bytes(field int, v []byte) {
+	b.tag(field, 2)
+	*b = binary.AppendUvarint(*b, uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+// string omits empty strings, which decode as the default anyway.
+func (b *protoBuffer) string(field int, s string) {
+	if s != "" {
+		b.bytes(field, []byte(s))
+	}
+}
+
+func (b *protoBuffer) message(field int, m protoBuffer) {
+	b.bytes(field, m)
+}
@@ -0,0 +1,301 @@
+// Package policytest runs scaling rules against synthetic samples, so rule
+// changes can be tested like code. Each case feeds a timeline of samples to a
+// scaler.Engine and checks every decision it makes, including rules that
+// fire again while their condition keeps holding. The engine has no
+// cooldown of its own; a case can set Cooldown to drop the decisions an
+// orchestrator with cooldowns would ignore:
+//
+//	func TestRules(t *testing.T) {
+//		hot := policytest.Sample(map[string]float64{"cpu": 90})
+//		policytest.Run(t, []policytest.Case{{
+//			Name:  "sustained load scales up every 2m",
+//			Rules: []string{"cpu > 80% for 2m -> scale up"},
+//			Steps: policytest.Hold(0, 5*time.Minute, 15*time.Second, hot),
+//			Want: []policytest.Want{
+//				{At: 2 * time.Minute, Action: scaler.ScaleUp},
+//				{At: 4 * time.Minute, Action: scaler.ScaleUp},
+//			},
+//		}})
+//	}
+package policytest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/abhi-arya1/autoscaled/monitor/scaler"
+)
+
+// Start is the time the offsets in steps and wants count from.
+var Start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DefaultTarget is the target of steps and wants that don't name one.
+const DefaultTarget = "instance"
+
+// Step is one sample observed from a target.
+type Step struct {
+	// At is the offset from Start the sample is observed at
+	At     time.Duration
+	Target string
+	// Sample is nil when the target couldn't be reached, which resets its
+	// rules as it does in the scaler
+	Sample *api.MonitorResponse
+}
+
+// Want is a decision a case expects.
+type Want struct {
+	At     time.Duration
+	Target string
+	Action scaler.Action
+	// Rule, when set, is the rule that must have fired, in any form
+	// scaler.ParseRule accepts
+	Rule string
+}
+
+// Case is one table entry for Run.
+type Case struct {
+	Name  string
+	Rules []string
+	Steps []Step
+	// Cooldown, per action, drops decisions made less than that long after
+	// the last one kept with the same action on the same target, as an
+	// orchestrator with scale-up and scale-down cooldowns would
+	Cooldown map[scaler.Action]time.Duration
+	// Want lists every decision expected, in the order they are made; any
+	// other decision fails the case
+	Want []Want
+}
+
+// Run runs each case as a subtest.
+func Run(t *testing.T, cases []Case) {
+	t.Helper()
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			Check(t, ApplyCooldown(Decisions(t, c.Rules, c.Steps), c.Cooldown), c.Want)
+		})
+	}
+}
+
+// Decisions feeds steps to a new engine built from rules, in order of their
+// offsets, and returns every decision it makes.
+func Decisions(t testing.TB, rules []string, steps []Step) []scaler.Decision {
+	t.Helper()
+	parsed := make([]scaler.Rule, len(rules))
+	for i, s := range rules {
+		rule, err := scaler.ParseRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed[i] = rule
+	}
+
+	steps = append([]Step(nil), steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].At < steps[j].At })
+
+	engine := scaler.NewEngine(parsed)
+	var decisions []scaler.Decision
+	for _, step := range steps {
+		target := targetOrDefault(step.Target)
+		if step.Sample == nil {
+			engine.Forget(target)
+			continue
+		}
+		decisions = append(decisions, engine.Observe(target, step.Sample, Start.Add(step.At))...)
+	}
+	return decisions
+}
+
+// ApplyCooldown returns the decisions that cooldown doesn't drop: each
+// action's cooldown runs from the last decision kept for that action on the
+// same target. A rule that keeps holding through a cooldown fires again
+// after its duration, so the next decision kept may come well after the
+// cooldown ends.
+func ApplyCooldown(decisions []scaler.Decision, cooldown map[scaler.Action]time.Duration) []scaler.Decision {
+	if len(cooldown) == 0 {
+		return decisions
+	}
+	type key struct {
+		target string
+		action scaler.Action
+	}
+	last := map[key]time.Time{}
+	var kept []scaler.Decision
+	for _, d := range decisions {
+		k := key{d.Target, d.Action}
+		if at, ok := last[k]; ok && d.At.Sub(at) < cooldown[d.Action] {
+			continue
+		}
+		last[k] = d.At
+		kept = append(kept, d)
+	}
+	return kept
+}
+
+// Check fails t unless got is exactly want.
+func Check(t testing.TB, got []scaler.Decision, want []Want) {
+	t.Helper()
+	ok := len(got) == len(want)
+	for i := 0; ok && i < len(got); i++ {
+		match, err := matches(got[i], want[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok = match
+	}
+	if ok {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("decisions differ\ngot:\n")
+	for _, d := range got {
+		fmt.Fprintf(&b, "\t%s %s %s (%s, %s = %g)\n", d.At.Sub(Start), d.Target, d.Action, d.Rule, d.Metric, d.Value)
+	}
+	b.WriteString("want:\n")
+	for _, w := range want {
+		fmt.Fprintf(&b, "\t%s %s %s", w.At, targetOrDefault(w.Target), w.Action)
+		if w.Rule != "" {
+			fmt.Fprintf(&b, " (%s)", w.Rule)
+		}
+		b.WriteByte('\n')
+	}
+	t.Error(b.String())
+}
+
+func matches(d scaler.Decision, w Want) (bool, error) {
+	if d.At.Sub(Start) != w.At || d.Target != targetOrDefault(w.Target) || d.Action != w.Action {
+		return false, nil
+	}
+	if w.Rule == "" {
+		return true, nil
+	}
+	rule, err := scaler.ParseRule(w.Rule)
+	if err != nil {
+		return false, err
+	}
+	return d.Rule == rule.String(), nil
+}
+
+func targetOrDefault(target string) string {
+	if target == "" {
+		return DefaultTarget
+	}
+	return target
+}
+
+// Hold repeats sample every interval from from through to, for a load that
+// holds steady.
+func Hold(from, to, every time.Duration, sample *api.MonitorResponse) []Step {
+	if every <= 0 {
+		panic("policytest: Hold needs a positive interval")
+	}
+	var steps []Step
+	for at := from; at <= to; at += every {
+		steps = append(steps, Step{At: at, Sample: sample})
+	}
+	return steps
+}
+
+// Unreachable marks the target unreachable at each offset.
+func Unreachable(at ...time.Duration) []Step {
+	steps := make([]Step, len(at))
+	for i, offset := range at {
+		steps[i] = Step{At: offset}
+	}
+	return steps
+}
+
+// OnTarget assigns steps to target, for cases with several instances.
+func OnTarget(target string, steps []Step) []Step {
+	out := make([]Step, len(steps))
+	for i, step := range steps {
+		step.Target = target
+		out[i] = step
+	}
+	return out
+}
+
+// Sample builds a sample in which each named metric was measured with the
+// given value. Names are the metrics rules are written against; metrics
+// that aren't named weren't measured, so rules on them never hold. Other
+// fields, such as Labels, can be set on the result.
+func Sample(values map[string]float64) *api.MonitorResponse {
+	r := &api.MonitorResponse{Status: map[string]string{}}
+	measured := func(collector string) { r.Status[collector] = api.StatusOK }
+	httpProxy := func() *api.HTTPProxyStats {
+		measured("http_proxy")
+		if r.Proxy == nil {
+			r.Proxy = &api.ProxyStats{HTTP: &api.HTTPProxyStats{}}
+		}
+		return r.Proxy.HTTP
+	}
+	latency := func() *api.LatencyPercentiles {
+		h := httpProxy()
+		if h.Latency == nil {
+			h.Latency = &api.LatencyPercentiles{}
+		}
+		return h.Latency
+	}
+
+	for name, v := range values {
+		v := v
+		switch name {
+		case "cpu":
+			r.CPUUsage = v
+			measured("cpu")
+		case "memory":
+			r.MemoryUsage = v
+			measured("memory")
+		case "disk":
+			r.DiskUsage = v
+			measured("disk")
+		case "pressure":
+			r.PressureScore = &v
+		case "load":
+			// Per core, as rules read it
+			r.LoadAverage = &api.LoadAverage{Load1: v, Cores: 1}
+			measured("load")
+		case "steal":
+			r.CPUSteal = &v
+			measured("steal")
+		case "process_cpu":
+			r.Process = &api.ProcessStats{CPUPercent: v}
+			measured("process")
+		case "rps":
+			httpProxy().RequestsPerSecond = v
+		case "concurrency":
+			httpProxy().Concurrency = &v
+		case "in_flight":
+			httpProxy().InFlight = int64(v)
+		case "error_rate":
+			httpProxy().ErrorPercent = &v
+		case "p50_latency":
+			latency().P50Seconds = v
+		case "p95_latency":
+			latency().P95Seconds = v
+		case "p99_latency":
+			latency().P99Seconds = v
+		default:
+			panic(fmt.Sprintf("policytest: unknown metric %q", name))
+		}
+	}
+	return r
+}
+
+// Healthy returns a copy of sample whose probes all pass, or all fail, for
+// rules gated with "while healthy".
+func Healthy(sample *api.MonitorResponse, healthy bool) *api.MonitorResponse {
+	r := *sample
+	r.Status = make(map[string]string, len(sample.Status)+1)
+	for k, v := range sample.Status {
+		r.Status[k] = v
+	}
+	r.Status["probes"] = api.StatusOK
+	r.Probes = &api.ProbesStatus{Healthy: healthy}
+	return &r
+}
@@ -0,0 +1,66 @@
+package policytest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/policytest"
+	"github.com/abhi-arya1/autoscaled/monitor/scaler"
+)
+
+func TestRun(t *testing.T) {
+	hot := policytest.Sample(map[string]float64{"cpu": 90})
+	idle := policytest.Sample(map[string]float64{"cpu": 5})
+
+	policytest.Run(t, []policytest.Case{{
+		Name:  "sustained load scales up every 2m",
+		Rules: []string{"cpu > 80% for 2m -> scale up"},
+		Steps: policytest.Hold(0, 5*time.Minute, 15*time.Second, hot),
+		Want: []policytest.Want{
+			{At: 2 * time.Minute, Action: scaler.ScaleUp},
+			{At: 4 * time.Minute, Action: scaler.ScaleUp},
+		},
+	}, {
+		Name:  "an unhealthy idle instance isn't removed",
+		Rules: []string{"cpu < 20% for 10m while healthy -> scale down"},
+		Steps: policytest.Hold(0, time.Hour, time.Minute, policytest.Healthy(idle, false)),
+	}, {
+		Name:  "an unreachable target starts over",
+		Rules: []string{"cpu > 80% for 2m -> scale up"},
+		Steps: append(policytest.Hold(0, 4*time.Minute, time.Minute, hot),
+			policytest.Unreachable(90*time.Second)...),
+		Want: []policytest.Want{
+			{At: 4 * time.Minute, Action: scaler.ScaleUp, Rule: "cpu > 80% for 2m -> scale up"},
+		},
+	}, {
+		Name:     "a cooldown drops decisions made during it",
+		Rules:    []string{"cpu > 80% for 2m -> scale up"},
+		Steps:    policytest.Hold(0, 10*time.Minute, 15*time.Second, hot),
+		Cooldown: map[scaler.Action]time.Duration{scaler.ScaleUp: 5 * time.Minute},
+		Want: []policytest.Want{
+			{At: 2 * time.Minute, Action: scaler.ScaleUp},
+			{At: 8 * time.Minute, Action: scaler.ScaleUp},
+		},
+	}, {
+		Name:  "a cooldown only holds back its own action",
+		Rules: []string{"cpu > 80% for 2m -> scale up", "cpu > 80% for 3m -> scale down"},
+		Steps: policytest.Hold(0, 4*time.Minute, time.Minute, hot),
+		Cooldown: map[scaler.Action]time.Duration{
+			scaler.ScaleUp: 10 * time.Minute,
+		},
+		Want: []policytest.Want{
+			{At: 2 * time.Minute, Action: scaler.ScaleUp},
+			{At: 3 * time.Minute, Action: scaler.ScaleDown},
+		},
+	}, {
+		Name:  "each target has its own cooldown",
+		Rules: []string{"cpu > 80% for 1m -> scale up"},
+		Steps: append(policytest.OnTarget("a", policytest.Hold(0, 2*time.Minute, time.Minute, hot)),
+			policytest.OnTarget("b", policytest.Hold(30*time.Second, 2*time.Minute, time.Minute, hot))...),
+		Cooldown: map[scaler.Action]time.Duration{scaler.ScaleUp: 5 * time.Minute},
+		Want: []policytest.Want{
+			{At: time.Minute, Target: "a", Action: scaler.ScaleUp},
+			{At: 90 * time.Second, Target: "b", Action: scaler.ScaleUp},
+		},
+	}})
+}
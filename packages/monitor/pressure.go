@@ -0,0 +1,34 @@
+package main
+
+// pressureScore summarises how loaded the instance is on a 0-100 scale, so
+// routers can send new sessions to the least-pressured instances and
+// scale-down can prefer evicting the idlest ones. An instance is limited by
+// its scarcest resource, so the score is the highest utilisation among the
+// metrics that were measured. It returns nil if none were.
+func pressureScore(r MonitorResponse) *float64 {
+	var score float64
+	measured := false
+	use := func(collector string, value float64) {
+		if r.Status[collector] != statusOK {
+			return
+		}
+		measured = true
+		score = max(score, value)
+	}
+
+	use("cpu", r.CPUUsage)
+	use("memory", r.MemoryUsage)
+	use("disk", r.DiskUsage)
+	if r.InodeUsage != nil {
+		use("disk", *r.InodeUsage)
+	}
+	if r.Conntrack != nil {
+		use("conntrack", r.Conntrack.UsagePercent)
+	}
+
+	if !measured {
+		return nil
+	}
+	score = min(score, 100)
+	return &score
+}
@@ -0,0 +1,253 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+type (
+	ProbesStatus = api.ProbesStatus
+	ProbeStatus  = api.ProbeStatus
+)
+
+// probes are the -probe checks, in the order they were given.
+var probes []*probe
+
+func init() {
+	flag.Func("probe", `Health check such as "api=http://localhost:8080/healthz,interval=10s,timeout=1s,success=1,failure=3"; the target may also be tcp://host:port or exec:command (repeatable)`, func(s string) error {
+		p, err := parseProbe(s)
+		if err != nil {
+			return err
+		}
+		for _, other := range probes {
+			if other.name == p.name {
+				return fmt.Errorf("duplicate probe %q", p.name)
+			}
+		}
+		probes = append(probes, p)
+		return nil
+	})
+	registerFeature(feature{
+		name: "probes",
+		collectors: func() []collector {
+			if len(probes) == 0 {
+				return nil
+			}
+			return []collector{probesCollector()}
+		},
+		start: func() error {
+			for _, p := range probes {
+				go p.run(context.Background())
+			}
+			return nil
+		},
+	})
+}
+
+// probe checks one dependency of the instance on its own interval. Like a
+// Kubernetes probe, it turns healthy after success consecutive passes and
+// unhealthy after failure consecutive failures, and starts out unhealthy.
+type probe struct {
+	name     string
+	kind     string
+	target   string
+	interval time.Duration
+	timeout  time.Duration
+	success  int
+	failure  int
+	check    func(ctx context.Context) error
+
+	mu     sync.Mutex
+	status ProbeStatus
+}
+
+// parseProbe parses "<name>=<target>[,<option>=<value>...]", where the
+// target is an http:// or https:// URL, tcp://host:port, or exec: followed
+// by a command, and the options are interval, timeout, success, and failure.
+func parseProbe(s string) (*probe, error) {
+	options := strings.Split(s, ",")
+	name, target, ok := strings.Cut(options[0], "=")
+	name, target = strings.TrimSpace(name), strings.TrimSpace(target)
+	if !ok || name == "" || target == "" {
+		return nil, fmt.Errorf("probe %q: expected \"<name>=<target>\"", s)
+	}
+
+	p := &probe{name: name, target: target, interval: 10 * time.Second, timeout: time.Second, success: 1, failure: 3}
+	switch {
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		if _, err := url.Parse(target); err != nil {
+			return nil, fmt.Errorf("probe %q: %w", name, err)
+		}
+		p.kind, p.check = "http", p.checkHTTP
+	case strings.HasPrefix(target, "tcp://"):
+		if _, _, err := net.SplitHostPort(strings.TrimPrefix(target, "tcp://")); err != nil {
+			return nil, fmt.Errorf("probe %q: %w", name, err)
+		}
+		p.kind, p.check = "tcp", p.checkTCP
+	case strings.HasPrefix(target, "exec:"):
+		if len(strings.Fields(strings.TrimPrefix(target, "exec:"))) == 0 {
+			return nil, fmt.Errorf("probe %q: missing command", name)
+		}
+		p.kind, p.check = "exec", p.checkExec
+	default:
+		return nil, fmt.Errorf("probe %q: target must be an http(s):// URL, tcp://host:port, or exec:command", name)
+	}
+
+	for _, option := range options[1:] {
+		key, value, _ := strings.Cut(option, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		var err error
+		switch key {
+		case "interval":
+			p.interval, err = time.ParseDuration(value)
+			if err == nil && p.interval <= 0 {
+				err = errors.New("must be positive")
+			}
+		case "timeout":
+			p.timeout, err = time.ParseDuration(value)
+			if err == nil && p.timeout <= 0 {
+				err = errors.New("must be positive")
+			}
+		case "success":
+			p.success, err = strconv.Atoi(value)
+			if err == nil && p.success < 1 {
+				err = errors.New("must be at least 1")
+			}
+		case "failure":
+			p.failure, err = strconv.Atoi(value)
+			if err == nil && p.failure < 1 {
+				err = errors.New("must be at least 1")
+			}
+		default:
+			return nil, fmt.Errorf("probe %q: unknown option %q", name, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("probe %q: invalid %s %q: %v", name, key, value, err)
+		}
+	}
+
+	p.status = ProbeStatus{Kind: p.kind, Target: p.target, Error: "not checked yet"}
+	return p, nil
+}
+
+func (p *probe) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		err := p.check(checkCtx)
+		cancel()
+		p.record(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *probe) record(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := &p.status
+	s.CheckedAt = time.Now().UTC().Format(time.RFC3339)
+	if err != nil {
+		s.Error = err.Error()
+		s.ConsecutiveSuccesses = 0
+		s.ConsecutiveFailures++
+		if s.ConsecutiveFailures >= p.failure {
+			s.Healthy = false
+		}
+		return
+	}
+	s.Error = ""
+	s.ConsecutiveFailures = 0
+	s.ConsecutiveSuccesses++
+	if s.ConsecutiveSuccesses >= p.success {
+		s.Healthy = true
+	}
+}
+
+func (p *probe) snapshot() ProbeStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// checkHTTP passes on any 2xx or 3xx response, as Kubernetes does.
+func (p *probe) checkHTTP(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 399 {
+		return fmt.Errorf("returned %s", resp.Status)
+	}
+	return nil
+}
+
+// checkTCP passes once a connection is accepted.
+func (p *probe) checkTCP(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", strings.TrimPrefix(p.target, "tcp://"))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkExec passes when the command exits with code 0. Its output is kept as
+// the error otherwise, since that is usually the reason.
+func (p *probe) checkExec(ctx context.Context) error {
+	args := strings.Fields(strings.TrimPrefix(p.target, "exec:"))
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if msg := strings.TrimSpace(string(out)); msg != "" {
+		const maxOutput = 256
+		if len(msg) > maxOutput {
+			msg = msg[:maxOutput]
+		}
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
+
+// probesCollector reports every probe's latest state. Probes run on their
+// own intervals, so reading them never blocks.
+func probesCollector() collector {
+	return collector{
+		name:    "probes",
+		timeout: time.Second,
+		collect: func(ctx context.Context) (func(*MonitorResponse), error) {
+			status := &ProbesStatus{Healthy: true, Probes: make(map[string]ProbeStatus, len(probes))}
+			for _, p := range probes {
+				s := p.snapshot()
+				status.Probes[p.name] = s
+				status.Healthy = status.Healthy && s.Healthy
+			}
+			return func(r *MonitorResponse) { r.Probes = status }, nil
+		},
+	}
+}
@@ -0,0 +1,138 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+type (
+	ProcessInfo       = api.ProcessInfo
+	ProcessesResponse = api.ProcessesResponse
+)
+
+const defaultTopProcesses = 10
+
+var processCache *sampleCache[[]ProcessInfo]
+
+func init() {
+	registerFeature(feature{
+		name:      "processes",
+		endpoints: []string{"GET /processesz"},
+		routes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/processesz", processesHandler)
+		},
+		start: func() error {
+			// Listing every process is far more expensive than the host metrics
+			processCache = newSampleCache("processes", *cacheTTL, 5*time.Second, sampleProcesses)
+			return nil
+		},
+	})
+}
+
+// sampleProcesses measures every visible process. CPU usage is computed from
+// the change in CPU time across a short window, since gopsutil's per-process
+// percentage is otherwise an average over the process's whole lifetime.
+func sampleProcesses(ctx context.Context) ([]ProcessInfo, error) {
+	const window = 200 * time.Millisecond
+
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	before := make(map[int32]float64, len(procs))
+	for _, p := range procs {
+		if t, err := p.TimesWithContext(ctx); err == nil {
+			before[p.Pid] = cpuSeconds(t)
+		}
+	}
+
+	start := time.Now()
+	select {
+	case <-time.After(window):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	elapsed := time.Since(start).Seconds()
+
+	// Read once here rather than per process, as gopsutil's MemoryPercent
+	// would
+	var total uint64
+	if v, err := mem.VirtualMemoryWithContext(ctx); err == nil {
+		total = v.Total
+	}
+
+	infos := make([]ProcessInfo, 0, len(procs))
+	for _, p := range procs {
+		info := ProcessInfo{PID: p.Pid}
+		// Processes can exit between listing and inspection; skip them
+		name, err := p.NameWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		info.Name = name
+
+		if t, err := p.TimesWithContext(ctx); err == nil {
+			if prev, ok := before[p.Pid]; ok {
+				info.CPUPercent = (cpuSeconds(t) - prev) / elapsed * 100
+			}
+		}
+		if m, err := p.MemoryInfoWithContext(ctx); err == nil {
+			info.RSSBytes = m.RSS
+			if total > 0 {
+				info.MemoryPercent = float32(float64(m.RSS) / float64(total) * 100)
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func topProcesses(infos []ProcessInfo, n int, less func(a, b ProcessInfo) bool) []ProcessInfo {
+	sorted := make([]ProcessInfo, len(infos))
+	copy(sorted, infos)
+	sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+func processesHandler(w http.ResponseWriter, r *http.Request) {
+	n := defaultTopProcesses
+	if value := r.URL.Query().Get("n"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			api.WriteError(w, http.StatusBadRequest, api.CodeInvalidArgument, "n must be a positive integer")
+			return
+		}
+		n = parsed
+	}
+
+	infos, err := processCache.Get(r.Context())
+	if err != nil {
+		api.WriteError(w, http.StatusServiceUnavailable, api.CodeUnavailable, "failed to list processes: "+err.Error())
+		return
+	}
+
+	resp := ProcessesResponse{
+		ByCPU: topProcesses(infos, n, func(a, b ProcessInfo) bool {
+			return a.CPUPercent > b.CPUPercent
+		}),
+		ByMemory: topProcesses(infos, n, func(a, b ProcessInfo) bool {
+			return a.RSSBytes > b.RSSBytes
+		}),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,393 @@
+//go:build !minimal
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+func init() {
+	registerFeature(feature{
+		name:      "prometheus",
+		endpoints: []string{"GET /metrics"},
+		routes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/metrics", prometheusHandler)
+		},
+	})
+}
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricFamily is a metric and its samples, as /metrics and the OTLP
+// exporter both report them.
+type metricFamily struct {
+	name, typ, help string
+	samples         []metricSample
+}
+
+type metricSample struct {
+	value float64
+	// labels alternate between names and values
+	labels []string
+}
+
+// metricWriter collects metric families in the order they're written.
+type metricWriter struct {
+	families []metricFamily
+}
+
+// metric starts a metric; its samples follow.
+func (p *metricWriter) metric(name, typ, help string) {
+	p.families = append(p.families, metricFamily{name: name, typ: typ, help: help})
+}
+
+// sample adds one sample to the metric started last; labels alternate
+// between names and values.
+func (p *metricWriter) sample(value float64, labels ...string) {
+	f := &p.families[len(p.families)-1]
+	f.samples = append(f.samples, metricSample{value: value, labels: labels})
+}
+
+func (p *metricWriter) gauge(name, help string, value float64) {
+	p.metric(name, "gauge", help)
+	p.sample(value)
+}
+
+var promLabelValue = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promLabelName maps an arbitrary key (e.g. a Kubernetes label such as
+// app.kubernetes.io/name) to a valid Prometheus label name.
+func promLabelName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricFamilies converts a sample to metrics. Metrics whose collector isn't
+// ok are left out rather than reported as 0, and monitor_collector_up says
+// which ones are missing.
+func metricFamilies(r MonitorResponse) []metricFamily {
+	var p metricWriter
+	measured := func(collector string) bool { return r.Status[collector] == statusOK }
+
+	if measured("cpu") {
+		p.gauge("monitor_cpu_usage_percent", "Host CPU usage on a 0-100 scale.", r.CPUUsage)
+	}
+	if measured("memory") {
+		p.gauge("monitor_memory_usage_percent", "Host memory usage on a 0-100 scale.", r.MemoryUsage)
+	}
+	if measured("disk") {
+		p.gauge("monitor_disk_usage_percent", "Highest filesystem usage on a 0-100 scale.", r.DiskUsage)
+		p.gauge("monitor_disk_sample_age_seconds", "Age of the background disk usage sample.", r.DiskSampleAgeSeconds)
+		if r.InodeUsage != nil {
+			p.gauge("monitor_inode_usage_percent", "Highest filesystem inode usage on a 0-100 scale.", *r.InodeUsage)
+		}
+		if len(r.Disks) > 0 {
+			p.metric("monitor_filesystem_usage_percent", "gauge", "Filesystem usage on a 0-100 scale.")
+			for _, d := range r.Disks {
+				p.sample(d.UsagePercent, "path", d.Path)
+			}
+			if r.InodeUsage != nil {
+				p.metric("monitor_filesystem_inode_usage_percent", "gauge", "Filesystem inode usage on a 0-100 scale.")
+				for _, d := range r.Disks {
+					if d.InodeUsagePercent != nil {
+						p.sample(*d.InodeUsagePercent, "path", d.Path)
+					}
+				}
+			}
+		}
+	}
+	if r.LoadAverage != nil {
+		p.gauge("monitor_load1", "1 minute load average.", r.LoadAverage.Load1)
+		p.gauge("monitor_load5", "5 minute load average.", r.LoadAverage.Load5)
+		p.gauge("monitor_load15", "15 minute load average.", r.LoadAverage.Load15)
+		p.gauge("monitor_cpu_cores", "Logical CPU cores.", float64(r.LoadAverage.Cores))
+	}
+	if r.CPUSteal != nil {
+		p.gauge("monitor_cpu_steal_percent", "CPU time stolen by the hypervisor on a 0-100 scale.", *r.CPUSteal)
+	}
+	if r.ClockOffsetSeconds != nil {
+		p.gauge("monitor_clock_offset_seconds", "How far the local clock is ahead of NTP time.", *r.ClockOffsetSeconds)
+	}
+	if r.EntropyAvailable != nil {
+		p.gauge("monitor_entropy_available_bits", "Kernel entropy available.", float64(*r.EntropyAvailable))
+	}
+	if r.Conntrack != nil {
+		p.gauge("monitor_conntrack_entries", "Connection tracking table entries.", float64(r.Conntrack.Count))
+		p.gauge("monitor_conntrack_entries_limit", "Connection tracking table size.", float64(r.Conntrack.Max))
+	}
+	if r.Network != nil && len(r.Network.Interfaces) > 0 {
+		names := make([]string, 0, len(r.Network.Interfaces))
+		for name := range r.Network.Interfaces {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		networkMetrics := []struct {
+			name, help string
+			value      func(NetworkRates) float64
+		}{
+			{"monitor_network_receive_bytes_per_second", "Bytes received per second.", func(n NetworkRates) float64 { return n.BytesInPerSecond }},
+			{"monitor_network_transmit_bytes_per_second", "Bytes sent per second.", func(n NetworkRates) float64 { return n.BytesOutPerSecond }},
+			{"monitor_network_receive_packets_per_second", "Packets received per second.", func(n NetworkRates) float64 { return n.PacketsInPerSecond }},
+			{"monitor_network_transmit_packets_per_second", "Packets sent per second.", func(n NetworkRates) float64 { return n.PacketsOutPerSecond }},
+			{"monitor_network_receive_errors_per_second", "Receive errors per second.", func(n NetworkRates) float64 { return n.ErrorsInPerSecond }},
+			{"monitor_network_transmit_errors_per_second", "Transmit errors per second.", func(n NetworkRates) float64 { return n.ErrorsOutPerSecond }},
+		}
+		for _, m := range networkMetrics {
+			p.metric(m.name, "gauge", m.help)
+			for _, name := range names {
+				p.sample(m.value(r.Network.Interfaces[name]), "interface", name)
+			}
+		}
+	}
+	if r.DiskIO != nil && len(r.DiskIO.Devices) > 0 {
+		names := make([]string, 0, len(r.DiskIO.Devices))
+		for name := range r.DiskIO.Devices {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		diskIOMetrics := []struct {
+			name, help string
+			value      func(DiskIORates) float64
+		}{
+			{"monitor_disk_reads_per_second", "Completed disk reads per second.", func(d DiskIORates) float64 { return d.ReadsPerSecond }},
+			{"monitor_disk_writes_per_second", "Completed disk writes per second.", func(d DiskIORates) float64 { return d.WritesPerSecond }},
+			{"monitor_disk_read_bytes_per_second", "Bytes read from disk per second.", func(d DiskIORates) float64 { return d.ReadBytesPerSecond }},
+			{"monitor_disk_written_bytes_per_second", "Bytes written to disk per second.", func(d DiskIORates) float64 { return d.WriteBytesPerSecond }},
+			{"monitor_disk_busy_percent", "Share of time the disk had I/O in flight on a 0-100 scale.", func(d DiskIORates) float64 { return d.BusyPercent }},
+		}
+		for _, m := range diskIOMetrics {
+			p.metric(m.name, "gauge", m.help)
+			for _, name := range names {
+				p.sample(m.value(r.DiskIO.Devices[name]), "device", name)
+			}
+		}
+	}
+	if r.ChildRestarts != nil {
+		p.metric("monitor_child_restarts_total", "counter", "Times the child has been restarted.")
+		p.sample(float64(*r.ChildRestarts))
+	}
+	if r.Probes != nil {
+		p.gauge("monitor_probes_healthy", "Whether every -probe health check is passing.", boolValue(r.Probes.Healthy))
+		names := make([]string, 0, len(r.Probes.Probes))
+		for name := range r.Probes.Probes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		p.metric("monitor_probe_healthy", "gauge", "Whether the health check is passing.")
+		for _, name := range names {
+			probe := r.Probes.Probes[name]
+			p.sample(boolValue(probe.Healthy), "probe", name, "kind", probe.Kind)
+		}
+	}
+	if r.Process != nil {
+		p.gauge("monitor_process_cpu_percent", "CPU usage of the child's process tree, where 100 is one core.", r.Process.CPUPercent)
+		p.gauge("monitor_process_resident_memory_bytes", "Resident memory of the child's process tree.", float64(r.Process.RSSBytes))
+		p.gauge("monitor_process_threads", "Threads in the child's process tree.", float64(r.Process.Threads))
+		p.gauge("monitor_process_count", "Processes in the child's process tree.", float64(r.Process.Processes))
+		if r.Process.OpenFDs != nil {
+			p.gauge("monitor_process_open_fds", "Open file descriptors in the child's process tree.", float64(*r.Process.OpenFDs))
+		}
+	}
+	if r.Proxy != nil && r.Proxy.HTTP != nil {
+		h := r.Proxy.HTTP
+		labels := []string{"listen", h.Listen, "upstream", h.Upstream}
+		httpMetrics := []struct {
+			name, typ, help string
+			value           float64
+		}{
+			{"monitor_http_proxy_requests_total", "counter", "Requests received by the HTTP proxy, including rejected ones.", float64(h.TotalRequests)},
+			{"monitor_http_proxy_rejected_requests_total", "counter", "Requests rejected with 503 because of /controlz.", float64(h.RejectedRequests)},
+			{"monitor_http_proxy_errors_total", "counter", "Requests answered with a 5xx status.", float64(h.Errors)},
+			{"monitor_http_proxy_upstream_errors_total", "counter", "Requests the upstream couldn't be reached for.", float64(h.UpstreamErrors)},
+			{"monitor_http_proxy_in_flight_requests", "gauge", "Requests being served right now.", float64(h.InFlight)},
+			{"monitor_http_proxy_requests_per_second", "gauge", "Requests per second over the last interval.", h.RequestsPerSecond},
+			{"monitor_http_proxy_errors_per_second", "gauge", "5xx responses per second over the last interval.", h.ErrorsPerSecond},
+		}
+		for _, m := range httpMetrics {
+			p.metric(m.name, m.typ, m.help)
+			p.sample(m.value, labels...)
+		}
+		if h.Concurrency != nil {
+			p.metric("monitor_http_proxy_concurrency", "gauge", "Average requests in flight over the last interval.")
+			p.sample(*h.Concurrency, labels...)
+		}
+		if h.ErrorPercent != nil {
+			p.metric("monitor_http_proxy_error_percent", "gauge", "Share of requests answered with a 5xx status on a 0-100 scale.")
+			p.sample(*h.ErrorPercent, labels...)
+		}
+		if h.Latency != nil {
+			p.metric("monitor_http_proxy_latency_seconds", "gauge", "Request latency percentiles over the last interval.")
+			p.sample(h.Latency.P50Seconds, append(labels, "quantile", "0.5")...)
+			p.sample(h.Latency.P95Seconds, append(labels, "quantile", "0.95")...)
+			p.sample(h.Latency.P99Seconds, append(labels, "quantile", "0.99")...)
+		}
+	}
+	if r.Proxy != nil && len(r.Proxy.TCP) > 0 {
+		tcpMetrics := []struct {
+			name, typ, help string
+			value           func(api.TCPProxyStats) float64
+		}{
+			{"monitor_tcp_proxy_active_connections", "gauge", "Open connections through the TCP proxy.", func(s api.TCPProxyStats) float64 { return float64(s.ActiveConnections) }},
+			{"monitor_tcp_proxy_connections_total", "counter", "Connections accepted by the TCP proxy.", func(s api.TCPProxyStats) float64 { return float64(s.TotalConnections) }},
+			{"monitor_tcp_proxy_rejected_connections_total", "counter", "Connections closed on accept because of /controlz.", func(s api.TCPProxyStats) float64 { return float64(s.RejectedConnections) }},
+			{"monitor_tcp_proxy_upstream_errors_total", "counter", "Connections the upstream refused or didn't accept in time.", func(s api.TCPProxyStats) float64 { return float64(s.UpstreamErrors) }},
+			{"monitor_tcp_proxy_received_bytes_total", "counter", "Bytes received from clients and sent upstream.", func(s api.TCPProxyStats) float64 { return float64(s.BytesIn) }},
+			{"monitor_tcp_proxy_sent_bytes_total", "counter", "Bytes received from upstream and sent to clients.", func(s api.TCPProxyStats) float64 { return float64(s.BytesOut) }},
+		}
+		for _, m := range tcpMetrics {
+			p.metric(m.name, m.typ, m.help)
+			for _, s := range r.Proxy.TCP {
+				p.sample(m.value(s), "listen", s.Listen, "upstream", s.Upstream)
+			}
+		}
+	}
+	if r.Proxy != nil && len(r.Proxy.UDP) > 0 {
+		udpMetrics := []struct {
+			name, typ, help string
+			value           func(api.UDPProxyStats) float64
+		}{
+			{"monitor_udp_proxy_active_flows", "gauge", "Client flows through the UDP proxy that haven't timed out.", func(s api.UDPProxyStats) float64 { return float64(s.ActiveFlows) }},
+			{"monitor_udp_proxy_flows_total", "counter", "Client flows started by the UDP proxy.", func(s api.UDPProxyStats) float64 { return float64(s.TotalFlows) }},
+			{"monitor_udp_proxy_dropped_packets_total", "counter", "Packets dropped because of /controlz or an unreachable upstream.", func(s api.UDPProxyStats) float64 { return float64(s.DroppedPackets) }},
+			{"monitor_udp_proxy_received_packets_total", "counter", "Packets received from clients and sent upstream.", func(s api.UDPProxyStats) float64 { return float64(s.PacketsIn) }},
+			{"monitor_udp_proxy_sent_packets_total", "counter", "Packets received from upstream and sent to clients.", func(s api.UDPProxyStats) float64 { return float64(s.PacketsOut) }},
+			{"monitor_udp_proxy_received_bytes_total", "counter", "Bytes received from clients and sent upstream.", func(s api.UDPProxyStats) float64 { return float64(s.BytesIn) }},
+			{"monitor_udp_proxy_sent_bytes_total", "counter", "Bytes received from upstream and sent to clients.", func(s api.UDPProxyStats) float64 { return float64(s.BytesOut) }},
+		}
+		for _, m := range udpMetrics {
+			p.metric(m.name, m.typ, m.help)
+			for _, s := range r.Proxy.UDP {
+				p.sample(m.value(s), "listen", s.Listen, "upstream", s.Upstream)
+			}
+		}
+	}
+	if r.PressureScore != nil {
+		p.gauge("monitor_pressure_score", "Highest utilisation among the measured metrics, 0-100.", *r.PressureScore)
+	}
+	p.gauge("monitor_degraded", "Whether any collector failed or a value can't be trusted.", boolValue(r.Degraded))
+	p.gauge("monitor_protected", "Whether the instance has asked not to be evicted.", boolValue(r.Protected))
+	if r.Degradation != nil {
+		p.gauge("monitor_degradation_level", "Steps of the -degrade ladder in effect.", float64(r.Degradation.Level))
+		p.gauge("monitor_degradation_alerting", "Whether a -degrade alert step is in effect.", boolValue(r.Degradation.Alerting))
+	}
+	if r.Cost != nil {
+		p.gauge("monitor_cost_per_hour", "What the instance costs per hour, from -hourly-cost.", r.Cost.HourlyCost)
+		if r.Cost.Utilization != nil {
+			p.gauge("monitor_cost_utilization_percent", "Average pressure over the last -cost-window on a 0-100 scale.", *r.Cost.Utilization)
+			p.gauge("monitor_cost_idle", "Cost of unused capacity over the last -cost-window.", *r.Cost.IdleCost)
+		}
+		if r.Cost.CostPer1kRequests != nil {
+			p.gauge("monitor_cost_per_1k_requests", "Cost per 1000 proxied requests over the last -cost-window.", *r.Cost.CostPer1kRequests)
+		}
+	}
+	if r.Control != nil {
+		p.gauge("monitor_shed_percent", "Percentage of proxied requests rejected with 503.", r.Control.ShedPercent)
+		p.gauge("monitor_paused", "Whether every proxied request is rejected with 503.", boolValue(r.Control.Paused))
+	}
+
+	collectorNames := make([]string, 0, len(r.Status))
+	for name := range r.Status {
+		collectorNames = append(collectorNames, name)
+	}
+	sort.Strings(collectorNames)
+	p.metric("monitor_collector_up", "gauge", "Whether the collector produced a value for the last sample.")
+	for _, name := range collectorNames {
+		p.sample(boolValue(measured(name)), "collector", name)
+	}
+	if len(r.CollectorTimings) > 0 {
+		p.metric("monitor_collector_duration_seconds", "gauge", "How long the collector's last call took.")
+		for _, name := range collectorNames {
+			if t, ok := r.CollectorTimings[name]; ok {
+				p.sample(t.DurationSeconds, "collector", name)
+			}
+		}
+		p.metric("monitor_collector_interval_seconds", "gauge", "How often the collector runs, including any slowdown for slow calls.")
+		for _, name := range collectorNames {
+			if t, ok := r.CollectorTimings[name]; ok {
+				p.sample(t.IntervalSeconds, "collector", name)
+			}
+		}
+	}
+
+	// Labels and the environment go on an info metric rather than every
+	// series, so they can be joined in where needed
+	var info []string
+	if r.Environment != nil {
+		info = append(info, "runtime", r.Environment.Runtime, "os", r.Environment.OS, "arch", r.Environment.Arch)
+	}
+	labelKeys := make([]string, 0, len(r.Labels))
+	for key := range r.Labels {
+		labelKeys = append(labelKeys, key)
+	}
+	sort.Strings(labelKeys)
+	seen := map[string]bool{"runtime": true, "os": true, "arch": true}
+	for _, key := range labelKeys {
+		name := promLabelName(key)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		info = append(info, name, r.Labels[key])
+	}
+	p.metric("monitor_info", "gauge", "Labels and environment of this instance.")
+	p.sample(1, info...)
+
+	return p.families
+}
+
+// renderPrometheus converts a sample to the text exposition format.
+func renderPrometheus(r MonitorResponse) []byte {
+	var buf bytes.Buffer
+	for _, f := range metricFamilies(r) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+		for _, s := range f.samples {
+			buf.WriteString(f.name)
+			if len(s.labels) > 0 {
+				buf.WriteByte('{')
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					if i > 0 {
+						buf.WriteByte(',')
+					}
+					fmt.Fprintf(&buf, "%s=\"%s\"", s.labels[i], promLabelValue.Replace(s.labels[i+1]))
+				}
+				buf.WriteByte('}')
+			}
+			buf.WriteByte(' ')
+			buf.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+func prometheusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	w.Write(renderPrometheus(sample(r.Context())))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+type ProtectionStatus = api.ProtectionStatus
+
+// evictionGuard lets the workload mark itself "do not evict", e.g. while it
+// processes a long batch job, so the scaler skips it during scale-down.
+type evictionGuard struct {
+	mu      sync.Mutex
+	active  bool
+	reason  string
+	since   time.Time
+	expires time.Time
+}
+
+var protection = &evictionGuard{}
+
+// protect marks the instance protected until release is called or, if ttl
+// is non-zero, until ttl elapses. A ttl keeps a crashed job from pinning the
+// instance forever.
+func (g *evictionGuard) protect(reason string, ttl time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	if !g.isActive(now) {
+		g.since = now
+	}
+	g.active = true
+	g.reason = reason
+	g.expires = time.Time{}
+	if ttl > 0 {
+		g.expires = now.Add(ttl)
+	}
+}
+
+func (g *evictionGuard) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.active = false
+}
+
+func (g *evictionGuard) protected() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.isActive(time.Now())
+}
+
+func (g *evictionGuard) isActive(now time.Time) bool {
+	return g.active && (g.expires.IsZero() || now.Before(g.expires))
+}
+
+func (g *evictionGuard) status() ProtectionStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.isActive(time.Now()) {
+		return ProtectionStatus{}
+	}
+	s := ProtectionStatus{
+		Protected: true,
+		Reason:    g.reason,
+		Since:     g.since.UTC().Format(time.RFC3339),
+	}
+	if !g.expires.IsZero() {
+		s.ExpiresAt = g.expires.UTC().Format(time.RFC3339)
+	}
+	return s
+}
+
+func protectHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		var ttl time.Duration
+		if v := r.URL.Query().Get("ttl"); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil || parsed < 0 {
+				api.WriteError(w, http.StatusBadRequest, api.CodeInvalidArgument, "invalid ttl")
+				return
+			}
+			ttl = parsed
+		}
+		protection.protect(r.URL.Query().Get("reason"), ttl)
+	case http.MethodDelete:
+		protection.release()
+	default:
+		api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(protection.status())
+}
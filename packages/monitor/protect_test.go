@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func FuzzProtectHandler(f *testing.F) {
+	f.Add("10m")
+	f.Add("0")
+	f.Add("-1s")
+	f.Add("2562047h47m16.854775807s")
+	f.Add("1.5h30m")
+	f.Add("9223372036854775808ns")
+	f.Fuzz(func(t *testing.T, ttl string) {
+		defer protection.release()
+
+		req := httptest.NewRequest(http.MethodPost, "/protectz?"+url.Values{"ttl": {ttl}, "reason": {"batch"}}.Encode(), nil)
+		rec := httptest.NewRecorder()
+		protectHandler(rec, req)
+
+		status := protection.status()
+		if rec.Code == http.StatusBadRequest {
+			if status.Protected {
+				t.Fatalf("rejected ttl %q protected the instance", ttl)
+			}
+			return
+		}
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		var parsed time.Duration
+		if ttl != "" {
+			var err error
+			parsed, err = time.ParseDuration(ttl)
+			if err != nil || parsed < 0 {
+				t.Fatalf("accepted ttl %q", ttl)
+			}
+		}
+		// A ttl too short to outlast the request may already have expired
+		if !status.Protected && parsed > time.Second {
+			t.Fatalf("ttl %q didn't protect the instance", ttl)
+		}
+		if status.Protected && (parsed == 0) != (status.ExpiresAt == "") {
+			t.Fatalf("ttl %q expires at %q", ttl, status.ExpiresAt)
+		}
+	})
+}
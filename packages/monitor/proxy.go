@@ -0,0 +1,70 @@
+//go:build !minimal && !noproxy
+
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+type ProxyStats = api.ProxyStats
+
+// rateMeter turns counters into per-second rates averaged over a fixed
+// interval, so proxy rates don't depend on how often the monitor is
+// scraped.
+type rateMeter struct {
+	counters []*atomic.Uint64
+
+	mu    sync.Mutex
+	last  []uint64
+	rates []float64
+}
+
+// newRateMeter measures counters from their current values, which may have
+// been restored from -state-file.
+func newRateMeter(counters ...*atomic.Uint64) *rateMeter {
+	m := &rateMeter{
+		counters: counters,
+		last:     make([]uint64, len(counters)),
+		rates:    make([]float64, len(counters)),
+	}
+	for i, c := range counters {
+		m.last[i] = c.Load()
+	}
+	return m
+}
+
+// run recomputes the rates every interval until ctx is done.
+func (m *rateMeter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastCheck := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(lastCheck).Seconds()
+			lastCheck = now
+			m.mu.Lock()
+			for i, c := range m.counters {
+				value := c.Load()
+				m.rates[i] = float64(value-m.last[i]) / elapsed
+				m.last[i] = value
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// get returns the rates in the order the counters were passed in.
+func (m *rateMeter) get() []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]float64(nil), m.rates...)
+}
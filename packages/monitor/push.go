@@ -0,0 +1,154 @@
+//go:build !minimal
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/signing"
+)
+
+var (
+	pushURL      = flag.String("push-url", "", "URL samples are POSTed to as JSON; enables push mode")
+	pushInterval = flag.Duration("push-interval", 15*time.Second, "How often a sample is pushed to -push-url")
+	pushSecret   = flag.String("push-secret", os.Getenv("MONITOR_PUSH_SECRET"), "Shared secret used to sign pushes (env MONITOR_PUSH_SECRET)")
+)
+
+// activePusher is the running pusher, if push mode is enabled.
+var activePusher *pusher
+
+func init() {
+	registerFeature(feature{
+		name:  "push",
+		start: startPush,
+	})
+}
+
+func startPush() error {
+	if *pushURL == "" {
+		return nil
+	}
+	if u, err := url.Parse(*pushURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("-push-url must be an http or https URL")
+	}
+	if *pushInterval <= 0 {
+		return fmt.Errorf("-push-interval must be positive")
+	}
+
+	p := &pusher{
+		url:      *pushURL,
+		secret:   []byte(*pushSecret),
+		interval: *pushInterval,
+		client:   &http.Client{Timeout: *pushInterval},
+	}
+	activePusher = p
+	go p.run(context.Background())
+	return nil
+}
+
+// pusher POSTs samples to a central endpoint, for instances that can't be
+// scraped (behind NAT, or serverless containers without inbound traffic).
+type pusher struct {
+	url      string
+	secret   []byte
+	interval time.Duration
+	client   *http.Client
+	// failing is set while pushes fail, so errors are logged once per outage
+	failing bool
+
+	// pending is the number of samples waiting to be delivered, which is at
+	// most one since a sample is dropped when the next one is due
+	pending   atomic.Int64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	retries   atomic.Uint64
+}
+
+func (p *pusher) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.push(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// push sends the current sample, retrying with jittered exponential backoff
+// until the next push is due. A sample that can't be delivered by then is
+// dropped in favour of a fresh one.
+func (p *pusher) push(ctx context.Context) {
+	body, err := json.Marshal(sample(ctx))
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.interval)
+	defer cancel()
+	p.pending.Add(1)
+	defer p.pending.Add(-1)
+
+	backoff := time.Second
+	for {
+		err = p.send(ctx, body)
+		if err == nil {
+			p.delivered.Add(1)
+			if p.failing {
+				fmt.Fprintf(os.Stderr, "[monitor] Push to %s recovered\n", p.url)
+				p.failing = false
+			}
+			return
+		}
+
+		// Full jitter keeps a fleet that lost its controller at the same
+		// moment from retrying in lockstep
+		wait := time.Duration(rand.Int63n(int64(backoff)))
+		backoff = min(2*backoff, 30*time.Second)
+		select {
+		case <-ctx.Done():
+			p.dropped.Add(1)
+			if !p.failing {
+				fmt.Fprintf(os.Stderr, "[monitor] Push to %s failed: %v\n", p.url, err)
+				p.failing = true
+			}
+			return
+		case <-time.After(wait):
+			p.retries.Add(1)
+		}
+	}
+}
+
+func (p *pusher) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(p.secret) > 0 {
+		signing.SignRequest(req, p.secret, body)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
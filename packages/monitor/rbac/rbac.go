@@ -0,0 +1,253 @@
+// Package rbac authenticates API tokens and authorizes them by role and
+// namespace, writing an audit record for every mutating call.
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+// Role orders what a token may do; each role includes the ones below it.
+type Role int
+
+const (
+	RoleReadOnly Role = iota + 1
+	RoleOperator
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleReadOnly:
+		return "read-only"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "read-only":
+		return RoleReadOnly, nil
+	case "operator":
+		return RoleOperator, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return 0, fmt.Errorf("rbac: unknown role %q", s)
+}
+
+func (r Role) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Role) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	role, err := ParseRole(s)
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
+
+// Token grants a role within a set of namespaces. An empty Namespaces list,
+// or one containing "*", grants every namespace.
+type Token struct {
+	Name       string   `json:"name"`
+	Secret     string   `json:"token"`
+	Role       Role     `json:"role"`
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+func (t Token) allows(namespace string) bool {
+	if len(t.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range t.Namespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrUnauthenticated = errors.New("rbac: missing or unknown token")
+	ErrForbidden       = errors.New("rbac: token not allowed for this action")
+)
+
+// Authorizer checks requests against a fixed set of tokens.
+type Authorizer struct {
+	tokens map[string]Token
+
+	auditMu sync.Mutex
+	audit   io.Writer
+}
+
+// New returns an Authorizer for tokens that writes audit records to audit
+// (which may be nil to disable auditing).
+func New(tokens []Token, audit io.Writer) (*Authorizer, error) {
+	a := &Authorizer{tokens: make(map[string]Token, len(tokens)), audit: audit}
+	for _, t := range tokens {
+		if t.Secret == "" {
+			return nil, fmt.Errorf("rbac: token %q has no secret", t.Name)
+		}
+		if t.Role < RoleReadOnly || t.Role > RoleAdmin {
+			return nil, fmt.Errorf("rbac: token %q has no role", t.Name)
+		}
+		if _, dup := a.tokens[t.Secret]; dup {
+			return nil, fmt.Errorf("rbac: token %q reuses another token's secret", t.Name)
+		}
+		a.tokens[t.Secret] = t
+	}
+	return a, nil
+}
+
+// LoadFile reads a JSON array of tokens from path.
+func LoadFile(path string, audit io.Writer) (*Authorizer, error) {
+	tokens, err := ReadTokens(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(tokens, audit)
+}
+
+// ReadTokens reads a JSON array of tokens from path without building an
+// Authorizer, so callers can add tokens from elsewhere first.
+func ReadTokens(path string) ([]Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("rbac: parsing %s: %w", path, err)
+	}
+	return tokens, nil
+}
+
+// Authorize returns the token presented by r if it holds at least role in
+// namespace.
+func (a *Authorizer) Authorize(r *http.Request, role Role, namespace string) (Token, error) {
+	secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return Token{}, ErrUnauthenticated
+	}
+	token, ok := a.tokens[secret]
+	if !ok {
+		return Token{}, ErrUnauthenticated
+	}
+	if token.Role < role || !token.allows(namespace) {
+		return token, ErrForbidden
+	}
+	return token, nil
+}
+
+// AuditRecord is written as one JSON line per mutating call.
+type AuditRecord struct {
+	Time      string `json:"time"`
+	Token     string `json:"token,omitempty"`
+	Role      Role   `json:"role,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	// Reason is the request's ?reason=, which calls that change what an
+	// instance does take to say why
+	Reason string `json:"reason,omitempty"`
+	Status int    `json:"status"`
+	Remote string `json:"remote"`
+}
+
+// Require wraps next so it only runs for tokens holding role in the
+// namespace returned by namespace(r). Non-GET/HEAD requests are audited,
+// including rejected ones.
+func (a *Authorizer) Require(role Role, namespace func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ns := namespace(r)
+		token, err := a.Authorize(r, role, ns)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		switch {
+		case errors.Is(err, ErrUnauthenticated):
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			api.WriteError(rec, http.StatusUnauthorized, api.CodeUnauthenticated, err.Error())
+		case err != nil:
+			api.WriteError(rec, http.StatusForbidden, api.CodePermissionDenied, err.Error())
+		default:
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), tokenKey{}, token)))
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			a.record(AuditRecord{
+				Time:      time.Now().UTC().Format(time.RFC3339),
+				Token:     token.Name,
+				Role:      token.Role,
+				Namespace: ns,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Reason:    r.URL.Query().Get("reason"),
+				Status:    rec.status,
+				Remote:    r.RemoteAddr,
+			})
+		}
+	})
+}
+
+type tokenKey struct{}
+
+// TokenFromContext returns the token a request passed Require with, so a
+// handler can tell who made a change.
+func TokenFromContext(ctx context.Context) (Token, bool) {
+	token, ok := ctx.Value(tokenKey{}).(Token)
+	return token, ok
+}
+
+func (a *Authorizer) record(rec AuditRecord) {
+	if a.audit == nil {
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	a.auditMu.Lock()
+	defer a.auditMu.Unlock()
+	a.audit.Write(append(line, '\n'))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
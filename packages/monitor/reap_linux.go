@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const reapInterval = time.Second
+
+// startReaper reaps orphaned processes when the monitor runs as PID 1 in a
+// container, where every orphan is re-parented to it and would otherwise
+// pile up as a zombie. exempt reports children the monitor waits for itself.
+//
+// Reaping with wait4(-1) would race os/exec for the exit status of the
+// monitor's own commands, so only zombies still unreaped a full interval
+// after they were first seen are collected; a command the monitor waits on
+// is reaped within microseconds of exiting.
+func startReaper(exempt func(pid int) bool) {
+	if os.Getpid() != 1 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(reapInterval)
+		defer ticker.Stop()
+
+		seen := map[int]bool{}
+		for range ticker.C {
+			next := map[int]bool{}
+			for _, pid := range zombieChildren() {
+				switch {
+				case exempt(pid):
+				case seen[pid]:
+					var status syscall.WaitStatus
+					syscall.Wait4(pid, &status, syscall.WNOHANG, nil)
+				default:
+					next[pid] = true
+				}
+			}
+			seen = next
+		}
+	}()
+}
+
+// zombieChildren lists the monitor's children that have exited but haven't
+// been waited for.
+func zombieChildren() []int {
+	stats, _ := filepath.Glob("/proc/[0-9]*/stat")
+	self := os.Getpid()
+
+	var zombies []int
+	for _, path := range stats {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// The command name is in parentheses and may contain spaces, so
+		// fields are counted from the last closing one
+		end := strings.LastIndexByte(string(data), ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(string(data[end+1:]))
+		if len(fields) < 2 || fields[0] != "Z" {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err != nil || ppid != self {
+			continue
+		}
+		if pid, err := strconv.Atoi(filepath.Base(filepath.Dir(path))); err == nil {
+			zombies = append(zombies, pid)
+		}
+	}
+	return zombies
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+// startReaper does nothing outside Linux, where the monitor doesn't run as a
+// container's PID 1.
+func startReaper(func(pid int) bool) {}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+var (
+	labelAllow = flag.String("label-allow", "", "Comma-separated key patterns (* matches anything) of labels and environment metadata exported as-is; when set, all other values are redacted")
+	labelDeny  = flag.String("label-deny", "", "Comma-separated key patterns (* matches anything) of labels and environment metadata whose values are always redacted")
+)
+
+// redactedValue replaces sensitive values, so consumers can still see which
+// keys exist.
+const redactedValue = "redacted"
+
+// redactor decides which label and metadata values may leave the instance.
+// Deny patterns win over allow patterns.
+type redactor struct {
+	allow []string
+	deny  []string
+}
+
+func newRedactor(allow, deny string) redactor {
+	return redactor{allow: splitPatterns(allow), deny: splitPatterns(deny)}
+}
+
+func splitPatterns(value string) []string {
+	var patterns []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+func (r redactor) redacts(key string) bool {
+	for _, p := range r.deny {
+		if globMatch(p, key) {
+			return true
+		}
+	}
+	if len(r.allow) == 0 {
+		return false
+	}
+	for _, p := range r.allow {
+		if globMatch(p, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns a copy of values with redacted values replaced.
+func (r redactor) apply(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	out := make(map[string]string, len(values))
+	for k, v := range values {
+		if r.redacts(k) {
+			v = redactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// environment returns a copy of env with its metadata redacted.
+func (r redactor) environment(env *Environment) *Environment {
+	if env == nil {
+		return nil
+	}
+	redacted := *env
+	redacted.Metadata = r.apply(env.Metadata)
+	return &redacted
+}
+
+// globMatch reports whether s matches pattern, where * matches any run of
+// characters (including "/", unlike path.Match, since label keys such as
+// app.kubernetes.io/name contain slashes).
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzGlobMatch(f *testing.F) {
+	f.Add("*secret*", "db_secret_key")
+	f.Add("app.kubernetes.io/*", "app.kubernetes.io/")
+	f.Add("*SECRET*", "SECRE")
+	f.Add("a*a", "a")
+	f.Add("**", "")
+	f.Add("*ab*ab", "abab")
+	f.Fuzz(func(t *testing.T, pattern, s string) {
+		// Patterns come from flags and keys from labels, both text
+		if !utf8.ValidString(pattern) || !utf8.ValidString(s) {
+			return
+		}
+		parts := strings.Split(pattern, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		want := regexp.MustCompile(`(?s)^` + strings.Join(parts, ".*") + `$`).MatchString(s)
+		if got := globMatch(pattern, s); got != want {
+			t.Fatalf("globMatch(%q, %q) = %v, want %v", pattern, s, got, want)
+		}
+	})
+}
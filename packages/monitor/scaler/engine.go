@@ -0,0 +1,115 @@
+package scaler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+// Decision is emitted when a rule has held for its full duration on one
+// target.
+type Decision struct {
+	Action Action    `json:"action"`
+	Target string    `json:"target"`
+	Rule   string    `json:"rule"`
+	Metric string    `json:"metric"`
+	Value  float64   `json:"value"`
+	At     time.Time `json:"at"`
+	// Labels are the target's labels from its latest sample, so hooks can
+	// tell which service or pool it belongs to
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Engine evaluates rules against a stream of samples per target. It is safe
+// for concurrent use.
+type Engine struct {
+	rules []Rule
+
+	mu sync.Mutex
+	// since records when each rule's condition started holding, per target
+	since map[string][]time.Time
+}
+
+func NewEngine(rules []Rule) *Engine {
+	return &Engine{rules: rules, since: map[string][]time.Time{}}
+}
+
+func (e *Engine) Rules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rules
+}
+
+// SetRules replaces the rules, e.g. when they're reloaded while running. A
+// rule that is kept unchanged keeps how long it has held on each target;
+// new and changed rules start over.
+func (e *Engine) SetRules(rules []Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	previous := make(map[string]int, len(e.rules))
+	for i, rule := range e.rules {
+		previous[rule.String()] = i
+	}
+	for target, since := range e.since {
+		next := make([]time.Time, len(rules))
+		for i, rule := range rules {
+			if j, ok := previous[rule.String()]; ok {
+				next[i] = since[j]
+			}
+		}
+		e.since[target] = next
+	}
+	e.rules = rules
+}
+
+// Observe feeds one sample from target and returns the decisions it
+// triggers. A rule's condition must hold on every sample for the rule's
+// duration; a sample where the metric wasn't measured, or that fails the
+// rule's health gate, resets it. After firing, a rule that keeps holding
+// fires again every duration.
+func (e *Engine) Observe(target string, sample *api.MonitorResponse, now time.Time) []Decision {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	since, ok := e.since[target]
+	if !ok {
+		since = make([]time.Time, len(e.rules))
+		e.since[target] = since
+	}
+
+	var decisions []Decision
+	for i, rule := range e.rules {
+		value, ok := rule.value(sample)
+		if !ok || !rule.holds(value) || rule.gated(sample) {
+			since[i] = time.Time{}
+			continue
+		}
+		if since[i].IsZero() {
+			since[i] = now
+		}
+		if now.Sub(since[i]) < rule.For {
+			continue
+		}
+		decisions = append(decisions, Decision{
+			Action: rule.Action,
+			Target: target,
+			Rule:   rule.String(),
+			Metric: rule.Metric,
+			Value:  value,
+			At:     now,
+			Labels: sample.Labels,
+		})
+		since[i] = now
+	}
+	return decisions
+}
+
+// Forget drops target's state, e.g. when it can't be reached, so its rules
+// start over once it reports again.
+func (e *Engine) Forget(target string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.since, target)
+}
@@ -0,0 +1,123 @@
+package scaler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/abhi-arya1/autoscaled/monitor/signing"
+)
+
+// Hook carries out a decision, e.g. by calling an orchestrator.
+type Hook interface {
+	Emit(ctx context.Context, d Decision) error
+}
+
+// exitTempFail is EX_TEMPFAIL from sysexits.h, which an Exec hook exits
+// with when the provider throttled it.
+const exitTempFail = 75
+
+// HookErrorCode classifies an error from Hook.Emit: the code of an
+// *api.Error in its chain, CodeHookTimeout once its context's deadline
+// passed, or CodeHookFailed.
+func HookErrorCode(err error) string {
+	var e *api.Error
+	switch {
+	case errors.As(err, &e):
+		return e.Code
+	case errors.Is(err, context.DeadlineExceeded):
+		return api.CodeHookTimeout
+	}
+	return api.CodeHookFailed
+}
+
+// Webhook POSTs each decision as JSON to URL. When Secret is set the body
+// is signed with package signing, so the receiver can verify it with
+// signing.Middleware.
+type Webhook struct {
+	URL    string
+	Secret []byte
+	// Client defaults to http.DefaultClient; the context passed to Emit
+	// bounds each call
+	Client *http.Client
+}
+
+func (w *Webhook) Emit(ctx context.Context, d Decision) error {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.Secret) > 0 {
+		signing.SignRequest(req, w.Secret, body)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		code := api.CodeHookFailed
+		if resp.StatusCode == http.StatusTooManyRequests {
+			code = api.CodeProviderThrottled
+		}
+		return &api.Error{Code: code, Message: fmt.Sprintf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))}
+	}
+	return nil
+}
+
+// Exec runs Command for each decision, with the decision as JSON on stdin
+// and its main fields in SCALER_ACTION, SCALER_TARGET, SCALER_RULE,
+// SCALER_METRIC, and SCALER_VALUE. A command that exits with 75
+// (EX_TEMPFAIL) reports that the provider throttled it.
+type Exec struct {
+	Command []string
+}
+
+func (e *Exec) Emit(ctx context.Context, d Decision) error {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, e.Command[0], e.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"SCALER_ACTION="+string(d.Action),
+		"SCALER_TARGET="+d.Target,
+		"SCALER_RULE="+d.Rule,
+		"SCALER_METRIC="+d.Metric,
+		"SCALER_VALUE="+strconv.FormatFloat(d.Value, 'f', -1, 64),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == exitTempFail {
+			return &api.Error{Code: api.CodeProviderThrottled, Message: fmt.Sprintf("%s: %v: %s", e.Command[0], err, strings.TrimSpace(string(out)))}
+		}
+		return fmt.Errorf("scaler: %s: %w: %s", e.Command[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package scaler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+// Action is what a rule asks for when it fires.
+type Action string
+
+const (
+	ScaleUp   Action = "scale_up"
+	ScaleDown Action = "scale_down"
+)
+
+// Metrics a rule can be written against, the collector that must have
+// measured each one, and whether it's a percentage, whose threshold may be
+// written with a % suffix.
+var metrics = map[string]struct {
+	collector string
+	percent   bool
+	value     func(*api.MonitorResponse) (float64, bool)
+}{
+	"cpu":    {"cpu", true, func(r *api.MonitorResponse) (float64, bool) { return r.CPUUsage, true }},
+	"memory": {"memory", true, func(r *api.MonitorResponse) (float64, bool) { return r.MemoryUsage, true }},
+	"disk":   {"disk", true, func(r *api.MonitorResponse) (float64, bool) { return r.DiskUsage, true }},
+	"pressure": {"", true, func(r *api.MonitorResponse) (float64, bool) {
+		if r.PressureScore == nil {
+			return 0, false
+		}
+		return *r.PressureScore, true
+	}},
+	"load": {"load", false, func(r *api.MonitorResponse) (float64, bool) {
+		if r.LoadAverage == nil || r.LoadAverage.Cores == 0 {
+			return 0, false
+		}
+		return r.LoadAverage.Load1 / float64(r.LoadAverage.Cores), true
+	}},
+	"steal": {"steal", true, func(r *api.MonitorResponse) (float64, bool) {
+		if r.CPUSteal == nil {
+			return 0, false
+		}
+		return *r.CPUSteal, true
+	}},
+	"rps": {"http_proxy", false, func(r *api.MonitorResponse) (float64, bool) {
+		if r.Proxy == nil || r.Proxy.HTTP == nil {
+			return 0, false
+		}
+		return r.Proxy.HTTP.RequestsPerSecond, true
+	}},
+	"concurrency": {"http_proxy", false, func(r *api.MonitorResponse) (float64, bool) {
+		if r.Proxy == nil || r.Proxy.HTTP == nil || r.Proxy.HTTP.Concurrency == nil {
+			return 0, false
+		}
+		return *r.Proxy.HTTP.Concurrency, true
+	}},
+	"in_flight": {"http_proxy", false, func(r *api.MonitorResponse) (float64, bool) {
+		if r.Proxy == nil || r.Proxy.HTTP == nil {
+			return 0, false
+		}
+		return float64(r.Proxy.HTTP.InFlight), true
+	}},
+	"error_rate": {"http_proxy", true, func(r *api.MonitorResponse) (float64, bool) {
+		if r.Proxy == nil || r.Proxy.HTTP == nil || r.Proxy.HTTP.ErrorPercent == nil {
+			return 0, false
+		}
+		return *r.Proxy.HTTP.ErrorPercent, true
+	}},
+	"p50_latency": {"http_proxy", false, httpLatency(func(l *api.LatencyPercentiles) float64 { return l.P50Seconds })},
+	"p95_latency": {"http_proxy", false, httpLatency(func(l *api.LatencyPercentiles) float64 { return l.P95Seconds })},
+	"p99_latency": {"http_proxy", false, httpLatency(func(l *api.LatencyPercentiles) float64 { return l.P99Seconds })},
+	"process_cpu": {"process", true, func(r *api.MonitorResponse) (float64, bool) {
+		if r.Process == nil {
+			return 0, false
+		}
+		return r.Process.CPUPercent, true
+	}},
+}
+
+// httpLatency reads a latency percentile from the HTTP proxy. An interval
+// without requests has no latency, which isn't a fast one.
+func httpLatency(percentile func(*api.LatencyPercentiles) float64) func(*api.MonitorResponse) (float64, bool) {
+	return func(r *api.MonitorResponse) (float64, bool) {
+		if r.Proxy == nil || r.Proxy.HTTP == nil || r.Proxy.HTTP.Latency == nil {
+			return 0, false
+		}
+		return percentile(r.Proxy.HTTP.Latency), true
+	}
+}
+
+// Rule fires Action once Metric has compared against Threshold for at least
+// For, e.g. "cpu > 80% for 2m -> scale_up".
+type Rule struct {
+	Metric    string
+	Op        string
+	Threshold float64
+	For       time.Duration
+	Action    Action
+	// Healthy gates the rule on the target's -probe health checks: it only
+	// holds while every probe passes
+	Healthy bool
+}
+
+// ParseRule parses
+// "<metric> <op> <threshold>[%] [for <duration>] [while healthy] -> <action>".
+// The metric is one of cpu, memory, disk, pressure, load (the 1 minute load
+// average per core), steal, process_cpu, or one of the HTTP proxy's rps,
+// concurrency, in_flight, error_rate, p50_latency, p95_latency, and
+// p99_latency (in seconds); op is one of >, >=, <, <=; action is "scale up"
+// or "scale down" (spaces, hyphens, and underscores are interchangeable).
+// Only percentages, which are all but load, rps, concurrency, in_flight, and
+// the latencies, may take a % suffix.
+func ParseRule(s string) (Rule, error) {
+	condition, action, ok := strings.Cut(strings.ReplaceAll(s, "→", "->"), "->")
+	if !ok {
+		return Rule{}, fmt.Errorf("scaler: rule %q: missing \"-> <action>\"", s)
+	}
+
+	var rule Rule
+	switch strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(action))) {
+	case string(ScaleUp):
+		rule.Action = ScaleUp
+	case string(ScaleDown):
+		rule.Action = ScaleDown
+	default:
+		return Rule{}, fmt.Errorf("scaler: rule %q: unknown action %q", s, strings.TrimSpace(action))
+	}
+
+	fields := strings.Fields(condition)
+	if n := len(fields); n > 2 && strings.EqualFold(fields[n-2], "while") && strings.EqualFold(fields[n-1], "healthy") {
+		rule.Healthy = true
+		fields = fields[:n-2]
+	}
+	if len(fields) != 3 && len(fields) != 5 {
+		return Rule{}, fmt.Errorf("scaler: rule %q: expected \"<metric> <op> <threshold> [for <duration>]\"", s)
+	}
+
+	rule.Metric = strings.ToLower(fields[0])
+	if _, ok := metrics[rule.Metric]; !ok {
+		return Rule{}, fmt.Errorf("scaler: rule %q: unknown metric %q", s, fields[0])
+	}
+
+	switch fields[1] {
+	case ">", ">=", "<", "<=":
+		rule.Op = fields[1]
+	default:
+		return Rule{}, fmt.Errorf("scaler: rule %q: unknown operator %q", s, fields[1])
+	}
+
+	value, percent := strings.CutSuffix(fields[2], "%")
+	if percent && !metrics[rule.Metric].percent {
+		return Rule{}, fmt.Errorf("scaler: rule %q: %s isn't a percentage; drop the %% from %q", s, rule.Metric, fields[2])
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(threshold) || math.IsInf(threshold, 0) {
+		return Rule{}, fmt.Errorf("scaler: rule %q: invalid threshold %q", s, fields[2])
+	}
+	rule.Threshold = threshold
+
+	if len(fields) == 5 {
+		if fields[3] != "for" {
+			return Rule{}, fmt.Errorf("scaler: rule %q: expected \"for\", got %q", s, fields[3])
+		}
+		rule.For, err = time.ParseDuration(fields[4])
+		if err != nil || rule.For < 0 {
+			return Rule{}, fmt.Errorf("scaler: rule %q: invalid duration %q", s, fields[4])
+		}
+	}
+	return rule, nil
+}
+
+func (r Rule) String() string {
+	s := fmt.Sprintf("%s %s %g", r.Metric, r.Op, r.Threshold)
+	if metrics[r.Metric].percent {
+		s += "%"
+	}
+	if r.For > 0 {
+		s += " for " + r.For.String()
+	}
+	if r.Healthy {
+		s += " while healthy"
+	}
+	return s + " -> " + string(r.Action)
+}
+
+// value reads the rule's metric from a sample. ok is false when the metric
+// wasn't measured, which must not be read as zero load, or isn't a finite
+// number, which would make the comparison meaningless.
+func (r Rule) value(sample *api.MonitorResponse) (value float64, ok bool) {
+	m := metrics[r.Metric]
+	if m.collector != "" && sample.Status[m.collector] != api.StatusOK {
+		return 0, false
+	}
+	value, ok = m.value(sample)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, ok
+}
+
+// gated reports whether the rule's health gate keeps it from holding on
+// sample. Probes that weren't measured don't count as passing.
+func (r Rule) gated(sample *api.MonitorResponse) bool {
+	if !r.Healthy {
+		return false
+	}
+	return sample.Status["probes"] != api.StatusOK || sample.Probes == nil || !sample.Probes.Healthy
+}
+
+func (r Rule) holds(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	}
+	return false
+}
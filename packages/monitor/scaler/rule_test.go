@@ -0,0 +1,42 @@
+package scaler
+
+import (
+	"math"
+	"testing"
+)
+
+func FuzzParseRule(f *testing.F) {
+	for _, s := range []string{
+		"cpu > 80% for 2m -> scale up",
+		"memory <= 20 for 10m while healthy -> scale-down",
+		"load >= 1.5 → scale_up",
+		"p99_latency > 0.25 for 1m30s -> scale up",
+		"rps < 1e3 -> SCALE DOWN",
+		"cpu > 80%% -> scale up",
+		"disk > NaN -> scale up",
+		"cpu > 80 for -1s -> scale up",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		rule, err := ParseRule(s)
+		if err != nil {
+			return
+		}
+		if math.IsNaN(rule.Threshold) || math.IsInf(rule.Threshold, 0) {
+			t.Fatalf("ParseRule(%q) threshold %g isn't finite", s, rule.Threshold)
+		}
+		if rule.For < 0 {
+			t.Fatalf("ParseRule(%q) duration %s is negative", s, rule.For)
+		}
+		// The scaler logs rules with String, and policytest compares them
+		// by it, so it must parse back to the same rule
+		again, err := ParseRule(rule.String())
+		if err != nil {
+			t.Fatalf("ParseRule(%q) = %q, which doesn't parse: %v", s, rule.String(), err)
+		}
+		if again != rule {
+			t.Fatalf("ParseRule(%q) = %+v, but its String %q parses to %+v", s, rule, rule.String(), again)
+		}
+	})
+}
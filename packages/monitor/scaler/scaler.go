@@ -0,0 +1,141 @@
+// Package scaler turns monitor samples into scaling decisions. It polls
+// monitors with package client, evaluates threshold rules such as
+// "cpu > 80% for 2m -> scale up" against each one, and hands the resulting
+// decisions to hooks (a webhook or a command) that act on them.
+package scaler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+	"github.com/abhi-arya1/autoscaled/monitor/client"
+)
+
+// Scaler polls Targets every Interval and emits the decisions Engine makes
+// to every hook.
+type Scaler struct {
+	Engine   *Engine
+	Targets  []*client.Client
+	Hooks    []Hook
+	Interval time.Duration
+	// Logf reports unreachable targets and failing hooks; nil discards them
+	Logf func(format string, args ...any)
+	// OnError, when set, is called with each failure as it happens, for
+	// automation that branches on its Code
+	OnError func(ErrorEvent)
+
+	paused atomic.Bool
+	held   atomic.Uint64
+}
+
+// ErrorEvent is a target that couldn't be sampled or a hook that failed.
+type ErrorEvent struct {
+	// Code is one of the api.Code constants: CodeTargetUnreachable, the code
+	// the monitor answered with, CodeProviderThrottled, CodeHookFailed, or
+	// CodeHookTimeout
+	Code   string `json:"code"`
+	Target string `json:"target"`
+	// Decision is the decision a hook failed to carry out
+	Decision *Decision `json:"decision,omitempty"`
+	Message  string    `json:"message"`
+	At       time.Time `json:"at"`
+}
+
+// Run polls until ctx is done.
+func (s *Scaler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll samples every target concurrently and emits their decisions.
+// Sampling a target may take at most one interval; hooks aren't bounded
+// beyond ctx.
+func (s *Scaler) poll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, target := range s.Targets {
+		target := target
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sampleCtx, cancel := context.WithTimeout(ctx, s.Interval)
+			sample, err := target.Monitor(sampleCtx)
+			cancel()
+			if err != nil {
+				code := api.CodeTargetUnreachable
+				var e *client.Error
+				if errors.As(err, &e) && e.Code != "" {
+					code = e.Code
+				}
+				s.logf("failed to sample %s (%s): %v", target.BaseURL, code, err)
+				s.report(ErrorEvent{Code: code, Target: target.BaseURL, Message: err.Error(), At: time.Now()})
+				s.Engine.Forget(target.BaseURL)
+				return
+			}
+			for _, d := range s.Engine.Observe(target.BaseURL, sample, time.Now()) {
+				s.emit(ctx, d)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// SetPaused stops or restarts sending decisions to hooks, e.g. during a
+// provider incident. While paused, targets are still sampled and rules
+// still evaluated, but each decision is logged and dropped rather than held
+// for later: a rule that still holds once the scaler resumes fires again
+// after its duration.
+func (s *Scaler) SetPaused(paused bool) {
+	s.paused.Store(paused)
+}
+
+// Paused reports whether decisions are being dropped.
+func (s *Scaler) Paused() bool {
+	return s.paused.Load()
+}
+
+// Held returns how many decisions were dropped while paused.
+func (s *Scaler) Held() uint64 {
+	return s.held.Load()
+}
+
+func (s *Scaler) emit(ctx context.Context, d Decision) {
+	if s.Paused() {
+		s.held.Add(1)
+		s.logf("paused, not sending %s %s: %s (%s = %.1f)", d.Action, d.Target, d.Rule, d.Metric, d.Value)
+		return
+	}
+	s.logf("%s %s: %s (%s = %.1f)", d.Action, d.Target, d.Rule, d.Metric, d.Value)
+	for _, hook := range s.Hooks {
+		if err := hook.Emit(ctx, d); err != nil {
+			code := HookErrorCode(err)
+			s.logf("hook failed for %s %s (%s): %v", d.Action, d.Target, code, err)
+			d := d
+			s.report(ErrorEvent{Code: code, Target: d.Target, Decision: &d, Message: err.Error(), At: time.Now()})
+		}
+	}
+}
+
+func (s *Scaler) report(e ErrorEvent) {
+	if s.OnError != nil {
+		s.OnError(e)
+	}
+}
+
+func (s *Scaler) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "Longest the server waits for in-flight requests once the monitor is stopping")
+
+// shutdownRequests receives shutdown signals, and POST /quitquitquit, which
+// is handled exactly like one.
+var shutdownRequests = make(chan os.Signal, 2)
+
+// stopping is closed once the server starts shutting down, so handlers that
+// never finish on their own, like streams, don't hold it up.
+var stopping = make(chan struct{})
+
+// quitHandler starts a graceful shutdown, for platforms that can't signal
+// the container: the child's work drains, it is signalled, and the monitor
+// exits once the child does. A second call skips the drain.
+func quitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		api.WriteError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	select {
+	case shutdownRequests <- shutdownSignals[len(shutdownSignals)-1]:
+	default:
+		// Already stopping as fast as it can
+	}
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "shutting down")
+}
+
+// shutdownServer stops accepting connections and waits up to
+// -shutdown-timeout for in-flight requests, so a scrape that's underway when
+// the monitor stops still gets its answer.
+func shutdownServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	close(stopping)
+	if err := server.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "[monitor] Stopped with requests in flight: %v\n", err)
+	}
+}
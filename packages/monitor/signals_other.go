@@ -0,0 +1,31 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Only os.Interrupt is delivered outside Unix; SIGTERM is never raised.
+var shutdownSignals = []os.Signal{os.Interrupt}
+
+// passthroughSignals is empty, since there are no other signals to forward.
+var passthroughSignals []os.Signal
+
+// reloadSignals is empty, so -config is only read at startup.
+var reloadSignals []os.Signal
+
+func prepareCommand(*exec.Cmd) {}
+
+// forwardSignal kills the child, since Windows can't send it an interrupt.
+func forwardSignal(p *os.Process, sig os.Signal) error {
+	if err := p.Signal(sig); err == nil {
+		return nil
+	}
+	return p.Kill()
+}
+
+func killProcess(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var processGroup = flag.Bool("process-group", os.Getpid() == 1, "Run the command in its own process group and signal the whole group (default on as PID 1)")
+
+// shutdownSignals are the signals that stop the monitor and are forwarded to
+// the child command.
+var shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// passthroughSignals are forwarded to the child straight away, so the
+// monitor is transparent as PID 1 to commands that reload on SIGHUP or dump
+// state on SIGQUIT.
+var passthroughSignals = []os.Signal{
+	syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2,
+	syscall.SIGWINCH, syscall.SIGALRM, syscall.SIGCONT,
+}
+
+// reloadSignals make the monitor reread -config. They are still passed
+// through to the child.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
+
+// prepareCommand puts the command in its own process group with
+// -process-group, so signals reach the workers it forks too.
+func prepareCommand(cmd *exec.Cmd) {
+	if *processGroup {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+}
+
+func forwardSignal(p *os.Process, sig os.Signal) error {
+	if s, ok := sig.(syscall.Signal); ok && *processGroup {
+		return syscall.Kill(-p.Pid, s)
+	}
+	return p.Signal(sig)
+}
+
+func killProcess(p *os.Process) error {
+	return forwardSignal(p, syscall.SIGKILL)
+}
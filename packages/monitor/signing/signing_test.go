@@ -0,0 +1,54 @@
+package signing
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func FuzzVerifyRequest(f *testing.F) {
+	secret := []byte("secret")
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	f.Add([]byte(`{"cpu_usage":12.5}`), now, "", true)
+	f.Add([]byte(`{}`), now, "sha256=00", false)
+	f.Add([]byte(``), "", "", false)
+	f.Add([]byte(`x`), "-9223372036854775808", "sha256=zz", false)
+	f.Add([]byte(`x`), "9223372036854775807", "", true)
+	f.Fuzz(func(t *testing.T, body []byte, timestamp, signature string, sign bool) {
+		unix, parseErr := strconv.ParseInt(timestamp, 10, 64)
+		want := Sign(secret, time.Unix(unix, 0), body)
+		if sign {
+			if parseErr != nil {
+				return
+			}
+			signature = want
+		}
+		req, err := http.NewRequest(http.MethodPost, "http://monitor/push", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, signature)
+
+		err = VerifyRequest(req, secret, time.Minute)
+		if err == nil {
+			// Hex is case-insensitive and the prefix optional, but the
+			// signature must be this body's at this timestamp
+			matches := parseErr == nil && strings.EqualFold(strings.TrimPrefix(signature, signaturePrefix), strings.TrimPrefix(want, signaturePrefix))
+			if !matches {
+				t.Fatalf("accepted signature %q for timestamp %q", signature, timestamp)
+			}
+		} else if sign && err != ErrExpired {
+			t.Fatalf("rejected a valid signature: %v", err)
+		}
+
+		restored, err := io.ReadAll(req.Body)
+		if err != nil || !bytes.Equal(restored, body) {
+			t.Fatalf("body not restored: got %q, want %q", restored, body)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	stateFile     = flag.String("state-file", "", "File counters are persisted to, so a monitor restart doesn't reset them")
+	stateInterval = flag.Duration("state-interval", 10*time.Second, "How often counters are written to -state-file")
+)
+
+// persistedState is the document kept in -state-file: signals the scaler
+// relies on that would otherwise reset whenever the monitor restarts.
+type persistedState struct {
+	// Starts counts how many times the monitor has started with this file
+	Starts        uint64 `json:"starts"`
+	ChildRestarts int    `json:"child_restarts"`
+	// LastActive anchors idle time, so a restart doesn't make an idle
+	// instance look freshly busy
+	LastActive time.Time         `json:"last_active,omitempty"`
+	Counters   map[string]uint64 `json:"counters,omitempty"`
+	SavedAt    time.Time         `json:"saved_at"`
+}
+
+var (
+	// restored is the state loaded at startup, or empty without -state-file
+	restored persistedState
+
+	persistedMu       sync.Mutex
+	persistedCounters = map[string]*atomic.Uint64{}
+)
+
+// loadState restores state from -state-file and starts saving it. A missing
+// file starts from zero; an unreadable one is reported and replaced, since
+// losing counters is better than not monitoring.
+func loadState() {
+	if *stateFile == "" {
+		return
+	}
+	data, err := os.ReadFile(*stateFile)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "[monitor] Failed to read state file, starting from zero: %v\n", err)
+	default:
+		if err := json.Unmarshal(data, &restored); err != nil {
+			fmt.Fprintf(os.Stderr, "[monitor] Failed to parse state file, starting from zero: %v\n", err)
+			restored = persistedState{}
+		}
+	}
+	if !restored.LastActive.IsZero() && restored.LastActive.Before(time.Now()) {
+		activity.restore(restored.LastActive)
+	}
+
+	go func() {
+		ticker := time.NewTicker(*stateInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			saveState()
+		}
+	}()
+}
+
+// persistCounter restores c from the state file and saves it from now on.
+// name must be stable across restarts.
+func persistCounter(name string, c *atomic.Uint64) {
+	c.Store(restored.Counters[name])
+	persistedMu.Lock()
+	persistedCounters[name] = c
+	persistedMu.Unlock()
+}
+
+// monitorRestarts is how many times the monitor has restarted with the
+// current -state-file.
+func monitorRestarts() uint64 {
+	return restored.Starts
+}
+
+// saveState writes the current state to -state-file, replacing it
+// atomically so a crash mid-write never leaves a truncated file.
+func saveState() {
+	if *stateFile == "" {
+		return
+	}
+	state := persistedState{
+		Starts:     restored.Starts + 1,
+		LastActive: activity.lastActiveAt(),
+		Counters:   map[string]uint64{},
+		SavedAt:    time.Now().UTC(),
+	}
+	if c := child.snapshot(); c != nil {
+		state.ChildRestarts = c.Restarts
+	}
+	persistedMu.Lock()
+	for name, c := range persistedCounters {
+		state.Counters[name] = c.Load()
+	}
+	persistedMu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = writeFileAtomic(*stateFile, data)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[monitor] Failed to save state: %v\n", err)
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
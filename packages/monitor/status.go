@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+type StatusResponse = api.StatusResponse
+
+// activityTracker records when the instance was last busy, so idle time can
+// be reported for scale-to-zero decisions.
+type activityTracker struct {
+	mu         sync.Mutex
+	threshold  float64
+	lastActive time.Time
+}
+
+var (
+	startedAt = time.Now()
+	activity  = &activityTracker{lastActive: startedAt}
+)
+
+// observe marks the instance active if CPU usage is at or above threshold.
+func (a *activityTracker) observe(cpu float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if cpu >= a.threshold {
+		a.lastActive = time.Now()
+	}
+}
+
+func (a *activityTracker) setThreshold(threshold float64) {
+	a.mu.Lock()
+	a.threshold = threshold
+	a.mu.Unlock()
+}
+
+// restore sets when the instance was last active, from a previous run.
+func (a *activityTracker) restore(at time.Time) {
+	a.mu.Lock()
+	a.lastActive = at
+	a.mu.Unlock()
+}
+
+func (a *activityTracker) lastActiveAt() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lastActive
+}
+
+func (a *activityTracker) idleFor() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return time.Since(a.lastActive)
+}
+
+// sample collects a full MonitorResponse, with any extra collectors the
+// caller asked for.
+func sample(ctx context.Context, extra ...collector) MonitorResponse {
+	samplesTaken.Add(1)
+	resp := collectAll(ctx, append(collectors[:len(collectors):len(collectors)], extra...))
+	resp.Environment = environment
+	resp.Labels = exportedLabels
+	resp.PressureScore = pressureScore(resp)
+	setCollectorTimings(&resp)
+	resp.Protected = protection.protected()
+	if c := child.snapshot(); c != nil {
+		resp.ChildRestarts = &c.Restarts
+	}
+	return resp
+}
+
+// ready reports whether the instance can take traffic: the monitor is
+// serving and not draining, in exec mode the child is running, and the
+// child's health check passes.
+func ready(c *ChildStatus, h *HealthStatus) bool {
+	return !draining.Load() && (c == nil || c.Running) && (h == nil || h.Healthy)
+}
+
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	childStatus := child.snapshot()
+	health := childHealth.snapshot()
+	resp := StatusResponse{
+		Metrics:       sample(r.Context()),
+		Child:         childStatus,
+		ChildHealth:   health,
+		Ready:         ready(childStatus, health),
+		Draining:      draining.Load(),
+		Port:          listenPort,
+		Restarts:      monitorRestarts(),
+		UptimeSeconds: time.Since(startedAt).Seconds(),
+		IdleSeconds:   activity.idleFor().Seconds(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,81 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+func init() {
+	registerFeature(feature{
+		name:      "stream",
+		endpoints: []string{"GET /monitorz/stream"},
+		routes: func(mux *http.ServeMux) {
+			mux.HandleFunc("/monitorz/stream", streamHandler)
+		},
+	})
+}
+
+// streamHandler sends a sample every interval as a Server-Sent Event, one
+// JSON document per event, so subscribers don't have to poll /monitorz.
+// The stream ends when the client goes away or the server shuts down.
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	interval := *sampleInterval
+	if value := r.URL.Query().Get("interval"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			api.WriteError(w, http.StatusBadRequest, api.CodeInvalidArgument, "interval must be a positive duration")
+			return
+		}
+		// CPU and memory don't change more often than they're sampled
+		interval = max(parsed, *sampleInterval)
+	}
+
+	rc := http.NewResponseController(w)
+	// The server's WriteTimeout would otherwise cut every stream off after
+	// 10 seconds
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.CodeInternal, "streaming is not supported on this connection")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keeps nginx and similar proxies from buffering events
+	w.Header().Set("X-Accel-Buffering", "no")
+	// Reconnecting clients come back after one interval, not the browser
+	// default of a few seconds
+	fmt.Fprintf(w, "retry: %d\n\n", interval.Milliseconds())
+
+	send := func(ctx context.Context) error {
+		data, err := json.Marshal(sample(ctx))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		return rc.Flush()
+	}
+	if err := send(r.Context()); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-stopping:
+			return
+		case <-ticker.C:
+		}
+		if err := send(r.Context()); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	restartPolicy     = flag.String("restart", "never", "When the command is restarted after it exits: never, on-failure, or always")
+	maxRestarts       = flag.Int("max-restarts", 5, "Consecutive restarts after which the monitor gives up and exits with the command's code; 0 means no limit")
+	restartBackoff    = flag.Duration("restart-backoff", time.Second, "Delay before the first restart, doubled for each consecutive one")
+	restartBackoffMax = flag.Duration("restart-backoff-max", time.Minute, "Longest delay between restarts")
+	killTimeout       = flag.Duration("kill-timeout", 0, "How long the command has to exit once signalled before it is killed; 0 waits indefinitely")
+)
+
+func validRestartPolicy(policy string) bool {
+	switch policy {
+	case "never", "on-failure", "always":
+		return true
+	}
+	return false
+}
+
+// supervisor runs the command in exec mode, restarting it according to
+// -restart so the monitor can serve as the container's init.
+type supervisor struct {
+	args []string
+
+	mu       sync.Mutex
+	proc     *os.Process
+	running  bool
+	stopping bool
+	stopped  chan struct{}
+	// restartRequested is set by restart, so the command's exit is followed
+	// by an immediate restart whatever -restart says
+	restartRequested bool
+}
+
+// commandSupervisor runs the command in exec mode, and holds nil otherwise.
+var commandSupervisor atomic.Pointer[supervisor]
+
+func newSupervisor(args []string) *supervisor {
+	return &supervisor{args: args, stopped: make(chan struct{})}
+}
+
+// run starts the command and returns the exit code the monitor should exit
+// with, once the command has exited for good.
+func (s *supervisor) run() int {
+	consecutive := 0
+	backoff := *restartBackoff
+	for runs := 0; ; runs++ {
+		started := time.Now()
+		code, err := s.runOnce()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[monitor] Failed to start command: %v\n", err)
+			if runs == 0 {
+				// It has never started, so restarting won't help
+				return 1
+			}
+		}
+
+		if s.takeRestartRequest() {
+			fmt.Fprintf(os.Stderr, "[monitor] Command exited with code %d, restarting as requested\n", code)
+			child.restarting()
+			continue
+		}
+		if !s.shouldRestart(code) {
+			return code
+		}
+		// A command that stayed up for a while isn't crash looping, so the
+		// limit and backoff start over
+		if time.Since(started) >= *restartBackoffMax {
+			consecutive, backoff = 0, *restartBackoff
+		}
+		if *maxRestarts > 0 && consecutive >= *maxRestarts {
+			fmt.Fprintf(os.Stderr, "[monitor] Command exited with code %d after %d restarts in a row, giving up\n", code, consecutive)
+			return code
+		}
+
+		fmt.Fprintf(os.Stderr, "[monitor] Command exited with code %d, restarting in %s\n", code, backoff)
+		select {
+		case <-s.stopped:
+			return code
+		case <-time.After(backoff):
+		}
+		consecutive++
+		backoff = min(2*backoff, *restartBackoffMax)
+		child.restarting()
+	}
+}
+
+// runOnce starts the command and waits for it to exit. It only returns an
+// error if the command couldn't be started, which counts as exiting with
+// code 1.
+func (s *supervisor) runOnce() (int, error) {
+	cmd := exec.Command(s.args[0], s.args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	prepareCommand(cmd)
+
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return 0, nil
+	}
+	if err := cmd.Start(); err != nil {
+		s.mu.Unlock()
+		return 1, err
+	}
+	s.proc, s.running = cmd.Process, true
+	s.mu.Unlock()
+	child.started(cmd.Process.Pid)
+
+	err := cmd.Wait()
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+	code := cmd.ProcessState.ExitCode()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		code = 1
+	}
+	child.exited(code)
+	return code, nil
+}
+
+func (s *supervisor) shouldRestart(code int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopping {
+		return false
+	}
+	switch *restartPolicy {
+	case "always":
+		return true
+	case "on-failure":
+		return code != 0
+	}
+	return false
+}
+
+// restart signals the running command to stop and starts it again once it
+// has exited, without counting toward -max-restarts. It reports false when
+// no command is running.
+func (s *supervisor) restart() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopping || !s.running {
+		return false
+	}
+	s.restartRequested = true
+	forwardSignal(s.proc, shutdownSignals[len(shutdownSignals)-1])
+	return true
+}
+
+func (s *supervisor) takeRestartRequest() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requested := s.restartRequested && !s.stopping
+	s.restartRequested = false
+	return requested
+}
+
+// stop forwards sig to the running command and prevents any further
+// restarts. With -kill-timeout, a command that hasn't exited in time is
+// killed.
+func (s *supervisor) stop(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.stopping {
+		s.stopping = true
+		close(s.stopped)
+	}
+	if !s.running {
+		return
+	}
+	forwardSignal(s.proc, sig)
+	if *killTimeout > 0 {
+		p := s.proc
+		time.AfterFunc(*killTimeout, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.proc == p && s.running {
+				fmt.Fprintf(os.Stderr, "[monitor] Command still running %s after %v, killing it\n", *killTimeout, sig)
+				killProcess(p)
+				child.killed()
+			}
+		})
+	}
+}
+
+// forward passes sig on to the running command without stopping it.
+func (s *supervisor) forward(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		forwardSignal(s.proc, sig)
+	}
+}
+
+// supervised reports whether pid is the running command, which the
+// supervisor waits for itself.
+func (s *supervisor) supervised(pid int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.proc != nil && s.proc.Pid == pid
+}
@@ -0,0 +1,189 @@
+//go:build !minimal && !noproxy
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var tcpProxyFlag = flag.String("tcp-proxy", "", "Comma-separated listen=upstream address pairs to proxy raw TCP for, e.g. :5433=127.0.0.1:5432")
+
+type TCPProxyStats = api.TCPProxyStats
+
+const tcpDialTimeout = 5 * time.Second
+
+var tcpProxies []*tcpProxy
+
+func init() {
+	registerFeature(feature{
+		name: "tcp-proxy",
+		collectors: func() []collector {
+			if *tcpProxyFlag == "" {
+				return nil
+			}
+			return []collector{{
+				name:    "tcp_proxy",
+				timeout: time.Second,
+				collect: func(context.Context) (func(*MonitorResponse), error) {
+					stats := make([]TCPProxyStats, len(tcpProxies))
+					for i, p := range tcpProxies {
+						stats[i] = p.stats()
+					}
+					return func(r *MonitorResponse) {
+						if r.Proxy == nil {
+							r.Proxy = &ProxyStats{}
+						}
+						r.Proxy.TCP = stats
+					}, nil
+				},
+			}}
+		},
+		start: startTCPProxies,
+	})
+}
+
+func startTCPProxies() error {
+	if *tcpProxyFlag == "" {
+		return nil
+	}
+	for _, pair := range strings.Split(*tcpProxyFlag, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		listen, upstream, ok := strings.Cut(pair, "=")
+		if !ok || listen == "" || upstream == "" {
+			return fmt.Errorf("invalid -tcp-proxy %q, expected listen=upstream", pair)
+		}
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			return err
+		}
+		p := &tcpProxy{listen: listen, upstream: upstream}
+		p.persist()
+		p.rates = newRateMeter(&p.bytesIn, &p.bytesOut)
+		tcpProxies = append(tcpProxies, p)
+		go p.serve(ln)
+		go p.rates.run(context.Background(), *sampleInterval)
+	}
+	return nil
+}
+
+// tcpProxy forwards raw TCP connections to the workload, so services that
+// don't speak HTTP (databases, game servers, MQTT brokers) report
+// connection and byte-rate load. TLS passes through untouched.
+type tcpProxy struct {
+	listen   string
+	upstream string
+
+	active         atomic.Int64
+	total          atomic.Uint64
+	rejected       atomic.Uint64
+	upstreamErrors atomic.Uint64
+	bytesIn        atomic.Uint64
+	bytesOut       atomic.Uint64
+	rates          *rateMeter
+}
+
+// persist keeps the proxy's totals across monitor restarts with
+// -state-file.
+func (p *tcpProxy) persist() {
+	prefix := "tcp_proxy/" + p.listen + "=" + p.upstream + "/"
+	persistCounter(prefix+"total_connections", &p.total)
+	persistCounter(prefix+"rejected_connections", &p.rejected)
+	persistCounter(prefix+"upstream_errors", &p.upstreamErrors)
+	persistCounter(prefix+"bytes_in", &p.bytesIn)
+	persistCounter(prefix+"bytes_out", &p.bytesOut)
+}
+
+func (p *tcpProxy) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[monitor] TCP proxy on %s stopped: %v\n", p.listen, err)
+			return
+		}
+		if !controls.admit() {
+			p.rejected.Add(1)
+			conn.Close()
+			continue
+		}
+		go p.handle(conn)
+	}
+}
+
+func (p *tcpProxy) handle(client net.Conn) {
+	defer client.Close()
+	p.total.Add(1)
+	p.active.Add(1)
+	defer p.active.Add(-1)
+
+	upstream, err := net.DialTimeout("tcp", p.upstream, tcpDialTimeout)
+	if err != nil {
+		p.upstreamErrors.Add(1)
+		return
+	}
+	defer upstream.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		pipe(upstream, client, &p.bytesIn)
+	}()
+	go func() {
+		defer wg.Done()
+		pipe(client, upstream, &p.bytesOut)
+	}()
+	wg.Wait()
+}
+
+// pipe copies src to dst, counting bytes as they go so long-lived
+// connections show up in the rate, then half-closes dst so the other
+// direction can finish.
+func pipe(dst, src net.Conn, counter *atomic.Uint64) {
+	io.Copy(countingWriter{dst, counter}, src)
+	if tcp, ok := dst.(*net.TCPConn); ok {
+		tcp.CloseWrite()
+	} else {
+		dst.Close()
+	}
+}
+
+type countingWriter struct {
+	w       io.Writer
+	counter *atomic.Uint64
+}
+
+func (c countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.counter.Add(uint64(n))
+	return n, err
+}
+
+func (p *tcpProxy) stats() TCPProxyStats {
+	rates := p.rates.get()
+	return TCPProxyStats{
+		Listen:              p.listen,
+		Upstream:            p.upstream,
+		ActiveConnections:   p.active.Load(),
+		TotalConnections:    p.total.Load(),
+		RejectedConnections: p.rejected.Load(),
+		UpstreamErrors:      p.upstreamErrors.Load(),
+		BytesIn:             p.bytesIn.Load(),
+		BytesOut:            p.bytesOut.Load(),
+		BytesInPerSecond:    rates[0],
+		BytesOutPerSecond:   rates[1],
+	}
+}
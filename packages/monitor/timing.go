@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var adaptiveIntervals = flag.Bool("adaptive-intervals", true, "Sample collectors whose calls are slow less often, up to 10x their configured interval")
+
+type CollectorTiming = api.CollectorTiming
+
+const (
+	// A collector is slow once a call takes more than 1/slowCallRatio of
+	// its interval, and is then run every slowCallRatio call durations
+	slowCallRatio = 4
+	// maxIntervalStretch caps how far a slow collector's interval grows
+	maxIntervalStretch = 10
+)
+
+// stretchedInterval returns how long to wait between calls that take took,
+// so collectors that are slow on this host (disk usage on a hung NFS mount,
+// a child that's slow to scrape) don't spend most of their time sampling.
+func stretchedInterval(base, took time.Duration) time.Duration {
+	if !*adaptiveIntervals || took*slowCallRatio <= base {
+		return base
+	}
+	return min(took*slowCallRatio, base*maxIntervalStretch)
+}
+
+// samplerTiming accumulates how long a collector's underlying sample call
+// takes, which for background samplers is invisible to scrapers.
+type samplerTiming struct {
+	mu       sync.Mutex
+	runs     uint64
+	failures uint64
+	last     time.Duration
+	total    time.Duration
+	max      time.Duration
+	// interval is the time between calls currently in effect, or 0 for
+	// collectors that only run on demand
+	interval time.Duration
+}
+
+// samplerTimingSnapshot is a copy of a samplerTiming.
+type samplerTimingSnapshot struct {
+	Name     string
+	Runs     uint64
+	Failures uint64
+	Last     time.Duration
+	Mean     time.Duration
+	Max      time.Duration
+	Interval time.Duration
+}
+
+var (
+	timingsMu sync.Mutex
+	timings   = map[string]*samplerTiming{}
+
+	// samplesTaken counts full samples, whether for a scrape, a push, or
+	// the history
+	samplesTaken atomic.Uint64
+)
+
+func timingFor(name string) *samplerTiming {
+	timingsMu.Lock()
+	defer timingsMu.Unlock()
+	t, ok := timings[name]
+	if !ok {
+		t = &samplerTiming{}
+		timings[name] = t
+	}
+	return t
+}
+
+// record notes one call and the interval the caller will wait before the
+// next one.
+func (t *samplerTiming) record(took time.Duration, err error, interval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.runs++
+	if err != nil && !errors.Is(err, errNoSampleYet) {
+		t.failures++
+	}
+	t.last = took
+	t.total += took
+	t.max = max(t.max, took)
+	t.interval = interval
+}
+
+// samplerTimings returns every collector's timings, sorted by name.
+func samplerTimings() []samplerTimingSnapshot {
+	timingsMu.Lock()
+	snapshots := make([]samplerTimingSnapshot, 0, len(timings))
+	for name, t := range timings {
+		t.mu.Lock()
+		s := samplerTimingSnapshot{Name: name, Runs: t.runs, Failures: t.failures, Last: t.last, Max: t.max, Interval: t.interval}
+		if t.runs > 0 {
+			s.Mean = t.total / time.Duration(t.runs)
+		}
+		t.mu.Unlock()
+		snapshots = append(snapshots, s)
+	}
+	timingsMu.Unlock()
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Name < snapshots[j].Name })
+	return snapshots
+}
+
+// setCollectorTimings reports the timings of the collectors in r.
+func setCollectorTimings(r *MonitorResponse) {
+	for _, t := range samplerTimings() {
+		if _, ok := r.Status[t.Name]; !ok || t.Runs == 0 {
+			continue
+		}
+		if r.CollectorTimings == nil {
+			r.CollectorTimings = make(map[string]CollectorTiming)
+		}
+		r.CollectorTimings[t.Name] = CollectorTiming{
+			DurationSeconds: t.Last.Seconds(),
+			IntervalSeconds: t.Interval.Seconds(),
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var (
+	tlsCert       = flag.String("tls-cert", "", "PEM certificate file; serves HTTPS instead of HTTP (requires -tls-key)")
+	tlsKey        = flag.String("tls-key", "", "PEM private key file for -tls-cert")
+	tlsSelfSigned = flag.Bool("tls-self-signed", false, "Serve HTTPS with a certificate generated at startup")
+	tlsClientCA   = flag.String("tls-client-ca", "", "PEM CA bundle; requires clients to present a certificate it signed (requires TLS)")
+)
+
+func servingTLS() bool {
+	return *tlsSelfSigned || *tlsCert != ""
+}
+
+// serverTLSConfig returns the TLS configuration selected by the flags, or
+// nil to serve plain HTTP.
+func serverTLSConfig() (*tls.Config, error) {
+	config, err := serverCertConfig()
+	if err != nil || *tlsClientCA == "" {
+		return config, err
+	}
+	if config == nil {
+		return nil, errors.New("-tls-client-ca requires -tls-cert or -tls-self-signed")
+	}
+
+	pem, err := os.ReadFile(*tlsClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", *tlsClientCA)
+	}
+	config.ClientCAs = pool
+	// Certificates that are presented must verify, but requiring one is left
+	// to requireClientCert so gossip peers can still authenticate with
+	// -gossip-secret
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	return config, nil
+}
+
+// requireClientCert rejects requests without a verified client certificate
+// when -tls-client-ca is set.
+func requireClientCert(next http.Handler) http.Handler {
+	if *tlsClientCA == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gossipz" && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+			api.WriteError(w, http.StatusUnauthorized, api.CodeUnauthenticated, "client certificate required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func serverCertConfig() (*tls.Config, error) {
+	switch {
+	case *tlsSelfSigned && (*tlsCert != "" || *tlsKey != ""):
+		return nil, errors.New("-tls-self-signed can't be combined with -tls-cert or -tls-key")
+	case *tlsSelfSigned:
+		cert, err := selfSignedCertificate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate certificate: %w", err)
+		}
+		fingerprint := sha256.Sum256(cert.Certificate[0])
+		fmt.Fprintf(os.Stderr, "[monitor] Generated self-signed certificate, SHA-256 fingerprint %s\n", hex.EncodeToString(fingerprint[:]))
+		return &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}, nil
+	case *tlsCert != "" || *tlsKey != "":
+		if *tlsCert == "" || *tlsKey == "" {
+			return nil, errors.New("-tls-cert and -tls-key must be set together")
+		}
+		loader := &certLoader{certFile: *tlsCert, keyFile: *tlsKey}
+		if _, err := loader.GetCertificate(nil); err != nil {
+			return nil, err
+		}
+		return &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: loader.GetCertificate}, nil
+	}
+	return nil, nil
+}
+
+// certLoader serves a certificate from disk and reloads it whenever the
+// files change, so rotated certificates (e.g. from cert-manager) are picked
+// up without a restart.
+type certLoader struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+func (l *certLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	modified := latestModTime(l.certFile, l.keyFile)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cert != nil && !modified.After(l.modified) {
+		return l.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		if l.cert != nil {
+			// Keep serving the previous certificate while a rotation is
+			// half-written
+			return l.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	l.cert, l.modified = &cert, modified
+	return l.cert, nil
+}
+
+func latestModTime(paths ...string) time.Time {
+	var latest time.Time
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// selfSignedCertificate generates a certificate for the hostname, localhost,
+// and loopback addresses, valid for a year.
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	hostname, _ := os.Hostname()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hostname},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if hostname != "" {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,260 @@
+//go:build !minimal && !noproxy
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhi-arya1/autoscaled/monitor/api"
+)
+
+var (
+	udpProxyFlag   = flag.String("udp-proxy", "", "Comma-separated listen=upstream address pairs to proxy UDP for, e.g. :5353=127.0.0.1:53")
+	udpFlowTimeout = flag.Duration("udp-flow-timeout", 30*time.Second, "How long a UDP flow may go without packets before it is dropped")
+)
+
+type UDPProxyStats = api.UDPProxyStats
+
+// maxDatagram covers the largest UDP payload over IPv4 or IPv6.
+const maxDatagram = 64 << 10
+
+var udpProxies []*udpProxy
+
+func init() {
+	registerFeature(feature{
+		name: "udp-proxy",
+		collectors: func() []collector {
+			if *udpProxyFlag == "" {
+				return nil
+			}
+			return []collector{{
+				name:    "udp_proxy",
+				timeout: time.Second,
+				collect: func(context.Context) (func(*MonitorResponse), error) {
+					stats := make([]UDPProxyStats, len(udpProxies))
+					for i, p := range udpProxies {
+						stats[i] = p.stats()
+					}
+					return func(r *MonitorResponse) {
+						if r.Proxy == nil {
+							r.Proxy = &ProxyStats{}
+						}
+						r.Proxy.UDP = stats
+					}, nil
+				},
+			}}
+		},
+		start: startUDPProxies,
+	})
+}
+
+func startUDPProxies() error {
+	if *udpProxyFlag == "" {
+		return nil
+	}
+	if *udpFlowTimeout <= 0 {
+		return errors.New("-udp-flow-timeout must be positive")
+	}
+	for _, pair := range strings.Split(*udpProxyFlag, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		listen, upstream, ok := strings.Cut(pair, "=")
+		if !ok || listen == "" || upstream == "" {
+			return fmt.Errorf("invalid -udp-proxy %q, expected listen=upstream", pair)
+		}
+		listenAddr, err := net.ResolveUDPAddr("udp", listen)
+		if err != nil {
+			return err
+		}
+		upstreamAddr, err := net.ResolveUDPAddr("udp", upstream)
+		if err != nil {
+			return err
+		}
+		conn, err := net.ListenUDP("udp", listenAddr)
+		if err != nil {
+			return err
+		}
+
+		p := &udpProxy{
+			listen:       listen,
+			upstream:     upstream,
+			upstreamAddr: upstreamAddr,
+			conn:         conn,
+			timeout:      *udpFlowTimeout,
+			flows:        map[string]*udpFlow{},
+		}
+		p.persist()
+		p.rates = newRateMeter(&p.packetsIn, &p.packetsOut, &p.bytesIn, &p.bytesOut)
+		udpProxies = append(udpProxies, p)
+		go p.serve()
+		go p.rates.run(context.Background(), *sampleInterval)
+	}
+	return nil
+}
+
+// udpProxy forwards datagrams to the workload (DNS, game traffic, ...).
+// Each client address gets its own upstream socket, so replies can be
+// routed back to it; together they form a flow keyed by the 5-tuple.
+type udpProxy struct {
+	listen       string
+	upstream     string
+	upstreamAddr *net.UDPAddr
+	conn         *net.UDPConn
+	timeout      time.Duration
+
+	mu    sync.Mutex
+	flows map[string]*udpFlow
+
+	active     atomic.Int64
+	total      atomic.Uint64
+	dropped    atomic.Uint64
+	packetsIn  atomic.Uint64
+	packetsOut atomic.Uint64
+	bytesIn    atomic.Uint64
+	bytesOut   atomic.Uint64
+	rates      *rateMeter
+}
+
+type udpFlow struct {
+	client   *net.UDPAddr
+	upstream *net.UDPConn
+	// lastSeen is the time of the last packet in either direction, in Unix
+	// nanoseconds
+	lastSeen atomic.Int64
+}
+
+// persist keeps the proxy's totals across monitor restarts with
+// -state-file.
+func (p *udpProxy) persist() {
+	prefix := "udp_proxy/" + p.listen + "=" + p.upstream + "/"
+	persistCounter(prefix+"total_flows", &p.total)
+	persistCounter(prefix+"dropped_packets", &p.dropped)
+	persistCounter(prefix+"packets_in", &p.packetsIn)
+	persistCounter(prefix+"packets_out", &p.packetsOut)
+	persistCounter(prefix+"bytes_in", &p.bytesIn)
+	persistCounter(prefix+"bytes_out", &p.bytesOut)
+}
+
+func (p *udpProxy) serve() {
+	buf := make([]byte, maxDatagram)
+	for {
+		n, client, err := p.conn.ReadFromUDP(buf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[monitor] UDP proxy on %s stopped: %v\n", p.listen, err)
+			return
+		}
+
+		flow := p.flow(client)
+		if flow == nil {
+			p.dropped.Add(1)
+			continue
+		}
+		flow.lastSeen.Store(time.Now().UnixNano())
+		if _, err := flow.upstream.Write(buf[:n]); err != nil {
+			p.dropped.Add(1)
+			continue
+		}
+		p.packetsIn.Add(1)
+		p.bytesIn.Add(uint64(n))
+	}
+}
+
+// flow returns client's flow, starting one if it's new and /controlz admits
+// it. It returns nil if the packet should be dropped.
+func (p *udpProxy) flow(client *net.UDPAddr) *udpFlow {
+	key := client.String()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if flow, ok := p.flows[key]; ok {
+		return flow
+	}
+	if !controls.admit() {
+		return nil
+	}
+
+	upstream, err := net.DialUDP("udp", nil, p.upstreamAddr)
+	if err != nil {
+		return nil
+	}
+	flow := &udpFlow{client: client, upstream: upstream}
+	flow.lastSeen.Store(time.Now().UnixNano())
+	p.flows[key] = flow
+	p.total.Add(1)
+	p.active.Add(1)
+	go p.reply(key, flow)
+	return flow
+}
+
+// reply relays the upstream's datagrams back to the flow's client until the
+// flow has been idle for the timeout.
+func (p *udpProxy) reply(key string, flow *udpFlow) {
+	defer func() {
+		p.mu.Lock()
+		delete(p.flows, key)
+		p.mu.Unlock()
+		flow.upstream.Close()
+		p.active.Add(-1)
+	}()
+
+	buf := make([]byte, maxDatagram)
+	for {
+		flow.upstream.SetReadDeadline(time.Now().Add(p.timeout))
+		n, err := flow.upstream.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// The client may still be sending without getting replies
+				if time.Since(time.Unix(0, flow.lastSeen.Load())) < p.timeout {
+					continue
+				}
+				return
+			}
+			// ICMP port unreachable surfaces as a read error; keep the flow
+			// so a restarting upstream can recover it
+			if time.Since(time.Unix(0, flow.lastSeen.Load())) >= p.timeout {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		flow.lastSeen.Store(time.Now().UnixNano())
+		if _, err := p.conn.WriteToUDP(buf[:n], flow.client); err != nil {
+			p.dropped.Add(1)
+			continue
+		}
+		p.packetsOut.Add(1)
+		p.bytesOut.Add(uint64(n))
+	}
+}
+
+func (p *udpProxy) stats() UDPProxyStats {
+	rates := p.rates.get()
+	return UDPProxyStats{
+		Listen:              p.listen,
+		Upstream:            p.upstream,
+		ActiveFlows:         p.active.Load(),
+		TotalFlows:          p.total.Load(),
+		DroppedPackets:      p.dropped.Load(),
+		PacketsIn:           p.packetsIn.Load(),
+		PacketsOut:          p.packetsOut.Load(),
+		BytesIn:             p.bytesIn.Load(),
+		BytesOut:            p.bytesOut.Load(),
+		PacketsInPerSecond:  rates[0],
+		PacketsOutPerSecond: rates[1],
+		BytesInPerSecond:    rates[2],
+		BytesOutPerSecond:   rates[3],
+	}
+}
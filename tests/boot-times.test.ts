@@ -0,0 +1,57 @@
+import { describe, it, expect } from "bun:test";
+import { summarizeBootTimes } from "../packages/autoscaled/src/boot-times";
+import type { BootRecord } from "../packages/autoscaled/src/types";
+
+// Runs without the worker: the summary only needs recorded boots
+function boot(id: number, bootMs: number, resumed: boolean): BootRecord {
+    return {
+        id,
+        instance: `instance-${id}`,
+        started_at: "2025-01-01T00:00:00.000Z",
+        ready_at: new Date(Date.UTC(2025, 0, 1) + bootMs).toISOString(),
+        boot_ms: bootMs,
+        resumed: resumed ? 1 : 0,
+    };
+}
+
+describe("Boot Time Summary", () => {
+    it("should report created and resumed boots apart", () => {
+        const boots = [
+            ...Array.from({ length: 20 }, (_, i) =>
+                boot(i, (i + 1) * 500, false),
+            ),
+            boot(20, 300, true),
+            boot(21, 700, true),
+        ];
+        const stats = summarizeBootTimes(boots, 2, 5_000);
+
+        expect(stats.booting).toBe(2);
+        expect(stats.created).toEqual({
+            count: 20,
+            p50: 5_000,
+            p95: 9_500,
+            max: 10_000,
+            withinObjective: 0.5,
+        });
+        expect(stats.resumed).toEqual({
+            count: 2,
+            p50: 300,
+            p95: 700,
+            max: 700,
+            withinObjective: 1,
+        });
+    });
+
+    it("should report nothing without boots or an objective", () => {
+        const stats = summarizeBootTimes([boot(0, 1_200, false)], 0, undefined);
+
+        expect(stats.created.withinObjective).toBeNull();
+        expect(stats.resumed).toEqual({
+            count: 0,
+            p50: null,
+            p95: null,
+            max: null,
+            withinObjective: null,
+        });
+    });
+});
@@ -0,0 +1,51 @@
+import { describe, it, expect } from "bun:test";
+import { ColdStartTracker } from "../packages/autoscaled/src/cold-starts";
+
+// Runs without the worker: the tracker only needs a clock
+function tracker(): ColdStartTracker {
+    return new ColdStartTracker(() => new Date().toISOString());
+}
+
+describe("Cold Start Attribution", () => {
+    it("should tag cold-start responses with their wait", () => {
+        const coldStarts = tracker();
+        const response = coldStarts.record(
+            new Response("ok", { headers: { "Content-Type": "text/plain" } }),
+            1234.4,
+            true,
+        );
+        expect(response.headers.get("X-Cold-Start")).toBe("1234");
+        expect(response.headers.get("Content-Type")).toBe("text/plain");
+    });
+
+    it("should report the fraction of traffic and the wait histogram", () => {
+        const coldStarts = tracker();
+        for (let i = 0; i < 10; i++) {
+            coldStarts.countRequest();
+        }
+        coldStarts.record(new Response("ok"), 200, true);
+        coldStarts.record(new Response(null, { status: 503 }), 800, false);
+
+        const stats = coldStarts.stats();
+        expect(stats.requests).toBe(10);
+        expect(stats.coldStarts).toBe(2);
+        expect(stats.turnedAway).toBe(1);
+        expect(stats.fraction).toBe(0.2);
+        expect(stats.waitMs.average).toBe(500);
+        expect(stats.waitMs.max).toBe(800);
+        expect(stats.waitMs.buckets["100"]).toBe(0);
+        expect(stats.waitMs.buckets["250"]).toBe(1);
+        expect(stats.waitMs.buckets["1000"]).toBe(2);
+        expect(stats.waitMs.buckets["+Inf"]).toBe(2);
+    });
+
+    it("should count waits past the last bucket only in +Inf", () => {
+        const coldStarts = tracker();
+        coldStarts.countRequest();
+        coldStarts.record(new Response("ok"), 45_000, true);
+
+        const { buckets } = coldStarts.stats().waitMs;
+        expect(buckets["30000"]).toBe(0);
+        expect(buckets["+Inf"]).toBe(1);
+    });
+});
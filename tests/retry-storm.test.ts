@@ -0,0 +1,61 @@
+import { describe, it, expect } from "bun:test";
+import {
+    MAX_TRACKED_KEYS,
+    RetryStormDetector,
+} from "../packages/autoscaled/src/retry-storm";
+import type { AutoscalerConfig } from "../packages/autoscaled/src/types";
+
+// Runs without the worker: the detector only needs its config
+function detector(threshold: number): RetryStormDetector {
+    const config = { retryStormThreshold: threshold } as AutoscalerConfig;
+    return new RetryStormDetector(
+        () => config,
+        () => new Date().toISOString(),
+    );
+}
+
+function keyed(key: string): Request {
+    return new Request("http://localhost/", {
+        headers: { "Idempotency-Key": key },
+    });
+}
+
+describe("Retry Storm Detection", () => {
+    it("should turn away retries beyond the threshold during a storm", () => {
+        const storm = detector(3);
+        for (let i = 0; i < 19; i++) {
+            expect(storm.admit(keyed(`key-${i % 5}`))).toBe(true);
+        }
+        expect(storm.status().active).toBe(false);
+        // The 20th request starts the storm, and is key-4's 4th attempt
+        expect(storm.admit(keyed("key-4"))).toBe(false);
+        expect(storm.status().active).toBe(true);
+        expect(storm.status().ratio).toBe(4);
+        expect(storm.admit(keyed("key-0"))).toBe(false);
+        expect(storm.admit(keyed("new-key"))).toBe(true);
+    });
+
+    it("should not read unique keys past the cap as a storm", () => {
+        const storm = detector(2);
+        for (let i = 0; i < 3 * MAX_TRACKED_KEYS; i++) {
+            expect(storm.admit(keyed(`key-${i}`))).toBe(true);
+        }
+        expect(storm.status()).toEqual({
+            active: false,
+            since: null,
+            ratio: 1,
+        });
+    });
+
+    it("should still count retries of tracked keys past the cap", () => {
+        const storm = detector(2);
+        for (let i = 0; i < 2 * MAX_TRACKED_KEYS; i++) {
+            storm.admit(keyed(`key-${i}`));
+        }
+        for (let i = 0; i < MAX_TRACKED_KEYS; i++) {
+            storm.admit(keyed(`key-${i}`));
+        }
+        expect(storm.status().active).toBe(true);
+        expect(storm.admit(keyed("key-0"))).toBe(false);
+    });
+});
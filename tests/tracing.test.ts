@@ -0,0 +1,72 @@
+import { describe, it, expect, afterEach } from "bun:test";
+import { Tracer } from "../packages/autoscaled/src/tracing";
+import type { AutoscalerConfig } from "../packages/autoscaled/src/types";
+
+// Runs without the worker: exports go to a stubbed fetch
+const realFetch = globalThis.fetch;
+
+function tracer(config: Partial<AutoscalerConfig>) {
+    const exports: Promise<unknown>[] = [];
+    const tracer = new Tracer(
+        () => config as AutoscalerConfig,
+        (promise) => exports.push(promise),
+    );
+    return { tracer, exported: () => Promise.all(exports) };
+}
+
+describe("Tracing", () => {
+    afterEach(() => {
+        globalThis.fetch = realFetch;
+    });
+
+    it("should export a trace once its pending spans end", async () => {
+        const bodies: any[] = [];
+        globalThis.fetch = (async (_url: string, init: RequestInit) => {
+            bodies.push(JSON.parse(init.body as string));
+            return new Response(null, { status: 200 });
+        }) as typeof fetch;
+
+        const { tracer: t, exported } = tracer({
+            otlpEndpoint: "http://collector/v1/traces",
+        });
+        await t.trace("autoscaler.evaluate", async (root) => {
+            await root.run("policy.evaluate", (span) => {
+                span.setAttribute("autoscaler.scale_up", true);
+                return true;
+            });
+            const ready = root.child("instance.ready");
+            root.waitFor(
+                new Promise((resolve) => setTimeout(resolve, 10)).then(() =>
+                    ready.end(),
+                ),
+            );
+        });
+        await exported();
+
+        expect(bodies).toHaveLength(1);
+        const spans = bodies[0].resourceSpans[0].scopeSpans[0].spans;
+        expect(spans.map((span: any) => span.name)).toEqual([
+            "autoscaler.evaluate",
+            "policy.evaluate",
+            "instance.ready",
+        ]);
+        expect(new Set(spans.map((span: any) => span.traceId)).size).toBe(1);
+        expect(spans[1].parentSpanId).toBe(spans[0].spanId);
+        expect(spans[1].attributes).toEqual([
+            { key: "autoscaler.scale_up", value: { boolValue: true } },
+        ]);
+    });
+
+    it("should record a failed span and rethrow", async () => {
+        const { tracer: t, exported } = tracer({});
+        await expect(
+            t.trace("autoscaler.scale_up", async (root) => {
+                await root.run("provider.create", () => {
+                    throw new Error("no capacity");
+                });
+            }),
+        ).rejects.toThrow("no capacity");
+        // Without an endpoint nothing is sent
+        await exported();
+    });
+});
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// chaosState holds the failure modes that can be toggled through /admin.
+type chaosState struct {
+	mu sync.Mutex
+
+	flapPeriod    time.Duration
+	refuseUntil   time.Time
+	shutdownDelay time.Duration
+	leakMBPerSec  int
+	leaked        [][]byte
+}
+
+type ChaosStatus struct {
+	FlapHealth    bool   `json:"flap_health"`
+	FlapPeriod    string `json:"flap_period,omitempty"`
+	RefuseUntil   string `json:"refuse_until,omitempty"`
+	ShutdownDelay string `json:"shutdown_delay,omitempty"`
+	LeakMBPerSec  int    `json:"leak_mb_per_sec"`
+	LeakedMB      int    `json:"leaked_mb"`
+}
+
+var chaos = &chaosState{}
+
+func (c *chaosState) status() ChaosStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := ChaosStatus{
+		FlapHealth:   c.flapPeriod > 0,
+		LeakMBPerSec: c.leakMBPerSec,
+		LeakedMB:     len(c.leaked),
+	}
+	if c.flapPeriod > 0 {
+		s.FlapPeriod = c.flapPeriod.String()
+	}
+	if time.Now().Before(c.refuseUntil) {
+		s.RefuseUntil = c.refuseUntil.UTC().Format(time.RFC3339)
+	}
+	if c.shutdownDelay > 0 {
+		s.ShutdownDelay = c.shutdownDelay.String()
+	}
+	return s
+}
+
+// healthy reports whether the health endpoints should currently succeed.
+// With flapping enabled, health alternates every flap period.
+func (c *chaosState) healthy() bool {
+	c.mu.Lock()
+	period := c.flapPeriod
+	c.mu.Unlock()
+
+	if period <= 0 {
+		return true
+	}
+	return (time.Now().UnixNano()/int64(period))%2 == 0
+}
+
+func (c *chaosState) refusing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Before(c.refuseUntil)
+}
+
+func (c *chaosState) getShutdownDelay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.shutdownDelay
+}
+
+// maxLeakMBPerSec bounds the leak rate, so a typo in mb_per_sec can't take
+// the worker down in one tick instead of over time.
+const maxLeakMBPerSec = 64
+
+// leak allocates leakMBPerSec megabytes every second and holds on to them.
+// Allocation happens outside the lock, so /admin stays responsive while the
+// leak runs.
+func (c *chaosState) leak() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.mu.Lock()
+		rate := c.leakMBPerSec
+		c.mu.Unlock()
+
+		blocks := make([][]byte, 0, rate)
+		for i := 0; i < rate; i++ {
+			block := make([]byte, 1<<20)
+			// Touch every page so the allocation shows up in RSS
+			for j := 0; j < len(block); j += 4096 {
+				block[j] = 1
+			}
+			blocks = append(blocks, block)
+		}
+
+		c.mu.Lock()
+		// Dropped if the leak was turned off or reset in the meantime
+		if c.leakMBPerSec > 0 {
+			c.leaked = append(c.leaked, blocks...)
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *chaosState) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flapPeriod = 0
+	c.refuseUntil = time.Time{}
+	c.shutdownDelay = 0
+	c.leakMBPerSec = 0
+	c.leaked = nil
+}
+
+// chaosListener closes incoming connections while refusal is enabled.
+type chaosListener struct {
+	net.Listener
+}
+
+func (l chaosListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if !chaos.refusing() {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
+
+func parseDurationParam(r *http.Request, name string, fallback time.Duration) (time.Duration, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func adminHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin" && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	enabled := r.URL.Query().Get("enabled") != "false"
+
+	switch r.URL.Path {
+	case "/admin":
+		// Status only
+	case "/admin/flap":
+		period, err := parseDurationParam(r, "period", 10*time.Second)
+		if err != nil || period <= 0 {
+			http.Error(w, "invalid period", http.StatusBadRequest)
+			return
+		}
+		chaos.mu.Lock()
+		if enabled {
+			chaos.flapPeriod = period
+		} else {
+			chaos.flapPeriod = 0
+		}
+		chaos.mu.Unlock()
+	case "/admin/refuse":
+		// Refusal always expires, otherwise the admin API would lock itself out
+		duration, err := parseDurationParam(r, "duration", 30*time.Second)
+		if err != nil || duration <= 0 {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+		chaos.mu.Lock()
+		if enabled {
+			chaos.refuseUntil = time.Now().Add(duration)
+		} else {
+			chaos.refuseUntil = time.Time{}
+		}
+		chaos.mu.Unlock()
+	case "/admin/shutdown-delay":
+		delay, err := parseDurationParam(r, "duration", 30*time.Second)
+		if err != nil || delay < 0 {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+		chaos.mu.Lock()
+		if enabled {
+			chaos.shutdownDelay = delay
+		} else {
+			chaos.shutdownDelay = 0
+		}
+		chaos.mu.Unlock()
+	case "/admin/leak":
+		rate := 1
+		if value := r.URL.Query().Get("mb_per_sec"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > maxLeakMBPerSec {
+				http.Error(w, "mb_per_sec must be between 0 and "+strconv.Itoa(maxLeakMBPerSec), http.StatusBadRequest)
+				return
+			}
+			rate = n
+		}
+		chaos.mu.Lock()
+		if enabled {
+			chaos.leakMBPerSec = rate
+		} else {
+			chaos.leakMBPerSec = 0
+		}
+		chaos.mu.Unlock()
+	case "/admin/reset":
+		chaos.reset()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.URL.Path != "/admin" {
+		log.Printf("Chaos updated via %s: %+v", r.URL.RequestURI(), chaos.status())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chaos.status())
+}
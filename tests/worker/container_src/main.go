@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
@@ -35,6 +40,14 @@ func handler(w http.ResponseWriter, r *http.Request) {
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	if !chaos.healthy() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":    "unhealthy",
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":    "healthy",
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
@@ -60,11 +73,105 @@ func loadHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// payloadHandler streams mb megabytes of random data, optionally throttled to
+// rate_kbps kilobytes per second.
+func payloadHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	mb := 1.0
+	if value := query.Get("mb"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || parsed > 1024 {
+			http.Error(w, "mb must be between 0 and 1024", http.StatusBadRequest)
+			return
+		}
+		mb = parsed
+	}
+
+	rateKBps := 0
+	if value := query.Get("rate_kbps"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "rate_kbps must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		rateKBps = parsed
+	}
+
+	total := int64(mb * (1 << 20))
+	chunk := make([]byte, 64<<10)
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(total, 10))
+
+	start := time.Now()
+	var sent int64
+	for sent < total {
+		n := int64(len(chunk))
+		if remaining := total - sent; remaining < n {
+			n = remaining
+		}
+		rand.Read(chunk[:n])
+		if _, err := w.Write(chunk[:n]); err != nil {
+			return
+		}
+		sent += n
+
+		if rateKBps > 0 {
+			// Sleep until the bytes sent so far fit within the requested rate
+			expected := time.Duration(float64(sent) / float64(rateKBps<<10) * float64(time.Second))
+			if elapsed := time.Since(start); expected > elapsed {
+				time.Sleep(expected - elapsed)
+			}
+		}
+	}
+}
+
 func errorHandler(w http.ResponseWriter, r *http.Request) {
 	panic("This is a panic")
 }
 
+// envOr returns the value of the environment variable key, or fallback if unset.
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// requireToken rejects requests without a matching bearer token. Health
+// endpoints stay open so orchestrator probes keep working.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz", "/health":
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func main() {
+	defaultPort, err := strconv.Atoi(envOr("PORT", "8080"))
+	if err != nil {
+		log.Fatalf("Invalid PORT: %v", err)
+	}
+	port := flag.Int("port", defaultPort, "Port to listen on (env PORT)")
+	tlsCert := flag.String("tls-cert", envOr("TLS_CERT", ""), "TLS certificate file (env TLS_CERT)")
+	tlsKey := flag.String("tls-key", envOr("TLS_KEY", ""), "TLS private key file (env TLS_KEY)")
+	authToken := flag.String("auth-token", envOr("AUTH_TOKEN", ""), "Require this bearer token on non-health endpoints (env AUTH_TOKEN)")
+	flag.Parse()
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatal("Both -tls-cert and -tls-key must be set to enable TLS")
+	}
+
 	// Listen for SIGINT and SIGTERM
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
@@ -75,20 +182,47 @@ func main() {
 	router.HandleFunc("/health", healthHandler)
 	router.HandleFunc("/load", loadHandler)
 	router.HandleFunc("/error", errorHandler)
+	router.HandleFunc("/payload", payloadHandler)
+	router.HandleFunc("/admin", adminHandler)
+	router.HandleFunc("/admin/", adminHandler)
+
+	var handler http.Handler = router
+	if *authToken != "" {
+		handler = requireToken(*authToken, router)
+	}
 
 	server := &http.Server{
-		Addr:    ":8080",
-		Handler: router,
+		Addr:    fmt.Sprintf(":%d", *port),
+		Handler: handler,
 	}
 
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go chaos.leak()
+
 	go func() {
-		log.Printf("Server listening on %s\n", server.Addr)
+		scheme := "http"
+		if *tlsCert != "" {
+			scheme = "https"
+		}
+		log.Printf("Server listening on %s (%s)\n", server.Addr, scheme)
 		log.Println("Available endpoints:")
 		log.Println("  GET / - Basic handler")
 		log.Println("  GET /healthz - Health check")
 		log.Println("  GET /load - Simulate CPU load")
 		log.Println("  GET /error - Trigger panic")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Println("  GET /payload?mb=10&rate_kbps=0 - Stream random bytes")
+		log.Println("  GET /admin - Chaos status (POST /admin/{flap,refuse,shutdown-delay,leak,reset})")
+		var err error
+		if *tlsCert != "" {
+			err = server.ServeTLS(chaosListener{listener}, *tlsCert, *tlsKey)
+		} else {
+			err = server.Serve(chaosListener{listener})
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatal(err)
 		}
 	}()
@@ -98,6 +232,12 @@ func main() {
 
 	log.Printf("Received signal (%s), shutting down server...", sig)
 
+	// Chaos: hold the process open past the orchestrator's grace period
+	if delay := chaos.getShutdownDelay(); delay > 0 {
+		log.Printf("Delaying shutdown by %s", delay)
+		time.Sleep(delay)
+	}
+
 	// Give the server 5 seconds to shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()